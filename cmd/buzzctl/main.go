@@ -0,0 +1,123 @@
+// buzzctl administers a Buzz install from the shell, against the same
+// configuration and database the server uses
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+	"go.uber.org/fx"
+
+	"github.com/nugrhrizki/buzz/internal/app"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+)
+
+const usage = `usage: buzzctl [config flags] <command> [arguments]
+
+commands:
+  migrate <status|up|down [n]>       manage the database schema
+  seed                               create the default role and admin if missing
+  user create [flags]                create a dashboard user
+  user reset-password <username>     set a new password and log the user out
+  user unlock <username>             clear failed logins of a user
+  session list                       list the WhatsApp sessions
+  session create [flags]             create a WhatsApp session
+  session delete <id>                delete a WhatsApp session
+  session status <id>                show whether a session is paired and connected
+  session qr <id>                    pair a session by scanning a QR code in the terminal
+  session send <id> <phone> <text>   send a test message
+  session export <id> [file]         write a paired session to a file, stdout by default
+  session import [file]              add an exported session, stdin by default
+
+Run "buzzctl <command> -h" for the flags of a command and "buzzctl -h" for
+the config flags, they are the ones of the server.`
+
+// actor is who buzzctl records audit events as
+var actor = audit.Actor{Type: audit.ActorSystem, Id: "buzzctl", Name: "buzzctl"}
+
+var errUsage = errors.New(usage)
+
+func main() {
+	cfg, args, err := config.Load("buzzctl", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "\n"+usage)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Setenv("TZ", cfg.Server.Timezone)
+
+	err = run(cfg, args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "buzzctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "migrate":
+		if len(args) < 2 {
+			return fmt.Errorf("%w\n\nmissing migrate command", errUsage)
+		}
+		return boot(cfg, func(db *database.Database, s app.Schema) error {
+			return app.Migrate(db, s, args[1:], os.Stdout)
+		})
+
+	case "seed":
+		return boot(cfg, func(db *database.Database, s app.Schema) error {
+			if err := db.Migrate(s.Modules()...); err != nil {
+				return err
+			}
+			s.Seed(db)
+			return nil
+		})
+
+	case "user":
+		return userCommand(cfg, args[1:])
+
+	case "session":
+		return sessionCommand(cfg, args[1:])
+
+	default:
+		return fmt.Errorf("%w\n\nunknown command %q", errUsage, args[0])
+	}
+}
+
+// boot builds what invoke asks for from the shared providers and runs it.
+// invoke takes fx dependencies and returns an error, which boot returns.
+func boot(cfg *config.Config, invoke any) error {
+	return fx.New(
+		app.Providers,
+		fx.Supply(cfg),
+		fx.NopLogger,
+		// keep stdout for the output of the commands, exports go there
+		fx.Decorate(func(cfg *config.Config) *zerolog.Logger {
+			// the log file belongs to the server, two writers would both
+			// rotate it
+			logCfg := *cfg
+			logCfg.Log.File = ""
+			return buzzLog.NewTo(&logCfg, os.Stderr, nil)
+		}),
+		fx.Invoke(invoke),
+	).Err()
+}
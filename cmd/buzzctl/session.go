@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/token"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	whatsappApi "github.com/nugrhrizki/buzz/pkg/whatsapp/api"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+	whatsappUser "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+// ErrSessionConnected is returned when a command would take over the
+// connection of a session the server is running
+var ErrSessionConnected = errors.New("session is connected, most likely by a running server. Stop it first or pass -force, WhatsApp keeps one connection per device")
+
+func sessionCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return listSessions(cfg, args[1:])
+	case "create":
+		return createSession(cfg, args[1:])
+	case "delete":
+		return deleteSession(cfg, args[1:])
+	case "status":
+		return sessionStatus(cfg, args[1:])
+	case "qr":
+		return pairSession(cfg, args[1:])
+	case "send":
+		return sendMessage(cfg, args[1:])
+	case "export":
+		return exportSession(cfg, args[1:])
+	case "import":
+		return importSession(cfg, args[1:])
+	default:
+		return fmt.Errorf("%w\n\nunknown session command %q", errUsage, args[0])
+	}
+}
+
+func listSessions(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session list", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	return boot(cfg, func(users *whatsappUser.Repository) error {
+		sessions, err := users.GetUsers()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTOKEN\tJID\tCONNECTED")
+		for _, u := range sessions {
+			session := u.Session()
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", session.Id, session.Name, session.TokenHint, orDash(session.Jid), yesNo(isConnected(&u)))
+		}
+		return w.Flush()
+	})
+}
+
+func createSession(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session create", flag.ContinueOnError)
+	name := flags.String("name", "", "name of the session (required)")
+	secret := flags.String("token", "", "token identifying the session, generated when empty")
+	webhook := flags.String("webhook", "", "url events are posted to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("%w\n\nsession create needs -name", errUsage)
+	}
+
+	if *secret == "" {
+		generated, _, err := token.NewRefreshToken()
+		if err != nil {
+			return err
+		}
+		*secret = generated
+	}
+
+	return boot(cfg, func(
+		api *whatsappApi.Api,
+		users *whatsappUser.Repository,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		created, err := api.CreateUser(&whatsappUser.User{Name: *name, Token: *secret})
+		if err != nil {
+			return err
+		}
+		if *webhook != "" {
+			if err := users.SetWebhook(created.Id, *webhook); err != nil {
+				return err
+			}
+			created.Webhook = *webhook
+		}
+
+		record(audits, log, "session.create", "session", strconv.Itoa(created.Id), nil, created.Session())
+
+		// the api only takes api keys, hand out one to start with
+		key, err := api.CreateApiKey(created.Id, &whatsappApi.CreateApiKeyPayload{
+			Name:   "buzzctl",
+			Scopes: []string{apikey.ScopeAdmin},
+		})
+		if err != nil {
+			return err
+		}
+		record(audits, log, "apikey.create", "apikey", strconv.FormatInt(key.Id, 10), nil, key.ApiKey)
+
+		fmt.Printf("created session %s (id %d)\napi key: %s\n", created.Name, created.Id, key.Key)
+		return nil
+	})
+}
+
+func deleteSession(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session delete", flag.ContinueOnError)
+	id, err := parseSessionId(flags, args)
+	if err != nil {
+		return err
+	}
+
+	return boot(cfg, func(
+		api *whatsappApi.Api,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		session, err := api.GetUserById(id)
+		if err != nil {
+			return fmt.Errorf("session %d: %w", id, err)
+		}
+		if err := api.DeleteUser(session); err != nil {
+			return err
+		}
+
+		record(audits, log, "session.delete", "session", strconv.Itoa(id), session.Session(), nil)
+		fmt.Printf("deleted session %s (id %d)\n", session.Name, session.Id)
+		return nil
+	})
+}
+
+func sessionStatus(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session status", flag.ContinueOnError)
+	id, err := parseSessionId(flags, args)
+	if err != nil {
+		return err
+	}
+
+	return boot(cfg, func(w *whatsapp.Whatsapp, users *whatsappUser.Repository) error {
+		session, err := users.GetUserById(id)
+		if err != nil {
+			return fmt.Errorf("session %d: %w", id, err)
+		}
+
+		paired := false
+		if session.Jid != "" {
+			if paired, err = w.HasDevice(session.Jid); err != nil {
+				return err
+			}
+		}
+
+		out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(out, "id\t%d\n", session.Id)
+		fmt.Fprintf(out, "name\t%s\n", session.Name)
+		fmt.Fprintf(out, "jid\t%s\n", orDash(session.Jid))
+		fmt.Fprintf(out, "paired\t%s\n", yesNo(paired))
+		fmt.Fprintf(out, "connected\t%s\n", yesNo(isConnected(session)))
+		fmt.Fprintf(out, "webhook\t%s\n", orDash(session.Webhook))
+		fmt.Fprintf(out, "events\t%s\n", orDash(session.Events))
+		return out.Flush()
+	})
+}
+
+// pairSession links a session to a phone, the QR codes whatsapp hands out are
+// printed until one is scanned
+func pairSession(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session qr", flag.ContinueOnError)
+	timeout := flags.Duration("timeout", 3*time.Minute, "how long to wait for the code to be scanned")
+	id, err := parseSessionId(flags, args)
+	if err != nil {
+		return err
+	}
+
+	return boot(cfg, func(
+		w *whatsapp.Whatsapp,
+		users *whatsappUser.Repository,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		session, err := users.GetUserById(id)
+		if err != nil {
+			return fmt.Errorf("session %d: %w", id, err)
+		}
+		if session.Jid != "" {
+			return fmt.Errorf("session %d is already paired with %s, log it out first", id, session.Jid)
+		}
+
+		w.OnQRCode(func(userID int, code string) {
+			if userID != id {
+				return
+			}
+			qr, err := qrcode.New(code, qrcode.Low)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to render QR code")
+				return
+			}
+			fmt.Println(qr.ToSmallString(false))
+			fmt.Println("scan the code in WhatsApp under Linked devices, a new one follows when it expires")
+		})
+
+		w.UpdateCacheUserInfo(session.Token, w.UserToUserInfo(session))
+		w.NewKillChannel(id)
+		go w.StartClient(id, "", session.Token, []string{})
+
+		paired, err := waitFor(*timeout, func() (bool, error) {
+			current, err := users.GetUserById(id)
+			if err != nil {
+				return false, err
+			}
+			session = current
+			return current.Jid != "", nil
+		})
+		if err != nil {
+			return err
+		}
+		if !paired {
+			return fmt.Errorf("session %d was not paired within %s", id, *timeout)
+		}
+
+		// connected sessions are the ones the server brings up when it starts
+		if err := users.SetUserConnected(id, 1); err != nil {
+			return err
+		}
+
+		record(audits, log, "session.paired", "session", strconv.Itoa(id), nil, session.Jid)
+		fmt.Printf("session %d is paired with %s, the server connects it when it starts\n", id, session.Jid)
+		return nil
+	})
+}
+
+func sendMessage(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session send", flag.ContinueOnError)
+	force := flags.Bool("force", false, "send even when the session looks connected elsewhere")
+	timeout := flags.Duration("timeout", 30*time.Second, "how long to wait for the connection")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 3 {
+		return fmt.Errorf("%w\n\nsession send needs an id, a phone number and a text", errUsage)
+	}
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%w\n\ninvalid session id %q", errUsage, flags.Arg(0))
+	}
+	phone, text := flags.Arg(1), flags.Arg(2)
+
+	return boot(cfg, func(
+		w *whatsapp.Whatsapp,
+		api *whatsappApi.Api,
+		users *whatsappUser.Repository,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		session, err := users.GetUserById(id)
+		if err != nil {
+			return fmt.Errorf("session %d: %w", id, err)
+		}
+		if session.Jid == "" {
+			return whatsapp.ErrNotPaired
+		}
+		wasConnected := isConnected(session)
+		if wasConnected && !*force {
+			return ErrSessionConnected
+		}
+
+		userInfo := w.UserToUserInfo(session)
+		w.UpdateCacheUserInfo(session.Token, userInfo)
+		w.NewKillChannel(id)
+		go w.StartClient(id, session.Jid, session.Token, []string{})
+
+		ready, err := waitFor(*timeout, func() (bool, error) {
+			client, err := w.GetClient(id)
+			if err != nil {
+				return false, nil
+			}
+			return client.IsConnected() && client.IsLoggedIn(), nil
+		})
+		if err != nil {
+			return err
+		}
+		if !ready {
+			return fmt.Errorf("session %d did not connect within %s", id, *timeout)
+		}
+
+		resp, sendErr := api.SendText(context.Background(), &userInfo, &whatsappApi.SendTextPayload{Phone: phone, Body: text})
+
+		if client, err := w.GetClient(id); err == nil {
+			client.Disconnect()
+		}
+		// leave the session the way the server expects to find it
+		connected := 0
+		if wasConnected {
+			connected = 1
+		}
+		if err := users.SetUserConnected(id, connected); err != nil {
+			log.Warn().Err(err).Msg("Failed to restore the connected state")
+		}
+
+		if sendErr != nil {
+			return sendErr
+		}
+		record(audits, log, "message.send", "session", strconv.Itoa(id), nil, phone)
+		fmt.Printf("sent message %s to %s\n", resp.ID, phone)
+		return nil
+	})
+}
+
+func exportSession(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session export", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		return fmt.Errorf("%w\n\nsession export needs an id and optionally a file", errUsage)
+	}
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%w\n\ninvalid session id %q", errUsage, flags.Arg(0))
+	}
+	file := flags.Arg(1)
+
+	return boot(cfg, func(
+		w *whatsapp.Whatsapp,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		export, err := w.ExportSession(id)
+		if err != nil {
+			return fmt.Errorf("session %d: %w", id, err)
+		}
+
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+
+		if file == "" {
+			if _, err := os.Stdout.Write(data); err != nil {
+				return err
+			}
+		} else if err := os.WriteFile(file, data, 0o600); err != nil {
+			return err
+		}
+
+		record(audits, log, "session.export", "session", strconv.Itoa(id), nil, nil)
+		if file != "" {
+			fmt.Fprintf(os.Stderr, "exported session %d to %s, it holds the keys of the device so keep it safe\n", id, file)
+		}
+		return nil
+	})
+}
+
+func importSession(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("session import", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("%w\n\nsession import takes at most a file", errUsage)
+	}
+
+	var in io.Reader = os.Stdin
+	if file := flags.Arg(0); file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var export whatsapp.Export
+	if err := json.NewDecoder(in).Decode(&export); err != nil {
+		return fmt.Errorf("%w: %v", whatsapp.ErrBadExport, err)
+	}
+
+	return boot(cfg, func(
+		w *whatsapp.Whatsapp,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		imported, err := w.ImportSession(&export)
+		if err != nil {
+			return err
+		}
+
+		record(audits, log, "session.import", "session", strconv.Itoa(imported.Id), nil, imported.Session())
+		fmt.Printf("imported session %s (id %d) paired with %s\n", imported.Name, imported.Id, imported.Jid)
+		if isConnected(imported) {
+			fmt.Println("the server connects it when it starts, stop the instance it came from first")
+		}
+		return nil
+	})
+}
+
+func parseSessionId(flags *flag.FlagSet, args []string) (int, error) {
+	target, err := parseTarget(flags, args, "session id")
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(target)
+	if err != nil {
+		return 0, fmt.Errorf("%w\n\ninvalid session id %q", errUsage, target)
+	}
+	return id, nil
+}
+
+// waitFor polls done every second until it reports true or timeout passes
+func waitFor(timeout time.Duration, done func() (bool, error)) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := done()
+		if ok || err != nil {
+			return ok, err
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func isConnected(session *whatsappUser.User) bool {
+	return session.Connected != nil && *session.Connected == 1
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/nugrhrizki/buzz/internal/authsession"
+	"github.com/nugrhrizki/buzz/internal/lockout"
+	"github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/internal/user"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/password"
+)
+
+func userCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "create":
+		return createUser(cfg, args[1:])
+	case "reset-password":
+		return resetPassword(cfg, args[1:])
+	case "unlock":
+		return unlockUser(cfg, args[1:])
+	default:
+		return fmt.Errorf("%w\n\nunknown user command %q", errUsage, args[0])
+	}
+}
+
+func createUser(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	name := flags.String("name", "", "display name, the username by default")
+	username := flags.String("username", "", "username to log in with (required)")
+	pass := flags.String("password", "", "password, a random one is generated and printed when empty")
+	roleName := flags.String("role", "Super Admin", "name of the role")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("%w\n\nuser create needs -username", errUsage)
+	}
+	if *name == "" {
+		*name = *username
+	}
+
+	return boot(cfg, func(
+		users *user.Repository,
+		roles *role.Repository,
+		password *password.Password,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		role, err := roles.GetRoleByRolename(*roleName)
+		if err != nil {
+			return fmt.Errorf("role %q: %w", *roleName, err)
+		}
+
+		secret, generated, err := choosePassword(cfg, *pass)
+		if err != nil {
+			return err
+		}
+		hash, err := password.GenerateHashPassword(secret)
+		if err != nil {
+			return err
+		}
+
+		created := &user.User{
+			Name:     *name,
+			Username: *username,
+			Password: hash,
+			RoleId:   role.Id,
+			// nobody but the user should keep using a password they did not pick
+			MustChange: generated,
+		}
+		if err := users.CreateUser(created); err != nil {
+			return err
+		}
+		if created, err = users.GetUserByUsername(*username); err != nil {
+			return err
+		}
+
+		record(audits, log, "user.create", "user", created.Username, nil, created)
+		fmt.Printf("created user %s (id %d) with role %s\n", created.Username, created.Id, role.Name)
+		if generated {
+			fmt.Printf("password: %s\nit has to be changed on the first login\n", secret)
+		}
+		return nil
+	})
+}
+
+func resetPassword(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	pass := flags.String("password", "", "new password, a random one is generated and printed when empty")
+	username, err := parseTarget(flags, args, "username")
+	if err != nil {
+		return err
+	}
+
+	return boot(cfg, func(
+		users *user.Repository,
+		logins *authsession.Repository,
+		attempts *lockout.Repository,
+		password *password.Password,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		target, err := users.GetUserByUsername(username)
+		if err != nil {
+			return fmt.Errorf("user %q: %w", username, err)
+		}
+
+		secret, generated, err := choosePassword(cfg, *pass)
+		if err != nil {
+			return err
+		}
+		hash, err := password.GenerateHashPassword(secret)
+		if err != nil {
+			return err
+		}
+
+		if err := users.SetPassword(target.Id, hash, true); err != nil {
+			return err
+		}
+		if err := logins.RevokeUserSessions(target.Id); err != nil {
+			return err
+		}
+		if err := attempts.Reset(lockout.UserKey(target.Username)); err != nil {
+			return err
+		}
+
+		record(audits, log, "password.reset", "user", target.Username, nil, nil)
+		fmt.Printf("password of %s reset, their sessions are logged out\n", target.Username)
+		if generated {
+			fmt.Printf("password: %s\n", secret)
+		}
+		fmt.Println("it has to be changed on the next login")
+		return nil
+	})
+}
+
+func unlockUser(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("user unlock", flag.ContinueOnError)
+	username, err := parseTarget(flags, args, "username")
+	if err != nil {
+		return err
+	}
+
+	return boot(cfg, func(
+		users *user.Repository,
+		attempts *lockout.Repository,
+		audits *audit.Repository,
+		log *zerolog.Logger,
+	) error {
+		target, err := users.GetUserByUsername(username)
+		if err != nil {
+			return fmt.Errorf("user %q: %w", username, err)
+		}
+		if err := attempts.Reset(lockout.UserKey(target.Username)); err != nil {
+			return err
+		}
+
+		record(audits, log, "login.unlocked", "user", target.Username, nil, nil)
+		fmt.Printf("%s can log in again\n", target.Username)
+		return nil
+	})
+}
+
+// choosePassword checks a password given on the command line or generates
+// one, generated tells which
+func choosePassword(cfg *config.Config, given string) (secret string, generated bool, err error) {
+	if given != "" {
+		if len(given) < cfg.Password.MinLength {
+			return "", false, fmt.Errorf("password is too short, use at least %d characters", cfg.Password.MinLength)
+		}
+		return given, false, nil
+	}
+
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false, err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), true, nil
+}
+
+// parseTarget parses flags and returns the single argument that names what a
+// command works on. Flags may come before or after it.
+func parseTarget(flags *flag.FlagSet, args []string, what string) (string, error) {
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	rest := flags.Args()
+	if len(rest) == 0 {
+		return "", fmt.Errorf("%w\n\n%s needs a %s", errUsage, flags.Name(), what)
+	}
+	target := rest[0]
+	if err := flags.Parse(rest[1:]); err != nil {
+		return "", err
+	}
+	if flags.NArg() > 0 {
+		return "", fmt.Errorf("%w\n\n%s takes a single %s", errUsage, flags.Name(), what)
+	}
+	return target, nil
+}
+
+// record keeps an audit event of a change buzzctl made, failing to do so
+// does not undo the change
+func record(audits *audit.Repository, log *zerolog.Logger, action string, targetType string, targetId string, before any, after any) {
+	event := &audit.Event{
+		ActorType:  actor.Type,
+		ActorId:    actor.Id,
+		ActorName:  actor.Name,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		UserAgent:  "buzzctl",
+	}
+	event.Before, event.After = audit.Diff(before, after)
+
+	if err := audits.Record(event); err != nil {
+		log.Warn().Err(err).Str("action", action).Msg("Could not record audit event")
+	}
+}
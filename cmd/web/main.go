@@ -2,86 +2,116 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/gofiber/contrib/fiberzerolog"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/rs/zerolog"
 	"go.uber.org/fx"
 
 	"github.com/nugrhrizki/buzz/cmd/web/routes"
 
+	"github.com/nugrhrizki/buzz/pkg/config"
 	"github.com/nugrhrizki/buzz/pkg/database"
-	"github.com/nugrhrizki/buzz/pkg/env"
-	"github.com/nugrhrizki/buzz/pkg/log"
-	"github.com/nugrhrizki/buzz/pkg/password"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/nugrhrizki/buzz/pkg/metrics"
+	"github.com/nugrhrizki/buzz/pkg/telemetry"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp"
 	whatsappApi "github.com/nugrhrizki/buzz/pkg/whatsapp/api"
-	whatsappUser "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
 
+	auditHandler "github.com/nugrhrizki/buzz/internal/api/audit"
 	authHandler "github.com/nugrhrizki/buzz/internal/api/auth"
+	flagHandler "github.com/nugrhrizki/buzz/internal/api/flag"
+	inboxHandler "github.com/nugrhrizki/buzz/internal/api/inbox"
 	roleHandler "github.com/nugrhrizki/buzz/internal/api/role"
+	systemHandler "github.com/nugrhrizki/buzz/internal/api/system"
 	userHandler "github.com/nugrhrizki/buzz/internal/api/user"
 	whatsappHandler "github.com/nugrhrizki/buzz/internal/api/whatsapp"
 
-	"github.com/nugrhrizki/buzz/internal/role"
-	"github.com/nugrhrizki/buzz/internal/user"
-)
-
-var (
-	prefork = flag.Bool("prefork", false, "enable prefork")
-	port    = flag.Int("port", 3000, "port to listen on")
-	tz      = flag.String("tz", "Asia/Jakarta", "timezone")
-	dev     = flag.Bool("dev", false, "enable development mode")
+	"github.com/nugrhrizki/buzz/internal/app"
+	featureFlag "github.com/nugrhrizki/buzz/internal/flag"
 )
 
 func server(
 	lc fx.Lifecycle,
+	cfg *config.Config,
 	router *routes.Router,
 	db *database.Database,
 	whatsapp *whatsapp.Whatsapp,
-	users *whatsappUser.Repository,
-	user *user.Repository,
-	role *role.Repository,
+	api *whatsappApi.Api,
+	flags *featureFlag.Evaluator,
+	schema app.Schema,
+	metrics *metrics.Metrics,
+	logs *buzzLog.Buffer,
+	telemetry *telemetry.Telemetry,
 	log *zerolog.Logger,
 ) *fiber.App {
-	db.Migrate(users, role, user)
-	db.Seeder(role, user)
+	if err := db.Migrate(schema.Modules()...); err != nil {
+		log.Fatal().Err(err).Msg("failed to migrate database")
+	}
+	schema.Seed(db)
 
 	app := fiber.New(fiber.Config{
-		Prefork: *prefork,
+		Prefork: cfg.Server.Prefork,
 	})
 
 	defer app.Shutdown()
 
 	app.Use(recover.New())
+	// spans are started even when they are not exported, the logs of a
+	// request still carry the trace id of its caller
+	app.Use(telemetry.Middleware())
+	if metrics.Enabled() {
+		app.Use(metrics.Middleware())
+		if cfg.Metrics.Token == "" && !cfg.Server.Dev {
+			log.Warn().Msg("metrics are served without a token, set metrics.token")
+		}
+	}
+	app.Use(requestid.New())
+	app.Use(buzzLog.Middleware(log))
 	app.Use(fiberzerolog.New(fiberzerolog.Config{
-		Logger: log,
+		GetLogger: func(c *fiber.Ctx) zerolog.Logger {
+			return *buzzLog.Request(c, log)
+		},
 	}))
-	if *dev {
+	if cfg.Server.Dev {
 		log.Info().Msg("development mode enabled")
+	} else {
+		log.Info().Msg("development mode disabled")
+	}
+	if len(cfg.Server.CorsOrigins) > 0 {
 		app.Use(cors.New(cors.Config{
-			AllowOrigins:     "http://localhost:5173",
+			AllowOrigins:     strings.Join(cfg.Server.CorsOrigins, ","),
 			AllowCredentials: true,
 		}))
-	} else {
-		log.Info().Msg("development mode disabled")
 	}
 
 	router.Setup(app)
 
+	// flags switch features per session without a restart
+	whatsapp.OnAutoDownload(func(userID int) bool {
+		return flags.SessionEnabled(featureFlag.MediaAutoDownload, userID)
+	})
+	api.OnHumanize(func(userID int) bool {
+		return flags.SessionEnabled(featureFlag.HumanizedTyping, userID)
+	})
+
 	whatsapp.ConnectOnStartup()
 
 	lc.Append(fx.Hook{
 		OnStart: func(context.Context) error {
-			go app.Listen(fmt.Sprintf(":%d", *port))
+			go app.Listen(fmt.Sprintf(":%d", cfg.Server.Port))
 			return nil
 		},
 		OnStop: func(context.Context) error {
+			logs.Close()
 			return app.Shutdown()
 		},
 	})
@@ -89,29 +119,42 @@ func server(
 	return app
 }
 
+// providers adds the web handlers to what buzzctl shares, fx only
+// constructs what is asked for
+var providers = fx.Options(
+	app.Providers,
+	fx.Provide(
+		routes.New,
+
+		authHandler.NewAuthApi,
+		roleHandler.NewRoleApi,
+		userHandler.NewUserApi,
+		whatsappHandler.NewWhatsappAPI,
+		inboxHandler.NewInboxApi,
+		auditHandler.NewAuditApi,
+		systemHandler.NewSystemApi,
+		flagHandler.NewFlagApi,
+	),
+)
+
 func main() {
-	flag.Parse()
-	os.Setenv("TZ", *tz)
+	cfg, args, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Setenv("TZ", cfg.Server.Timezone)
+
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(migrate(cfg, args[1:]))
+	}
 
 	fx.New(
-		fx.Provide(
-			database.New,
-			env.New,
-			log.New,
-			routes.New,
-			whatsappApi.New,
-			whatsapp.New,
-
-			role.NewRepository,
-			user.NewRepository,
-			password.NewPassword,
-			whatsappUser.NewRepository,
-
-			authHandler.NewAuthApi,
-			roleHandler.NewRoleApi,
-			userHandler.NewUserApi,
-			whatsappHandler.NewWhatsappAPI,
-		),
+		providers,
+		fx.Supply(cfg),
 		fx.Invoke(server),
 	).Run()
 }
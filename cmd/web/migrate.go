@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"go.uber.org/fx"
+
+	"github.com/nugrhrizki/buzz/internal/app"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+const migrateUsage = "usage: buzz migrate <command>\n\n" + app.MigrateUsage
+
+// migrate runs the migrate subcommand and returns the exit code
+func migrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	var err error
+	fxApp := fx.New(
+		app.Providers,
+		fx.Supply(cfg),
+		fx.NopLogger,
+		fx.Invoke(func(db *database.Database, s app.Schema) {
+			err = app.Migrate(db, s, args, os.Stdout)
+		}),
+	)
+	if appErr := fxApp.Err(); appErr != nil {
+		fmt.Fprintln(os.Stderr, appErr)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+	sessions "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+// actor tells the audit middleware who made a request. Requests made with an
+// api key are not recorded generically, only the changes the whatsapp
+// handlers describe.
+func (r *Router) actor(c *fiber.Ctx) (audit.Actor, bool) {
+	if token, ok := c.Locals("user").(*jwt.Token); ok {
+		claims := token.Claims.(jwt.MapClaims)
+		uid, _ := claims["uid"].(float64)
+		username, _ := claims["username"].(string)
+		return audit.Actor{
+			Type: audit.ActorUser,
+			Id:   strconv.FormatInt(int64(uid), 10),
+			Name: username,
+		}, true
+	}
+
+	if key, ok := c.Locals("apikey").(*apikey.ApiKey); ok {
+		return audit.Actor{
+			Type: audit.ActorApiKey,
+			Id:   strconv.FormatInt(key.Id, 10),
+			Name: key.Prefix,
+		}, false
+	}
+
+	if userInfo, ok := c.Locals("userinfo").(sessions.UserInfo); ok {
+		return audit.Actor{
+			Type: audit.ActorSession,
+			Id:   userInfo.Id,
+			Name: userInfo.Jid,
+		}, false
+	}
+
+	return audit.Actor{Type: audit.ActorAnonymous}, true
+}
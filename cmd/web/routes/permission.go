@@ -0,0 +1,171 @@
+package routes
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	roles "github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+)
+
+var errMissingClaims = errors.New("token is missing the uid or rid claim")
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"status":  "error",
+		"title":   "Unauthorized",
+		"message": message,
+	})
+}
+
+// activeLogin runs once the JWT is verified and rejects access tokens whose
+// login session was logged out or revoked
+func (r *Router) activeLogin(c *fiber.Ctx) error {
+	token := c.Locals("user").(*jwt.Token)
+	claims := token.Claims.(jwt.MapClaims)
+
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return unauthorized(c, "Your session has ended, please log in again")
+	}
+
+	active, err := r.logins.IsActive(int64(sid))
+	if err != nil || !active {
+		return unauthorized(c, "Your session has ended, please log in again")
+	}
+
+	for _, restriction := range restrictions {
+		if on, _ := claims[restriction.claim].(bool); on && !restriction.routes[c.Path()] {
+			return forbidden(c, restriction.message)
+		}
+	}
+
+	return c.Next()
+}
+
+// restrictions confine a login to a few routes until the user dealt with
+// whatever the claim asks for
+var restrictions = []struct {
+	claim   string
+	message string
+	routes  map[string]bool
+}{
+	{
+		claim:   "pwd_change",
+		message: "You have to change your password to continue",
+		routes: map[string]bool{
+			"/api/v1/auth/identify":        true,
+			"/api/v1/auth/change-password": true,
+		},
+	},
+	{
+		claim:   "mfa_setup",
+		message: "Your role requires two-factor authentication, set it up to continue",
+		routes: map[string]bool{
+			"/api/v1/auth/identify":   true,
+			"/api/v1/auth/2fa/setup":  true,
+			"/api/v1/auth/2fa/enable": true,
+		},
+	},
+}
+
+func forbidden(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"status":  "error",
+		"title":   "Forbidden",
+		"message": message,
+	})
+}
+
+// permissions resolves the dashboard user and the permissions of their role
+// from the JWT claims. It must run after the auth middleware.
+func (r *Router) permissions(c *fiber.Ctx) (int64, roles.Permissions, error) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return 0, nil, errMissingClaims
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return 0, nil, errMissingClaims
+	}
+	rid, ok := claims["rid"].(float64)
+	if !ok {
+		return 0, nil, errMissingClaims
+	}
+
+	permissions, err := r.roles.GetPermissions(int(rid))
+	if err != nil {
+		return 0, nil, err
+	}
+	return int64(uid), permissions, nil
+}
+
+// can only lets the request through when the role in the JWT rid claim grants
+// the permission
+func (r *Router) can(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		_, permissions, err := r.permissions(c)
+		if err != nil {
+			r.log.Error().Err(err).Msg("Failed to resolve role permissions")
+			return forbidden(c, "You are not allowed to do this")
+		}
+
+		if !permissions.Can(permission) {
+			return forbidden(c, "You need the "+permission+" permission to do this")
+		}
+
+		return c.Next()
+	}
+}
+
+// scopePermissions are the permissions a dashboard user needs for the session
+// routes an api key needs the scope for
+var scopePermissions = map[string]string{
+	apikey.ScopeSend:  roles.PermissionSessionSend,
+	apikey.ScopeRead:  roles.PermissionSessionRead,
+	apikey.ScopeAdmin: roles.PermissionSessionUpdate,
+}
+
+// canScope is can for the permission matching an api key scope
+func (r *Router) canScope(scope string) fiber.Handler {
+	return r.can(scopePermissions[scope])
+}
+
+// ownsSession scopes session routes to the sessions assigned to the caller,
+// unless their role grants session:all. The session id is read from param,
+// leave it empty for routes that do not target a single session.
+func (r *Router) ownsSession(param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, permissions, err := r.permissions(c)
+		if err != nil {
+			r.log.Error().Err(err).Msg("Failed to resolve role permissions")
+			return forbidden(c, "You are not allowed to do this")
+		}
+
+		if permissions.Can(roles.PermissionSessionAll) {
+			return c.Next()
+		}
+		c.Locals("sessionOwner", uid)
+
+		if param == "" {
+			return c.Next()
+		}
+
+		id, err := strconv.Atoi(c.Params(param))
+		if err != nil {
+			return forbidden(c, "You are not allowed to access this session")
+		}
+
+		session, err := r.sessions.GetUserById(id)
+		if err != nil || session.OwnerId == nil || *session.OwnerId != uid {
+			return forbidden(c, "You are not allowed to access this session")
+		}
+
+		return c.Next()
+	}
+}
@@ -4,12 +4,25 @@ import (
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
+	"github.com/nugrhrizki/buzz/internal/api/audit"
 	"github.com/nugrhrizki/buzz/internal/api/auth"
+	"github.com/nugrhrizki/buzz/internal/api/flag"
+	"github.com/nugrhrizki/buzz/internal/api/inbox"
 	"github.com/nugrhrizki/buzz/internal/api/role"
+	"github.com/nugrhrizki/buzz/internal/api/system"
 	"github.com/nugrhrizki/buzz/internal/api/user"
 	"github.com/nugrhrizki/buzz/internal/api/whatsapp"
-	"github.com/nugrhrizki/buzz/pkg/env"
+	"github.com/nugrhrizki/buzz/internal/authsession"
+	roles "github.com/nugrhrizki/buzz/internal/role"
+	audits "github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/health"
+	"github.com/nugrhrizki/buzz/pkg/metrics"
+	"github.com/nugrhrizki/buzz/pkg/token"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+	sessions "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
 	"github.com/nugrhrizki/buzz/web"
+	"github.com/rs/zerolog"
 )
 
 type Router struct {
@@ -17,7 +30,19 @@ type Router struct {
 	user     *user.UserApi
 	role     *role.RoleApi
 	auth     *auth.AuthApi
-	env      *env.Env
+	inbox    *inbox.InboxApi
+	audit    *audit.AuditApi
+	system   *system.SystemApi
+	flag     *flag.FlagApi
+	roles    *roles.Repository
+	sessions *sessions.Repository
+	logins   *authsession.Repository
+	audits   *audits.Repository
+	token    *token.Token
+	metrics  *metrics.Metrics
+	health   *health.Health
+	config   *config.Config
+	log      *zerolog.Logger
 }
 
 func New(
@@ -25,78 +50,148 @@ func New(
 	user *user.UserApi,
 	role *role.RoleApi,
 	auth *auth.AuthApi,
-	env *env.Env,
+	inbox *inbox.InboxApi,
+	audit *audit.AuditApi,
+	system *system.SystemApi,
+	flag *flag.FlagApi,
+	roles *roles.Repository,
+	sessions *sessions.Repository,
+	logins *authsession.Repository,
+	audits *audits.Repository,
+	token *token.Token,
+	metrics *metrics.Metrics,
+	health *health.Health,
+	config *config.Config,
+	log *zerolog.Logger,
 ) *Router {
 	return &Router{
 		whatsapp: whatsapp,
 		user:     user,
 		role:     role,
 		auth:     auth,
-		env:      env,
+		inbox:    inbox,
+		audit:    audit,
+		system:   system,
+		flag:     flag,
+		roles:    roles,
+		sessions: sessions,
+		logins:   logins,
+		audits:   audits,
+		token:    token,
+		metrics:  metrics,
+		health:   health,
+		config:   config,
+		log:      log,
 	}
 }
 
 func (r *Router) Setup(app *fiber.App) {
+	signingKeys := map[string]jwtware.SigningKey{}
+	for kid, key := range r.token.Keys() {
+		signingKeys[kid] = jwtware.SigningKey{JWTAlg: jwtware.HS256, Key: key}
+	}
+
 	authMiddleware := jwtware.New(jwtware.Config{
-		SigningKey:  jwtware.SigningKey{Key: []byte(r.env.Secret)},
-		TokenLookup: "cookie:auth-token",
+		SigningKeys:    signingKeys,
+		TokenLookup:    "cookie:auth-token",
+		SuccessHandler: r.activeLogin,
 	})
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.SendString("healthy")
 	})
+	app.Get("/livez", r.health.Livez)
+	app.Get("/readyz", r.health.Readyz)
+
+	if r.metrics.Enabled() {
+		app.Get("/metrics", r.metrics.Handler())
+	}
 
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
+	v1.Use(r.audits.Middleware(r.log, r.actor))
 
 	auth := v1.Group("/auth")
 	auth.Post("/login", r.auth.Login)
-	auth.Post("/register", authMiddleware, r.auth.CreateUser)
-	auth.Post("/logout", authMiddleware, r.auth.Logout)
+	auth.Post("/register", authMiddleware, r.can(roles.PermissionUserCreate), r.auth.CreateUser)
+	auth.Post("/refresh", r.auth.Refresh)
+	auth.Post("/logout", r.auth.Logout)
+	auth.Post("/force-logout/:id", authMiddleware, r.can(roles.PermissionUserUpdate), r.auth.ForceLogout)
+	auth.Post("/unlock/:id", authMiddleware, r.can(roles.PermissionUserUpdate), r.auth.Unlock)
+	auth.Post("/change-password", authMiddleware, r.auth.ChangePassword)
+	auth.Post("/reset-password", r.auth.ResetPassword)
+	auth.Post("/reset-password/:id", authMiddleware, r.can(roles.PermissionUserUpdate), r.auth.IssuePasswordReset)
+	auth.Post("/2fa/setup", authMiddleware, r.auth.SetupTwoFactor)
+	auth.Post("/2fa/enable", authMiddleware, r.auth.EnableTwoFactor)
+	auth.Post("/2fa/disable", authMiddleware, r.auth.DisableTwoFactor)
+	auth.Post("/2fa/recovery-codes", authMiddleware, r.auth.RegenerateRecoveryCodes)
+	auth.Post("/2fa/reset/:id", authMiddleware, r.can(roles.PermissionUserUpdate), r.auth.ResetTwoFactor)
 	auth.Get("/identify", authMiddleware, r.auth.IdentifyUser)
 
-	v1.Post("/whatsapp/create-user", r.whatsapp.CreateUser)
-	v1.Put("/whatsapp/update-user/:id", r.whatsapp.UpdateUser)
-	v1.Delete("/whatsapp/delete-user/:id", r.whatsapp.DeleteUser)
-	v1.Get("/whatsapp/users", r.whatsapp.GetWhatsappUser)
-	v1.Get("/whatsapp/user/:id", r.whatsapp.GetWhatsappUserById)
+	v1.Post("/whatsapp/create-user", authMiddleware, r.can(roles.PermissionSessionCreate), r.ownsSession(""), r.whatsapp.CreateUser)
+	v1.Put("/whatsapp/update-user/:id", authMiddleware, r.can(roles.PermissionSessionUpdate), r.ownsSession("id"), r.whatsapp.UpdateUser)
+	v1.Delete("/whatsapp/delete-user/:id", authMiddleware, r.can(roles.PermissionSessionDelete), r.ownsSession("id"), r.whatsapp.DeleteUser)
+	v1.Get("/whatsapp/users", authMiddleware, r.can(roles.PermissionSessionRead), r.ownsSession(""), r.whatsapp.GetWhatsappUser)
+	v1.Get("/whatsapp/user/:id", authMiddleware, r.can(roles.PermissionSessionRead), r.ownsSession("id"), r.whatsapp.GetWhatsappUserById)
+	v1.Get("/whatsapp/user/:id/keys", authMiddleware, r.can(roles.PermissionSessionRead), r.ownsSession("id"), r.whatsapp.GetApiKeys)
+	v1.Post("/whatsapp/user/:id/keys", authMiddleware, r.can(roles.PermissionSessionUpdate), r.ownsSession("id"), r.whatsapp.CreateApiKey)
+	v1.Delete("/whatsapp/user/:id/keys/:key", authMiddleware, r.can(roles.PermissionSessionUpdate), r.ownsSession("id"), r.whatsapp.RevokeApiKey)
+
+	// the dashboard drives the sessions it manages through the same api,
+	// naming the session in the path instead of presenting a key. It has to
+	// come before the key authenticated group, which matches every path.
+	dashboardSession := v1.Group("/whatsapp/user/:id/session", authMiddleware, r.ownsSession("id"), r.whatsapp.SessionInfo)
+	r.sessionRoutes(dashboardSession, r.canScope)
+
 	whatsapp := v1.Group("/whatsapp", r.whatsapp.UserInfo)
-	whatsapp.Post("/connect", r.whatsapp.Connect)
-	whatsapp.Post("/disconnect", r.whatsapp.Disconnect)
-	whatsapp.Get("/webhook", r.whatsapp.GetWebhook)
-	whatsapp.Post("/webhook", r.whatsapp.SetWebhook)
-	whatsapp.Get("/qr", r.whatsapp.GetQR)
-	whatsapp.Post("/logout", r.whatsapp.Logout)
-	whatsapp.Get("/status", r.whatsapp.GetStatus)
-	whatsapp.Post("/send-document", r.whatsapp.SendDocument)
-	whatsapp.Post("/send-audio", r.whatsapp.SendAudio)
-	whatsapp.Post("/send-image", r.whatsapp.SendImage)
-	whatsapp.Post("/send-sticker", r.whatsapp.SendSticker)
-	whatsapp.Post("/send-video", r.whatsapp.SendVideo)
-	whatsapp.Post("/send-contact", r.whatsapp.SendContact)
-	whatsapp.Post("/send-location", r.whatsapp.SendLocation)
-	whatsapp.Post("/send-button", r.whatsapp.SendButton)
-	whatsapp.Post("/send-list", r.whatsapp.SendList)
-	whatsapp.Post("/send-text", r.whatsapp.SendText)
-	whatsapp.Post("/check-user", r.whatsapp.CheckUser)
-	whatsapp.Post("/user", r.whatsapp.GetUser)
-	whatsapp.Post("/avatar", r.whatsapp.GetAvatar)
-	whatsapp.Post("/contacts", r.whatsapp.GetContacts)
-	whatsapp.Post("/send-chat-presence", r.whatsapp.SendChatPresence)
+	admin := r.whatsapp.Scope(apikey.ScopeAdmin)
+	whatsapp.Get("/keys", admin, r.whatsapp.GetApiKeys)
+	whatsapp.Post("/keys", admin, r.whatsapp.CreateApiKey)
+	whatsapp.Delete("/keys/:key", admin, r.whatsapp.RevokeApiKey)
+	r.sessionRoutes(whatsapp, r.whatsapp.Scope)
 
 	user := v1.Group("/user", authMiddleware)
-	user.Post("/create", r.user.CreateUser)
-	user.Post("/get", r.user.GetUser)
-	user.Get("/get-all", r.user.GetUsers)
-	user.Put("/update/:id", r.user.UpdateUser)
-	user.Delete("/delete/:id", r.user.DeleteUser)
+	user.Post("/create", r.can(roles.PermissionUserCreate), r.user.CreateUser)
+	user.Post("/get", r.can(roles.PermissionUserRead), r.user.GetUser)
+	user.Get("/get-all", r.can(roles.PermissionUserRead), r.user.GetUsers)
+	user.Put("/update/:id", r.can(roles.PermissionUserUpdate), r.user.UpdateUser)
+	user.Delete("/delete/:id", r.can(roles.PermissionUserDelete), r.user.DeleteUser)
 
 	role := v1.Group("/role", authMiddleware)
-	role.Post("/create", r.role.CreateRole)
-	role.Post("/get", r.role.GetRole)
-	role.Get("/get-all", r.role.GetRoles)
-	role.Put("/update/:id", r.role.UpdateRole)
-	role.Delete("/delete/:id", r.role.DeleteRole)
+	role.Post("/create", r.can(roles.PermissionRoleCreate), r.role.CreateRole)
+	role.Post("/get", r.can(roles.PermissionRoleRead), r.role.GetRole)
+	role.Get("/get-all", r.can(roles.PermissionRoleRead), r.role.GetRoles)
+	role.Put("/update/:id", r.can(roles.PermissionRoleUpdate), r.role.UpdateRole)
+	role.Delete("/delete/:id", r.can(roles.PermissionRoleDelete), r.role.DeleteRole)
+
+	inbox := v1.Group("/inbox", authMiddleware)
+	inbox.Use("/:session/conversations", r.ownsSession("session"))
+	inbox.Get("/canned-replies", r.can(roles.PermissionInboxRead), r.inbox.GetCannedReplies)
+	inbox.Post("/canned-replies", r.can(roles.PermissionInboxManage), r.inbox.CreateCannedReply)
+	inbox.Put("/canned-replies/:id", r.can(roles.PermissionInboxManage), r.inbox.UpdateCannedReply)
+	inbox.Delete("/canned-replies/:id", r.can(roles.PermissionInboxManage), r.inbox.DeleteCannedReply)
+	inbox.Get("/:session/conversations", r.can(roles.PermissionInboxRead), r.inbox.GetConversations)
+	inbox.Get("/:session/conversations/:chat/messages", r.can(roles.PermissionInboxRead), r.inbox.GetMessages)
+	inbox.Post("/:session/conversations/:chat/read", r.can(roles.PermissionInboxRead), r.inbox.MarkRead)
+	inbox.Put("/:session/conversations/:chat/status", r.can(roles.PermissionInboxManage), r.inbox.SetStatus)
+	inbox.Put("/:session/conversations/:chat/assign", r.can(roles.PermissionInboxManage), r.inbox.Assign)
+	inbox.Get("/:session/conversations/:chat/notes", r.can(roles.PermissionInboxRead), r.inbox.GetNotes)
+	inbox.Post("/:session/conversations/:chat/notes", r.can(roles.PermissionInboxManage), r.inbox.CreateNote)
+	inbox.Post("/:session/conversations/:chat/reply", r.can(roles.PermissionInboxReply), r.inbox.Reply)
+
+	audit := v1.Group("/audit", authMiddleware, r.can(roles.PermissionAuditRead))
+	audit.Get("/", r.audit.GetEvents)
+	audit.Get("/export", r.audit.Export)
+
+	logs := v1.Group("/system/logs", authMiddleware, r.can(roles.PermissionLogRead))
+	logs.Get("/", r.system.GetLogs)
+	logs.Get("/tail", r.system.TailLogs)
+
+	flags := v1.Group("/system/flags", authMiddleware)
+	flags.Get("/", r.can(roles.PermissionFlagRead), r.flag.GetFlags)
+	flags.Post("/", r.can(roles.PermissionFlagCreate), r.flag.CreateFlag)
+	flags.Put("/:id", r.can(roles.PermissionFlagUpdate), r.flag.UpdateFlag)
+	flags.Delete("/:id", r.can(roles.PermissionFlagDelete), r.flag.DeleteFlag)
 
 	app.Get("/*", filesystem.New(filesystem.Config{
 		Root:   web.Dist(),
@@ -104,3 +199,61 @@ func (r *Router) Setup(app *fiber.App) {
 		MaxAge: 3600,
 	}))
 }
+
+// sessionRoutes mounts the api a session is operated with. guard turns the
+// scope a route needs into the check for the callers of the group, api keys
+// need the scope itself and dashboard users the matching permission. Routes
+// that only relay traffic are not audited, whoever calls them, the handlers
+// audit every change to the account.
+func (r *Router) sessionRoutes(session fiber.Router, guard func(scope string) fiber.Handler) {
+	send := guard(apikey.ScopeSend)
+	read := guard(apikey.ScopeRead)
+	admin := guard(apikey.ScopeAdmin)
+	session.Post("/connect", admin, r.whatsapp.Connect)
+	session.Post("/disconnect", admin, r.whatsapp.Disconnect)
+	session.Get("/webhook", admin, r.whatsapp.GetWebhook)
+	session.Post("/webhook", admin, r.whatsapp.SetWebhook)
+	session.Get("/call-policy", admin, r.whatsapp.GetCallPolicy)
+	session.Post("/call-policy", admin, r.whatsapp.SetCallPolicy)
+	session.Get("/qr", admin, r.whatsapp.GetQR)
+	session.Post("/logout", admin, r.whatsapp.Logout)
+	session.Get("/status", read, r.whatsapp.GetStatus)
+	session.Post("/send-document", send, audits.Skip, r.whatsapp.SendDocument)
+	session.Post("/send-audio", send, audits.Skip, r.whatsapp.SendAudio)
+	session.Post("/send-image", send, audits.Skip, r.whatsapp.SendImage)
+	session.Post("/send-sticker", send, audits.Skip, r.whatsapp.SendSticker)
+	session.Post("/send-video", send, audits.Skip, r.whatsapp.SendVideo)
+	session.Post("/send-contact", send, audits.Skip, r.whatsapp.SendContact)
+	session.Post("/send-location", send, audits.Skip, r.whatsapp.SendLocation)
+	session.Post("/send-button", send, audits.Skip, r.whatsapp.SendButton)
+	session.Post("/send-list", send, audits.Skip, r.whatsapp.SendList)
+	session.Post("/send-text", send, audits.Skip, r.whatsapp.SendText)
+	session.Post("/status", send, audits.Skip, r.whatsapp.SendStatus)
+	session.Post("/check-user", read, r.whatsapp.CheckUser)
+	session.Post("/user", read, r.whatsapp.GetUser)
+	session.Post("/avatar", read, r.whatsapp.GetAvatar)
+	session.Post("/contacts", read, r.whatsapp.GetContacts)
+	session.Post("/send-chat-presence", send, audits.Skip, r.whatsapp.SendChatPresence)
+	session.Post("/mark-read", send, audits.Skip, r.whatsapp.MarkRead)
+	session.Post("/chat/archive", send, r.whatsapp.ArchiveChat)
+	session.Post("/chat/pin", send, r.whatsapp.PinChat)
+	session.Post("/chat/mute", send, r.whatsapp.MuteChat)
+	session.Post("/chat/mark-unread", send, r.whatsapp.MarkChatUnread)
+	session.Post("/chat/delete", send, r.whatsapp.DeleteChat)
+	session.Post("/chat/clear", send, r.whatsapp.ClearChat)
+	session.Post("/profile/name", admin, r.whatsapp.SetPushName)
+	session.Post("/profile/about", admin, r.whatsapp.SetAbout)
+	session.Post("/profile/picture", admin, r.whatsapp.SetProfilePicture)
+	session.Get("/privacy", read, r.whatsapp.GetPrivacySettings)
+	session.Post("/privacy", admin, r.whatsapp.SetPrivacySetting)
+	session.Get("/blocklist", read, r.whatsapp.GetBlocklist)
+	session.Post("/block", admin, r.whatsapp.Block)
+	session.Post("/unblock", admin, r.whatsapp.Unblock)
+	session.Get("/newsletters", read, r.whatsapp.GetSubscribedNewsletters)
+	session.Post("/newsletter/create", send, r.whatsapp.CreateNewsletter)
+	session.Post("/newsletter/info", read, r.whatsapp.GetNewsletterInfo)
+	session.Post("/newsletter/follow", send, r.whatsapp.FollowNewsletter)
+	session.Post("/newsletter/unfollow", send, r.whatsapp.UnfollowNewsletter)
+	session.Post("/newsletter/send", send, audits.Skip, r.whatsapp.SendNewsletterMessage)
+	session.Post("/newsletter/messages", read, r.whatsapp.GetNewsletterMessages)
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/rs/zerolog"
+)
+
+// exportLimit caps how many events a single export can contain
+const exportLimit = 50000
+
+type AuditApi struct {
+	audits *audit.Repository
+	log    *zerolog.Logger
+}
+
+func NewAuditApi(audits *audit.Repository, log *zerolog.Logger) *AuditApi {
+	return &AuditApi{
+		audits: audits,
+		log:    log,
+	}
+}
+
+// filter reads the audit filter from the query string. from and to take
+// RFC 3339 timestamps or plain dates.
+func filter(c *fiber.Ctx) (audit.Filter, error) {
+	f := audit.Filter{
+		ActorType:  c.Query("actor_type"),
+		ActorId:    c.Query("actor_id"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetId:   c.Query("target_id"),
+		Limit:      c.QueryInt("limit", 50),
+		Offset:     c.QueryInt("offset"),
+	}
+
+	for param, dest := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse(time.DateOnly, value)
+		}
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, param+" should be a date or an RFC 3339 timestamp")
+		}
+		*dest = &t
+	}
+
+	return f, nil
+}
+
+func (aa *AuditApi) GetEvents(c *fiber.Ctx) error {
+	f, err := filter(c)
+	if err != nil {
+		return err
+	}
+	if f.Limit <= 0 || f.Limit > 500 {
+		f.Limit = 50
+	}
+
+	events, total, err := aa.audits.GetEvents(f)
+	if err != nil {
+		buzzLog.Request(c, aa.log).Error().Err(err).Msg("Failed to get audit events")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Audit events",
+		"message": "Audit events retrieved",
+		"data": fiber.Map{
+			"events": events,
+			"total":  total,
+		},
+	})
+}
+
+// Export downloads the matching events as CSV
+func (aa *AuditApi) Export(c *fiber.Ctx) error {
+	f, err := filter(c)
+	if err != nil {
+		return err
+	}
+	f.Limit, f.Offset = exportLimit, 0
+
+	events, _, err := aa.audits.GetEvents(f)
+	if err != nil {
+		buzzLog.Request(c, aa.log).Error().Err(err).Msg("Failed to export audit events")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Attachment("audit-" + time.Now().Format("20060102-150405") + ".csv")
+
+	w := csv.NewWriter(c.Response().BodyWriter())
+	w.Write([]string{
+		"id", "created_at", "actor_type", "actor_id", "actor_name", "action",
+		"target_type", "target_id", "method", "path", "status", "ip",
+		"user_agent", "before", "after",
+	})
+	for _, e := range events {
+		w.Write([]string{
+			strconv.FormatInt(e.Id, 10),
+			e.CreatedAt.Format(time.RFC3339),
+			e.ActorType,
+			e.ActorId,
+			e.ActorName,
+			e.Action,
+			e.TargetType,
+			e.TargetId,
+			e.Method,
+			e.Path,
+			strconv.Itoa(e.Status),
+			e.Ip,
+			e.UserAgent,
+			e.Before.String(),
+			e.After.String(),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
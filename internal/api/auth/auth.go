@@ -1,15 +1,26 @@
 package auth
 
 import (
+	"database/sql"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/nugrhrizki/buzz/internal/authsession"
+	"github.com/nugrhrizki/buzz/internal/lockout"
 	"github.com/nugrhrizki/buzz/internal/role"
 	"github.com/nugrhrizki/buzz/internal/user"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
 	"github.com/nugrhrizki/buzz/pkg/password"
+	"github.com/nugrhrizki/buzz/pkg/token"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/api"
+	wauser "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
 	"github.com/rs/zerolog"
 )
 
@@ -24,8 +35,15 @@ type HashParams struct {
 type AuthApi struct {
 	user     *user.Repository
 	role     *role.Repository
+	sessions *authsession.Repository
+	lockout  *lockout.Repository
 	log      *zerolog.Logger
 	password *password.Password
+	token    *token.Token
+	config   *config.Config
+	senders  *wauser.Repository
+	whatsapp *whatsapp.Whatsapp
+	api      *api.Api
 }
 
 var (
@@ -33,22 +51,95 @@ var (
 	ErrIncompatibleVersion = errors.New("incompatible version of argon2")
 )
 
-func NewAuthApi(user *user.Repository, role *role.Repository, log *zerolog.Logger, password *password.Password) *AuthApi {
+func NewAuthApi(
+	user *user.Repository,
+	role *role.Repository,
+	sessions *authsession.Repository,
+	lockout *lockout.Repository,
+	log *zerolog.Logger,
+	password *password.Password,
+	token *token.Token,
+	config *config.Config,
+	senders *wauser.Repository,
+	whatsapp *whatsapp.Whatsapp,
+	api *api.Api,
+) *AuthApi {
 	return &AuthApi{
 		user:     user,
 		role:     role,
+		sessions: sessions,
+		lockout:  lockout,
 		log:      log,
 		password: password,
+		token:    token,
+		config:   config,
+		senders:  senders,
+		whatsapp: whatsapp,
+		api:      api,
 	}
 }
 
+const refreshCookie = "refresh-token"
+
+// issueTokens signs a short lived access token for the session and sets it
+// together with the refresh token as cookies. Without a refresh token only
+// the access token is replaced.
+func (a *AuthApi) issueTokens(c *fiber.Ctx, user *user.User, session *authsession.Session, refreshToken string) error {
+	claims := jwt.MapClaims{
+		"uid":      user.Id,
+		"nama":     user.Name,
+		"username": user.Username,
+		"rid":      user.RoleId,
+		"sid":      session.Id,
+		"exp":      time.Now().Add(a.config.Auth.AccessTokenTTL).Unix(),
+	}
+	if user.MustChange {
+		claims["pwd_change"] = true
+	}
+	if a.requires2faSetup(user) {
+		claims["mfa_setup"] = true
+	}
+
+	tokenString, err := a.token.Sign(claims)
+	if err != nil {
+		return err
+	}
+
+	c.Cookie(a.cookie("auth-token", tokenString, "", time.Now().Add(a.config.Auth.AccessTokenTTL)))
+	if refreshToken == "" {
+		return nil
+	}
+	c.Cookie(a.cookie(refreshCookie, refreshToken, "/api/v1/auth", session.ExpiresAt))
+	return nil
+}
+
+// cookie builds a login cookie with the configured security attributes
+func (a *AuthApi) cookie(name string, value string, path string, expires time.Time) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   a.config.Auth.CookieDomain,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   a.config.Auth.CookieSecure,
+		SameSite: a.config.Auth.CookieSameSite,
+	}
+}
+
+func (a *AuthApi) clearCookies(c *fiber.Ctx) {
+	expired := time.Now().Add(-(time.Hour * 2))
+	c.Cookie(a.cookie("auth-token", "", "", expired))
+	c.Cookie(a.cookie(refreshCookie, "", "/api/v1/auth", expired))
+}
+
 func (a *AuthApi) IdentifyUser(c *fiber.Ctx) error {
 	userToken := c.Locals("user").(*jwt.Token)
 	claims := userToken.Claims.(jwt.MapClaims)
 
 	user, err := a.user.GetUserById(int(claims["uid"].(float64)))
 	if err != nil {
-		a.log.Error().Err(err).Msg("Failed to get user")
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to get user")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"status":  "error",
 			"title":   "Unauthorized",
@@ -65,9 +156,93 @@ func (a *AuthApi) IdentifyUser(c *fiber.Ctx) error {
 	})
 }
 
+// audit describes an authentication event about a user for the audit log
+func (a *AuthApi) audit(c *fiber.Ctx, action string, username string) {
+	audit.Describe(c, action, "user", username)
+}
+
+// loginFailed counts the failure against the username and the ip, locking
+// them when they reach their limit, and holds the response back a little
+// longer with every failure
+func (a *AuthApi) loginFailed(c *fiber.Ctx, username string) error {
+	limits := map[string]int{
+		lockout.UserKey(username): a.config.Login.MaxFailures,
+		lockout.IPKey(c.IP()):     a.config.Login.IPMaxFailures,
+	}
+
+	failures := 0
+	for key, max := range limits {
+		attempt, locked, err := a.lockout.Fail(key, max)
+		if err != nil {
+			buzzLog.Request(c, a.log).Error().Err(err).Str("key", key).Msg("Failed to record login attempt")
+			continue
+		}
+		if attempt.Failures > failures {
+			failures = attempt.Failures
+		}
+		if locked {
+			a.audit(c, "login.locked", key)
+		}
+	}
+
+	a.audit(c, "login.failed", username)
+	time.Sleep(a.lockout.Delay(failures))
+
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"status":  "error",
+		"title":   "Login Failed",
+		"message": "Username or password is incorrent",
+	})
+}
+
+// Unlock lifts the lockout of a user account, and of an ip when one is given
+func (a *AuthApi) Unlock(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "failed to convert id to int",
+		})
+	}
+
+	user, err := a.user.GetUserById(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "User not found",
+		})
+	}
+
+	keys := []string{lockout.UserKey(user.Username)}
+	if ip := c.Query("ip"); ip != "" {
+		keys = append(keys, lockout.IPKey(ip))
+	}
+
+	for _, key := range keys {
+		if err := a.lockout.Reset(key); err != nil {
+			buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to unlock")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"status":  "error",
+				"title":   "Oops, something went wrong",
+				"message": "Internal server error",
+			})
+		}
+		a.audit(c, "login.unlocked", key)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "User unlocked",
+		"message": "The user can log in again",
+	})
+}
+
 type LoginPayload struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Code     string `json:"code"`
 }
 
 func (a *AuthApi) Login(c *fiber.Ctx) error {
@@ -80,16 +255,33 @@ func (a *AuthApi) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	user, err := a.user.GetUserByUsername(request.Username)
+	until, err := a.lockout.LockedUntil(lockout.UserKey(request.Username), lockout.IPKey(c.IP()))
 	if err != nil {
-		a.log.Error().Err(err).Msg("Failed to get user")
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to check login lockout")
+	}
+	if until != nil {
+		retry := time.Until(*until).Round(time.Second)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retry.Seconds())))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 			"status":  "error",
-			"title":   "Login Failed",
-			"message": "Username or password is incorrent",
+			"title":   "Too many attempts",
+			"message": "Too many failed logins, please try again in " + retry.String(),
 		})
 	}
 
+	user, err := a.user.GetUserByUsername(request.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			buzzLog.Request(c, a.log).Debug().Msg("Login with an unknown username")
+		} else {
+			buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to get user")
+		}
+		// take as long as a wrong password would, unknown usernames should
+		// not stand out
+		a.password.CompareDummy(request.Password)
+		return a.loginFailed(c, request.Username)
+	}
+
 	isMatch, err := a.password.CompareHashPassword(request.Password, user.Password)
 	if err != nil {
 		log.Error(err)
@@ -101,25 +293,40 @@ func (a *AuthApi) Login(c *fiber.Ctx) error {
 	}
 
 	if !isMatch {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"status":  "error",
-			"title":   "Login Failed",
-			"message": "Username or password is incorrent",
-		})
+		return a.loginFailed(c, request.Username)
 	}
 
-	claims := jwt.MapClaims{
-		"uid":      user.Id,
-		"nama":     user.Name,
-		"username": user.Username,
-		"rid":      user.RoleId,
-		"exp":      time.Now().Add(time.Hour * 24 * 7).Unix(),
+	if user.TotpEnabled {
+		if request.Code == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"status":  "error",
+				"title":   "Two-factor authentication required",
+				"message": "Enter the code from your authenticator app or a recovery code",
+				"data": fiber.Map{
+					"mfa_required": true,
+				},
+			})
+		}
+
+		ok, err := a.verifySecondFactor(c, user, request.Code)
+		if err != nil {
+			buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to verify second factor")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"status":  "error",
+				"title":   "Login Failed",
+				"message": "Internal server error",
+			})
+		}
+		if !ok {
+			return a.loginFailed(c, request.Username)
+		}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret := "secret"
-	tokenString, err := token.SignedString([]byte(secret))
+	if err := a.lockout.Reset(lockout.UserKey(request.Username)); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to reset login attempts")
+	}
 
+	refreshToken, refreshHash, err := token.NewRefreshToken()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
@@ -128,16 +335,30 @@ func (a *AuthApi) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	cookie := fiber.Cookie{
-		Name:     "auth-token",
-		Value:    tokenString,
-		Expires:  time.Now().Add(time.Hour * 24 * 7),
-		HTTPOnly: true,
-		Secure:   false,
-		SameSite: "lax",
+	session := &authsession.Session{
+		UserId:      user.Id,
+		RefreshHash: refreshHash,
+		UserAgent:   c.Get(fiber.HeaderUserAgent),
+		Ip:          c.IP(),
+		ExpiresAt:   time.Now().Add(a.config.Auth.RefreshTokenTTL),
 	}
 
-	c.Cookie(&cookie)
+	if err := a.sessions.CreateSession(session); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to create session")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	if err := a.issueTokens(c, user, session, refreshToken); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
 
 	user.Password = ""
 
@@ -177,7 +398,7 @@ func (a *AuthApi) CreateUser(c *fiber.Ctx) error {
 	}
 
 	if err := a.user.CreateUser(&newUser); err != nil {
-		a.log.Error().Err(err).Msg("Failed to create user")
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to create user")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
 			"title":   "Failed register user",
@@ -195,16 +416,108 @@ func (a *AuthApi) CreateUser(c *fiber.Ctx) error {
 	})
 }
 
-func (a *AuthApi) Logout(c *fiber.Ctx) error {
-	c.Cookie(&fiber.Cookie{
-		Name:     "auth-token",
-		Expires:  time.Now().Add(-(time.Hour * 2)),
-		HTTPOnly: true,
-		SameSite: "lax",
+// Refresh trades the refresh token cookie for a new access token. The refresh
+// token is rotated on every use.
+func (a *AuthApi) Refresh(c *fiber.Ctx) error {
+	unauthorized := func() error {
+		a.clearCookies(c)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Unauthorized",
+			"message": "Your session has ended, please log in again",
+		})
+	}
+
+	refreshToken := c.Cookies(refreshCookie)
+	if refreshToken == "" {
+		return unauthorized()
+	}
+
+	session, err := a.sessions.GetSessionByRefreshHash(token.HashRefreshToken(refreshToken))
+	if err != nil || !session.Active() {
+		return unauthorized()
+	}
+
+	user, err := a.user.GetUserById(int(session.UserId))
+	if err != nil {
+		return unauthorized()
+	}
+
+	newToken, newHash, err := token.NewRefreshToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	if err := a.sessions.Rotate(session, newHash); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to rotate refresh token")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	if err := a.issueTokens(c, user, session, newToken); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Session refreshed",
+		"message": "Your session has been refreshed",
 	})
+}
+
+func (a *AuthApi) Logout(c *fiber.Ctx) error {
+	if refreshToken := c.Cookies(refreshCookie); refreshToken != "" {
+		session, err := a.sessions.GetSessionByRefreshHash(token.HashRefreshToken(refreshToken))
+		if err == nil {
+			if err := a.sessions.RevokeSession(session.Id); err != nil {
+				buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to revoke session")
+			}
+		}
+	}
+
+	a.clearCookies(c)
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"title":   "Logout successfully",
 		"message": "Have a nice day",
 	})
 }
+
+// ForceLogout ends every session of a user, their access tokens stop working
+// on the next request
+func (a *AuthApi) ForceLogout(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "failed to convert id to int",
+		})
+	}
+
+	if err := a.sessions.RevokeUserSessions(id); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to revoke user sessions")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "User logged out",
+		"message": "The user has been logged out everywhere",
+	})
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/nugrhrizki/buzz/internal/authsession"
+	"github.com/nugrhrizki/buzz/internal/lockout"
+	"github.com/nugrhrizki/buzz/internal/user"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/nugrhrizki/buzz/pkg/token"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/api"
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordReused   = errors.New("new password must differ from the old one")
+	ErrNoSystemSession  = errors.New("no system session is configured")
+	ErrNoWhatsapp       = errors.New("user has no whatsapp number")
+)
+
+type ChangePasswordPayload struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type ResetPasswordPayload struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (a *AuthApi) checkPassword(password string) error {
+	if len(password) < a.config.Password.MinLength {
+		return fmt.Errorf("%w, use at least %d characters", ErrPasswordTooShort, a.config.Password.MinLength)
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of the logged in user after checking
+// the current one. Other logins of the user are ended.
+func (a *AuthApi) ChangePassword(c *fiber.Ctx) error {
+	payload := new(ChangePasswordPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": err.Error(),
+		})
+	}
+
+	user, claims, err := a.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Unauthorized",
+			"message": "You are not authorized",
+		})
+	}
+
+	match, err := a.password.CompareHashPassword(payload.OldPassword, user.Password)
+	if err != nil || !match {
+		a.audit(c, "password.change_failed", user.Username)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Failed to change password",
+			"message": "The current password is incorrect",
+		})
+	}
+
+	if err := a.checkPassword(payload.NewPassword); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Failed to change password",
+			"message": err.Error(),
+		})
+	}
+	if payload.NewPassword == payload.OldPassword {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Failed to change password",
+			"message": ErrPasswordReused.Error(),
+		})
+	}
+
+	hash, err := a.password.GenerateHashPassword(payload.NewPassword)
+	if err == nil {
+		err = a.user.SetPassword(user.Id, hash, false)
+	}
+	if err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to change password")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	session := &authsession.Session{Id: int64(claims["sid"].(float64))}
+	if err := a.sessions.RevokeOtherSessions(user.Id, session.Id); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to revoke other sessions")
+	}
+
+	a.audit(c, "password.changed", user.Username)
+
+	// Swap the access token so a pending change no longer restricts it
+	user.MustChange = false
+	if err := a.issueTokens(c, user, session, ""); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to issue access token")
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Password changed",
+		"message": "Your password has been changed, other devices have been logged out",
+	})
+}
+
+// sendResetToken delivers a reset token to the whatsapp number of the user
+// through the configured system session
+func (a *AuthApi) sendResetToken(ctx context.Context, user *user.User, resetToken string) error {
+	if a.config.Whatsapp.SystemSessionId == 0 {
+		return ErrNoSystemSession
+	}
+	if user.Whatsapp == nil || *user.Whatsapp == "" {
+		return ErrNoWhatsapp
+	}
+
+	sender, err := a.senders.GetUserById(a.config.Whatsapp.SystemSessionId)
+	if err != nil {
+		return err
+	}
+
+	userInfo := a.whatsapp.UserToUserInfo(sender)
+	_, err = a.api.SendText(ctx, &userInfo, &api.SendTextPayload{
+		Phone: *user.Whatsapp,
+		Body: fmt.Sprintf(
+			"Hi %s, your Buzz password reset token is:\n\n%s\n\nIt expires in %s. Ignore this message if you did not ask for a reset.",
+			user.Name,
+			resetToken,
+			a.config.Password.ResetTTL,
+		),
+	})
+	return err
+}
+
+// IssuePasswordReset creates a one time reset token for a user. The token is
+// sent over WhatsApp when possible, otherwise it is handed to the admin.
+func (a *AuthApi) IssuePasswordReset(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "failed to convert id to int",
+		})
+	}
+
+	target, err := a.user.GetUserById(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "User not found",
+		})
+	}
+
+	resetToken, hash, err := token.NewRefreshToken()
+	if err == nil {
+		err = a.user.CreatePasswordReset(&user.PasswordReset{
+			UserId:    target.Id,
+			Hash:      hash,
+			ExpiresAt: time.Now().Add(a.config.Password.ResetTTL),
+		})
+	}
+	if err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to create password reset")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	a.audit(c, "password.reset_issued", target.Username)
+
+	if err := a.sendResetToken(c.UserContext(), target, resetToken); err != nil {
+		buzzLog.Request(c, a.log).Warn().Err(err).Str("username", target.Username).Msg("Password reset token not delivered over whatsapp")
+		return c.JSON(fiber.Map{
+			"status":  "success",
+			"title":   "Password reset issued",
+			"message": "Hand this token to the user, it can only be used once",
+			"data": fiber.Map{
+				"delivered": false,
+				"token":     resetToken,
+			},
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Password reset issued",
+		"message": "The reset token has been sent to the user over WhatsApp",
+		"data": fiber.Map{
+			"delivered": true,
+		},
+	})
+}
+
+// ResetPassword sets a new password using a reset token. Every login of the
+// user is ended and any lockout is lifted.
+func (a *AuthApi) ResetPassword(c *fiber.Ctx) error {
+	payload := new(ResetPasswordPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": err.Error(),
+		})
+	}
+
+	if err := a.checkPassword(payload.NewPassword); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Failed to reset password",
+			"message": err.Error(),
+		})
+	}
+
+	reset, err := a.user.UsePasswordReset(token.HashRefreshToken(payload.Token))
+	if err != nil {
+		a.audit(c, "password.reset_failed", "")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Failed to reset password",
+			"message": "The reset token is invalid or has expired",
+		})
+	}
+
+	target, err := a.user.GetUserById(int(reset.UserId))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "User not found",
+		})
+	}
+
+	hash, err := a.password.GenerateHashPassword(payload.NewPassword)
+	if err == nil {
+		err = a.user.SetPassword(target.Id, hash, false)
+	}
+	if err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to reset password")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	if err := a.sessions.RevokeUserSessions(target.Id); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to revoke user sessions")
+	}
+	if err := a.lockout.Reset(lockout.UserKey(target.Username)); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to reset login lockout")
+	}
+
+	a.audit(c, "password.reset", target.Username)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Password reset",
+		"message": "Your password has been reset, you can log in now",
+	})
+}
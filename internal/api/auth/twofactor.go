@@ -0,0 +1,411 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/nugrhrizki/buzz/internal/authsession"
+	"github.com/nugrhrizki/buzz/internal/user"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/nugrhrizki/buzz/pkg/totp"
+)
+
+const (
+	totpIssuer        = "Buzz"
+	recoveryCodeCount = 10
+)
+
+type TwoFactorPayload struct {
+	Code string `json:"code"`
+}
+
+// currentUser loads the dashboard user the access token belongs to
+func (a *AuthApi) currentUser(c *fiber.Ctx) (*user.User, jwt.MapClaims, error) {
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(jwt.MapClaims)
+
+	user, err := a.user.GetUserById(int(claims["uid"].(float64)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, claims, nil
+}
+
+// requires2faSetup tells whether the role of a user makes two-factor
+// mandatory while the user has not turned it on yet
+func (a *AuthApi) requires2faSetup(user *user.User) bool {
+	if user.TotpEnabled {
+		return false
+	}
+
+	role, err := a.role.GetRoleById(int(user.RoleId))
+	if err != nil {
+		a.log.Error().Err(err).Msg("Failed to get role")
+		return false
+	}
+	return role.Require2fa
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// newRecoveryCodes returns fresh codes to show the user once and the hashes
+// to store for them
+func (a *AuthApi) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(buf)
+		hash, err := a.password.GenerateHashPassword(code)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hash
+	}
+	return codes, hashes, nil
+}
+
+// verifySecondFactor accepts either a code from the authenticator app or an
+// unused recovery code
+func (a *AuthApi) verifySecondFactor(c *fiber.Ctx, user *user.User, code string) (bool, error) {
+	if user.TotpSecret == nil {
+		return false, nil
+	}
+
+	if step, ok := totp.Validate(*user.TotpSecret, code, time.Now()); ok {
+		return a.user.UseTotpStep(user.Id, step)
+	}
+
+	recoveryCode := normalizeRecoveryCode(code)
+	codes, err := a.user.GetUnusedRecoveryCodes(user.Id)
+	if err != nil {
+		return false, err
+	}
+
+	for _, stored := range codes {
+		match, err := a.password.CompareHashPassword(recoveryCode, stored.Hash)
+		if err != nil || !match {
+			continue
+		}
+
+		used, err := a.user.UseRecoveryCode(stored.Id)
+		if err != nil || !used {
+			return false, err
+		}
+
+		a.audit(c, "2fa.recovery_code_used", user.Username)
+		return true, nil
+	}
+	return false, nil
+}
+
+// SetupTwoFactor creates a new secret for the current user and returns it with
+// a QR code for the authenticator app. It only takes effect once confirmed
+// through EnableTwoFactor.
+func (a *AuthApi) SetupTwoFactor(c *fiber.Ctx) error {
+	user, _, err := a.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Unauthorized",
+			"message": "You are not authorized",
+		})
+	}
+
+	if user.TotpEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Already enabled",
+			"message": "Two-factor authentication is already enabled, disable it first to set it up again",
+		})
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	if err := a.user.SetTotpSecret(user.Id, secret); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to store totp secret")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	uri := totp.URI(totpIssuer, user.Username, secret)
+	image, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Scan the QR code",
+		"message": "Scan the QR code with your authenticator app and confirm with a code",
+		"data": fiber.Map{
+			"secret": secret,
+			"uri":    uri,
+			"qrcode": "data:image/png;base64," + base64.StdEncoding.EncodeToString(image),
+		},
+	})
+}
+
+// EnableTwoFactor confirms the pending secret with a code and hands out the
+// recovery codes
+func (a *AuthApi) EnableTwoFactor(c *fiber.Ctx) error {
+	payload := new(TwoFactorPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": err.Error(),
+		})
+	}
+
+	user, claims, err := a.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Unauthorized",
+			"message": "You are not authorized",
+		})
+	}
+
+	if user.TotpEnabled || user.TotpSecret == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Nothing to enable",
+			"message": "Set up two-factor authentication first",
+		})
+	}
+
+	step, ok := totp.Validate(*user.TotpSecret, payload.Code, time.Now())
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Invalid code",
+			"message": "The code is not valid, check the time on your device",
+		})
+	}
+
+	codes, hashes, err := a.newRecoveryCodes()
+	if err == nil {
+		err = a.user.ReplaceRecoveryCodes(user.Id, hashes)
+	}
+	if err == nil {
+		_, err = a.user.UseTotpStep(user.Id, step)
+	}
+	if err == nil {
+		err = a.user.EnableTotp(user.Id)
+	}
+	if err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to enable two-factor authentication")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	a.audit(c, "2fa.enabled", user.Username)
+
+	// Swap the access token so a pending setup no longer restricts it
+	user.TotpEnabled = true
+	session := &authsession.Session{Id: int64(claims["sid"].(float64))}
+	if err := a.issueTokens(c, user, session, ""); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to issue access token")
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Two-factor authentication enabled",
+		"message": "Store these recovery codes somewhere safe, they will not be shown again",
+		"data": fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor turns two-factor off after checking a current code
+func (a *AuthApi) DisableTwoFactor(c *fiber.Ctx) error {
+	payload := new(TwoFactorPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": err.Error(),
+		})
+	}
+
+	user, _, err := a.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Unauthorized",
+			"message": "You are not authorized",
+		})
+	}
+
+	if !user.TotpEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Not enabled",
+			"message": "Two-factor authentication is not enabled",
+		})
+	}
+
+	role, err := a.role.GetRoleById(int(user.RoleId))
+	if err == nil && role.Require2fa {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Forbidden",
+			"message": "Your role requires two-factor authentication",
+		})
+	}
+
+	ok, err := a.verifySecondFactor(c, user, payload.Code)
+	if err != nil || !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Invalid code",
+			"message": "The code is not valid",
+		})
+	}
+
+	if err := a.user.DisableTotp(user.Id); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to disable two-factor authentication")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	a.audit(c, "2fa.disabled", user.Username)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Two-factor authentication disabled",
+		"message": "Your account is protected by password only",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a
+// current code, the old ones stop working
+func (a *AuthApi) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	payload := new(TwoFactorPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": err.Error(),
+		})
+	}
+
+	user, _, err := a.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Unauthorized",
+			"message": "You are not authorized",
+		})
+	}
+
+	ok, err := a.verifySecondFactor(c, user, payload.Code)
+	if err != nil || !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Invalid code",
+			"message": "The code is not valid",
+		})
+	}
+
+	codes, hashes, err := a.newRecoveryCodes()
+	if err == nil {
+		err = a.user.ReplaceRecoveryCodes(user.Id, hashes)
+	}
+	if err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to replace recovery codes")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	a.audit(c, "2fa.recovery_codes_regenerated", user.Username)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Recovery codes replaced",
+		"message": "Store these recovery codes somewhere safe, they will not be shown again",
+		"data": fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// ResetTwoFactor lets an admin turn two-factor off for a user who lost their
+// device
+func (a *AuthApi) ResetTwoFactor(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "failed to convert id to int",
+		})
+	}
+
+	user, err := a.user.GetUserById(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "User not found",
+		})
+	}
+
+	if err := a.user.DisableTotp(user.Id); err != nil {
+		buzzLog.Request(c, a.log).Error().Err(err).Msg("Failed to reset two-factor authentication")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Oops, something went wrong",
+			"message": "Internal server error",
+		})
+	}
+
+	a.audit(c, "2fa.reset", user.Username)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Two-factor authentication reset",
+		"message": "The user can log in with their password and set it up again",
+	})
+}
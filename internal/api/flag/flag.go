@@ -0,0 +1,163 @@
+package flag
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nugrhrizki/buzz/internal/flag"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/rs/zerolog"
+)
+
+type FlagApi struct {
+	flags     *flag.Repository
+	evaluator *flag.Evaluator
+	log       *zerolog.Logger
+}
+
+func NewFlagApi(flags *flag.Repository, evaluator *flag.Evaluator, log *zerolog.Logger) *FlagApi {
+	return &FlagApi{
+		flags:     flags,
+		evaluator: evaluator,
+		log:       log,
+	}
+}
+
+func failed(c *fiber.Ctx, status int, title string, err error) error {
+	return c.Status(status).JSON(fiber.Map{
+		"status":  "error",
+		"title":   title,
+		"message": err.Error(),
+	})
+}
+
+// saveFailed answers a rejected create or update
+func (fa *FlagApi) saveFailed(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, flag.ErrInvalidName), errors.Is(err, flag.ErrInvalidRollout):
+		return failed(c, fiber.StatusBadRequest, "Invalid flag", err)
+	case errors.Is(err, flag.ErrFlagExists):
+		return failed(c, fiber.StatusConflict, "Invalid flag", err)
+	default:
+		buzzLog.Request(c, fa.log).Error().Err(err).Msg("Failed to save feature flag")
+		return failed(c, fiber.StatusInternalServerError, "Failed to save flag", err)
+	}
+}
+
+// GetFlags returns the stored flags and the defaults of the flags Buzz
+// knows, which apply until they are stored
+func (fa *FlagApi) GetFlags(c *fiber.Ctx) error {
+	flags, err := fa.flags.GetFlags()
+	if err != nil {
+		buzzLog.Request(c, fa.log).Error().Err(err).Msg("Failed to get feature flags")
+		return failed(c, fiber.StatusInternalServerError, "Failed to get flags", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Feature flags",
+		"message": "Feature flags retrieved",
+		"data": fiber.Map{
+			"flags":    flags,
+			"defaults": flag.Defaults,
+		},
+	})
+}
+
+func (fa *FlagApi) CreateFlag(c *fiber.Ctx) error {
+	payload := new(flag.Flag)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	if err := payload.Validate(); err != nil {
+		return fa.saveFailed(c, err)
+	}
+	if err := fa.flags.CreateFlag(payload); err != nil {
+		return fa.saveFailed(c, err)
+	}
+	fa.evaluator.Invalidate()
+	audit.Change(c, "flag.create", "flag", payload.Name, nil, payload)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Flag created",
+		"message": "Flag " + payload.Name + " is created",
+		"data":    payload,
+	})
+}
+
+func (fa *FlagApi) UpdateFlag(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return errors.New("failed to convert id to int")
+	}
+
+	payload := new(flag.Flag)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	existing, err := fa.flags.GetFlagById(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return failed(c, fiber.StatusNotFound, "Flag not found", err)
+	}
+	if err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to get flag", err)
+	}
+
+	before := *existing
+	existing.Name = payload.Name
+	existing.Description = payload.Description
+	existing.Enabled = payload.Enabled
+	existing.Rollout = payload.Rollout
+	existing.Sessions = payload.Sessions
+	existing.Roles = payload.Roles
+
+	if err := existing.Validate(); err != nil {
+		return fa.saveFailed(c, err)
+	}
+	if err := fa.flags.UpdateFlag(existing); err != nil {
+		return fa.saveFailed(c, err)
+	}
+	fa.evaluator.Invalidate()
+	audit.Change(c, "flag.update", "flag", existing.Name, before, existing)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Flag updated",
+		"message": "Flag " + existing.Name + " is updated",
+		"data":    existing,
+	})
+}
+
+func (fa *FlagApi) DeleteFlag(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return errors.New("failed to convert id to int")
+	}
+
+	existing, err := fa.flags.GetFlagById(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return failed(c, fiber.StatusNotFound, "Flag not found", err)
+	}
+	if err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to get flag", err)
+	}
+
+	if err := fa.flags.DeleteFlag(existing); err != nil {
+		buzzLog.Request(c, fa.log).Error().Err(err).Msg("Failed to delete feature flag")
+		return failed(c, fiber.StatusInternalServerError, "Failed to delete flag", err)
+	}
+	fa.evaluator.Invalidate()
+	audit.Change(c, "flag.delete", "flag", existing.Name, existing, nil)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Flag deleted",
+		"message": "Flag " + existing.Name + " is deleted",
+	})
+}
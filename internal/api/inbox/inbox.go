@@ -0,0 +1,412 @@
+package inbox
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nugrhrizki/buzz/internal/inbox"
+	dashboardUser "github.com/nugrhrizki/buzz/internal/user"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/api"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/message"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+	"github.com/rs/zerolog"
+)
+
+type InboxApi struct {
+	inbox    *inbox.Repository
+	messages *message.Repository
+	users    *user.Repository
+	agents   *dashboardUser.Repository
+	whatsapp *whatsapp.Whatsapp
+	api      *api.Api
+	log      *zerolog.Logger
+}
+
+func NewInboxApi(
+	inbox *inbox.Repository,
+	messages *message.Repository,
+	users *user.Repository,
+	agents *dashboardUser.Repository,
+	whatsapp *whatsapp.Whatsapp,
+	api *api.Api,
+	log *zerolog.Logger,
+) *InboxApi {
+	return &InboxApi{
+		inbox:    inbox,
+		messages: messages,
+		users:    users,
+		agents:   agents,
+		whatsapp: whatsapp,
+		api:      api,
+		log:      log,
+	}
+}
+
+type StatusPayload struct {
+	Status string `json:"status"`
+}
+
+type AssignPayload struct {
+	AssigneeId *int64 `json:"assignee_id"`
+}
+
+type NotePayload struct {
+	Body string `json:"body"`
+}
+
+type ReplyPayload struct {
+	Body          string `json:"body"`
+	CannedReplyId int    `json:"canned_reply_id"`
+}
+
+func agentId(c *fiber.Ctx) int64 {
+	userToken := c.Locals("user").(*jwt.Token)
+	claims := userToken.Claims.(jwt.MapClaims)
+	return int64(claims["uid"].(float64))
+}
+
+func conversationParams(c *fiber.Ctx) (int, string, error) {
+	session, err := strconv.Atoi(c.Params("session"))
+	if err != nil {
+		return 0, "", errors.New("failed to convert session to int")
+	}
+
+	chat, err := url.PathUnescape(c.Params("chat"))
+	if err != nil {
+		return 0, "", errors.New("failed to decode chat")
+	}
+
+	return session, chat, nil
+}
+
+func failed(c *fiber.Ctx, status int, title string, err error) error {
+	return c.Status(status).JSON(fiber.Map{
+		"status":  "error",
+		"title":   title,
+		"message": err.Error(),
+	})
+}
+
+func (ia *InboxApi) GetConversations(c *fiber.Ctx) error {
+	session, err := strconv.Atoi(c.Params("session"))
+	if err != nil {
+		return errors.New("failed to convert session to int")
+	}
+
+	conversations, err := ia.inbox.GetConversations(inbox.ConversationFilter{
+		WhatsappUserId: session,
+		Status:         c.Query("status"),
+		AssigneeId:     int64(c.QueryInt("assignee")),
+	})
+	if err != nil {
+		buzzLog.Request(c, ia.log).Error().Err(err).Msg("Failed to get conversations")
+		return failed(c, fiber.StatusInternalServerError, "Failed to get conversations", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Conversations",
+		"message": "Conversations retrieved",
+		"data":    conversations,
+	})
+}
+
+func (ia *InboxApi) GetMessages(c *fiber.Ctx) error {
+	session, chat, err := conversationParams(c)
+	if err != nil {
+		return err
+	}
+
+	messages, err := ia.messages.GetMessagesByChat(
+		session,
+		chat,
+		c.QueryInt("limit", 50),
+		c.QueryInt("offset", 0),
+	)
+	if err != nil {
+		buzzLog.Request(c, ia.log).Error().Err(err).Msg("Failed to get messages")
+		return failed(c, fiber.StatusInternalServerError, "Failed to get messages", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Messages",
+		"message": "Messages retrieved",
+		"data":    messages,
+	})
+}
+
+func (ia *InboxApi) MarkRead(c *fiber.Ctx) error {
+	session, chat, err := conversationParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := ia.messages.MarkChatRead(session, chat); err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to mark conversation read", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Conversation read",
+		"message": "Conversation marked as read",
+	})
+}
+
+func (ia *InboxApi) SetStatus(c *fiber.Ctx) error {
+	session, chat, err := conversationParams(c)
+	if err != nil {
+		return err
+	}
+
+	payload := new(StatusPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	if err := ia.inbox.SetStatus(session, chat, payload.Status); err != nil {
+		if errors.Is(err, inbox.ErrInvalidStatus) {
+			return failed(c, fiber.StatusBadRequest, "Invalid status", err)
+		}
+		return failed(c, fiber.StatusInternalServerError, "Failed to update status", err)
+	}
+	audit.Change(c, "conversation.status", "chat", chat, nil, payload)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Status updated",
+		"message": "Conversation is now " + payload.Status,
+	})
+}
+
+func (ia *InboxApi) Assign(c *fiber.Ctx) error {
+	session, chat, err := conversationParams(c)
+	if err != nil {
+		return err
+	}
+
+	payload := new(AssignPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	if payload.AssigneeId != nil {
+		_, err := ia.agents.GetUserById(int(*payload.AssigneeId))
+		if errors.Is(err, sql.ErrNoRows) {
+			return failed(c, fiber.StatusBadRequest, "Invalid assignee", errors.New("assignee is not a dashboard user"))
+		}
+		if err != nil {
+			buzzLog.Request(c, ia.log).Error().Err(err).Msg("Failed to get assignee")
+			return failed(c, fiber.StatusInternalServerError, "Failed to assign conversation", err)
+		}
+	}
+
+	if err := ia.inbox.Assign(session, chat, payload.AssigneeId); err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to assign conversation", err)
+	}
+	audit.Change(c, "conversation.assign", "chat", chat, nil, payload)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Conversation assigned",
+		"message": "Conversation assignment updated",
+	})
+}
+
+func (ia *InboxApi) GetNotes(c *fiber.Ctx) error {
+	session, chat, err := conversationParams(c)
+	if err != nil {
+		return err
+	}
+
+	notes, err := ia.inbox.GetNotes(session, chat)
+	if err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to get notes", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Notes",
+		"message": "Notes retrieved",
+		"data":    notes,
+	})
+}
+
+func (ia *InboxApi) CreateNote(c *fiber.Ctx) error {
+	session, chat, err := conversationParams(c)
+	if err != nil {
+		return err
+	}
+
+	payload := new(NotePayload)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	note := inbox.Note{
+		WhatsappUserId: session,
+		Chat:           chat,
+		AuthorId:       agentId(c),
+		Body:           payload.Body,
+	}
+
+	if err := ia.inbox.CreateNote(&note); err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to create note", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Note created",
+		"message": "Note added to the conversation",
+		"data":    note,
+	})
+}
+
+// Reply sends a text through the session the conversation belongs to, the
+// replying agent is recorded on the stored message.
+func (ia *InboxApi) Reply(c *fiber.Ctx) error {
+	session, chat, err := conversationParams(c)
+	if err != nil {
+		return err
+	}
+
+	payload := new(ReplyPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	body := payload.Body
+	if payload.CannedReplyId != 0 {
+		reply, err := ia.inbox.GetCannedReplyById(payload.CannedReplyId)
+		if err != nil {
+			return failed(c, fiber.StatusNotFound, "Canned reply not found", err)
+		}
+		body = reply.Body
+	}
+
+	if body == "" {
+		return failed(c, fiber.StatusBadRequest, "Failed to send reply", whatsapp.ErrEmptyBody)
+	}
+
+	sessionUser, err := ia.users.GetUserById(session)
+	if err != nil {
+		return failed(c, fiber.StatusNotFound, "Session not found", err)
+	}
+
+	agent := agentId(c)
+	userInfo := ia.whatsapp.UserToUserInfo(sessionUser)
+	resp, err := ia.api.SendText(c.UserContext(), &userInfo, &api.SendTextPayload{
+		Phone:   chat,
+		Body:    body,
+		AgentId: &agent,
+	})
+	if err != nil {
+		buzzLog.Request(c, ia.log).Error().Err(err).Str("chat", chat).Msg("Failed to send reply")
+		return failed(c, fiber.StatusInternalServerError, "Failed to send reply", err)
+	}
+	audit.Describe(c, "conversation.reply", "chat", chat)
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Reply sent",
+		"message": "Your reply is on its way",
+		"data": fiber.Map{
+			"id":        resp.ID,
+			"timestamp": resp.Timestamp,
+		},
+	})
+}
+
+func (ia *InboxApi) GetCannedReplies(c *fiber.Ctx) error {
+	replies, err := ia.inbox.GetCannedReplies()
+	if err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to get canned replies", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Canned replies",
+		"message": "Canned replies retrieved",
+		"data":    replies,
+	})
+}
+
+func (ia *InboxApi) CreateCannedReply(c *fiber.Ctx) error {
+	payload := new(inbox.CannedReply)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	payload.CreatedBy = agentId(c)
+	if err := ia.inbox.CreateCannedReply(payload); err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to create canned reply", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Canned reply created",
+		"message": "Canned reply is ready to use",
+		"data":    payload,
+	})
+}
+
+func (ia *InboxApi) UpdateCannedReply(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return errors.New("failed to convert id to int")
+	}
+
+	payload := new(inbox.CannedReply)
+	if err := c.BodyParser(payload); err != nil {
+		return failed(c, fiber.StatusBadRequest, "Oops, something went wrong", err)
+	}
+
+	reply, err := ia.inbox.GetCannedReplyById(id)
+	if err != nil {
+		return failed(c, fiber.StatusNotFound, "Canned reply not found", err)
+	}
+
+	reply.Shortcut = payload.Shortcut
+	reply.Title = payload.Title
+	reply.Body = payload.Body
+
+	if err := ia.inbox.UpdateCannedReply(reply); err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to update canned reply", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Canned reply updated",
+		"message": "Canned reply is updated",
+		"data":    reply,
+	})
+}
+
+func (ia *InboxApi) DeleteCannedReply(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return errors.New("failed to convert id to int")
+	}
+
+	reply, err := ia.inbox.GetCannedReplyById(id)
+	if err != nil {
+		return failed(c, fiber.StatusNotFound, "Canned reply not found", err)
+	}
+
+	if err := ia.inbox.DeleteCannedReply(reply); err != nil {
+		return failed(c, fiber.StatusInternalServerError, "Failed to delete canned reply", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Canned reply deleted",
+		"message": "Canned reply is deleted",
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/pkg/audit"
 )
 
 type RoleApi struct {
@@ -22,10 +23,15 @@ func (ra *RoleApi) CreateRole(c *fiber.Ctx) error {
 		return err
 	}
 
+	if err := role.ValidatePermissions(payload.Actions); err != nil {
+		return err
+	}
+
 	err := ra.role.CreateRole(payload)
 	if err != nil {
 		return err
 	}
+	audit.Change(c, "role.create", "role", payload.Name, nil, payload)
 
 	return nil
 }
@@ -64,17 +70,24 @@ func (ra *RoleApi) UpdateRole(c *fiber.Ctx) error {
 		return err
 	}
 
+	if err := role.ValidatePermissions(payload.Actions); err != nil {
+		return err
+	}
+
 	role, err := ra.role.GetRoleById(id)
 	if err != nil {
 		return errors.New("failed to get user by id")
 	}
 
+	before := *role
 	role.Name = payload.Name
 	role.Actions = payload.Actions
+	role.Require2fa = payload.Require2fa
 
 	if err := ra.role.UpdateRole(role); err != nil {
 		return err
 	}
+	audit.Change(c, "role.update", "role", role.Name, before, role)
 
 	return nil
 }
@@ -93,6 +106,7 @@ func (ra *RoleApi) DeleteRole(c *fiber.Ctx) error {
 	if err := ra.role.DeleteRole(role); err != nil {
 		return err
 	}
+	audit.Change(c, "role.delete", "role", role.Name, role, nil)
 
 	return nil
 }
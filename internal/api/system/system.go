@@ -0,0 +1,137 @@
+package system
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/rs/zerolog"
+)
+
+// heartbeat keeps idle tails open through proxies
+const heartbeat = 15 * time.Second
+
+type SystemApi struct {
+	logs *buzzLog.Buffer
+	log  *zerolog.Logger
+}
+
+func NewSystemApi(logs *buzzLog.Buffer, log *zerolog.Logger) *SystemApi {
+	return &SystemApi{
+		logs: logs,
+		log:  log,
+	}
+}
+
+// logFilter reads the log filter from the query string. level is the lowest
+// level returned, from and to take RFC 3339 timestamps.
+func logFilter(c *fiber.Ctx) (buzzLog.Filter, error) {
+	f := buzzLog.Filter{
+		Level:   zerolog.TraceLevel,
+		Session: c.Query("session"),
+		Text:    c.Query("text"),
+		Limit:   c.QueryInt("limit", 200),
+	}
+
+	if value := c.Query("level"); value != "" {
+		level, err := zerolog.ParseLevel(value)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, "level should be trace, debug, info, warn, error, fatal or panic")
+		}
+		f.Level = level
+	}
+
+	for param, dest := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, param+" should be an RFC 3339 timestamp")
+		}
+		*dest = &t
+	}
+
+	return f, nil
+}
+
+// GetLogs returns the buffered log entries matching the filter, newest
+// first
+func (sa *SystemApi) GetLogs(c *fiber.Ctx) error {
+	f, err := logFilter(c)
+	if err != nil {
+		return err
+	}
+	if f.Limit <= 0 || f.Limit > 1000 {
+		f.Limit = 200
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Logs",
+		"message": "Log entries retrieved",
+		"data": fiber.Map{
+			"entries": sa.logs.Entries(f),
+		},
+	})
+}
+
+// TailLogs streams new log entries matching the filter as server-sent
+// events until the client goes away
+func (sa *SystemApi) TailLogs(c *fiber.Ctx) error {
+	f, err := logFilter(c)
+	if err != nil {
+		return err
+	}
+
+	entries, cancel := sa.logs.Subscribe()
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		// tell the client the stream is open before the first entry
+		fmt.Fprint(w, ": connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case entry, ok := <-entries:
+				if !ok {
+					return
+				}
+				if !f.Match(&entry) {
+					continue
+				}
+				data, err := json.Marshal(entry)
+				if err != nil {
+					sa.log.Error().Err(err).Msg("Failed to encode log entry")
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", entry.Id, data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+
+			// a failed flush means the client is gone
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
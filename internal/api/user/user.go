@@ -6,6 +6,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/nugrhrizki/buzz/internal/user"
+	"github.com/nugrhrizki/buzz/pkg/audit"
 	"github.com/nugrhrizki/buzz/pkg/password"
 )
 
@@ -28,6 +29,7 @@ func (ua *UserApi) CreateUser(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	audit.Change(c, "user.create", "user", payload.Username, nil, payload)
 
 	return nil
 }
@@ -71,6 +73,7 @@ func (ua *UserApi) UpdateUser(c *fiber.Ctx) error {
 		return errors.New("failed to get user by id")
 	}
 
+	before := *user
 	user.Name = payload.Name
 
 	if user.Username != payload.Username {
@@ -96,6 +99,7 @@ func (ua *UserApi) UpdateUser(c *fiber.Ctx) error {
 	if err := ua.user.UpdateUser(user); err != nil {
 		return err
 	}
+	audit.Change(c, "user.update", "user", before.Username, before, user)
 
 	return nil
 }
@@ -114,6 +118,7 @@ func (ua *UserApi) DeleteUser(c *fiber.Ctx) error {
 	if err := ua.user.DeleteUser(user); err != nil {
 		return err
 	}
+	audit.Change(c, "user.delete", "user", user.Username, user, nil)
 
 	return nil
 }
@@ -1,12 +1,16 @@
 package whatsapp
 
 import (
+	"encoding/json"
 	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp/api"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
 	"github.com/rs/zerolog"
 )
@@ -27,41 +31,74 @@ func NewWhatsappAPI(api *api.Api, wa *whatsapp.Whatsapp, log *zerolog.Logger, us
 	}
 }
 
+// UserInfo authenticates the session api with an api key, from the token
+// header or query parameter. Session tokens that predate api keys were turned
+// into keys, the token itself is not a credential anymore.
 func (w *WhatsappAPI) UserInfo(c *fiber.Ctx) error {
-	// Get token from headers or uri parameters
 	token := c.Get("token")
 	if token == "" {
 		token = c.Query("token")
 	}
+	if token == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, apikey.ErrInvalidKey.Error())
+	}
 
-	userInfo, found := w.whatsapp.GetCacheUserInfo(token)
+	userInfo, key, err := w.api.Authenticate(token, c.IP())
+	if err != nil {
+		buzzLog.Request(c, w.log).Warn().Err(err).Msg("api key rejected")
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
 
-	if !found {
-		w.log.Info().Msg("Looking for user information in DB")
-		user, err := w.user.GetUserByToken(token)
-		if err != nil {
-			w.log.Error().Err(err).Msg("failed to get user by token")
-			return err
-		}
+	c.Locals("userinfo", *userInfo)
+	c.Locals("apikey", key)
+	return c.Next()
+}
 
-		userInfo = w.whatsapp.UserToUserInfo(user)
-		w.whatsapp.UpdateCacheUserInfo(token, userInfo)
-		c.Locals("userinfo", userInfo)
-		return c.Next()
+// SessionInfo serves the session api to a dashboard user, the session comes
+// from the id route param. Access to it is checked before.
+func (w *WhatsappAPI) SessionInfo(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid session id")
 	}
 
-	userid, err := strconv.Atoi(userInfo.Id)
+	session, err := w.user.GetUserById(id)
 	if err != nil {
-		w.log.Error().Err(err).Msg("failed to convert user id to int")
-		return err
+		return fiber.NewError(fiber.StatusNotFound, "session not found")
 	}
 
-	if userid == 0 {
-		return fiber.ErrUnauthorized
+	c.Locals("userinfo", w.whatsapp.UserToUserInfo(session))
+	return c.Next()
+}
+
+// Scope only lets api keys through when they grant scope. Dashboard users
+// operating the session have no key and keep full access.
+func (w *WhatsappAPI) Scope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := c.Locals("apikey").(*apikey.ApiKey)
+		if ok && !key.HasScope(scope) {
+			return fiber.NewError(fiber.StatusForbidden, "api key is missing the "+scope+" scope")
+		}
+		return c.Next()
 	}
+}
 
-	c.Locals("userinfo", userInfo)
-	return c.Next()
+// sessionOwner returns the dashboard user a request is scoped to when the
+// caller may only manage the sessions assigned to them
+func sessionOwner(c *fiber.Ctx) (int64, bool) {
+	owner, ok := c.Locals("sessionOwner").(int64)
+	return owner, ok
+}
+
+// hasField tells whether the json body sets key, even to null. Fields left
+// out keep their value, so a rename does not take the owner away.
+func hasField(c *fiber.Ctx, key string) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &fields); err != nil {
+		return false
+	}
+	_, found := fields[key]
+	return found
 }
 
 func (wa *WhatsappAPI) CreateUser(c *fiber.Ctx) error {
@@ -70,21 +107,37 @@ func (wa *WhatsappAPI) CreateUser(c *fiber.Ctx) error {
 		return errors.New("failed to parse body")
 	}
 
-	_, err := wa.api.CreateUser(payload)
+	if owner, scoped := sessionOwner(c); scoped {
+		payload.OwnerId = &owner
+	}
+
+	created, err := wa.api.CreateUser(payload)
 	if err != nil {
 		return errors.New("failed to create user")
 	}
+	audit.Change(c, "session.create", "session", strconv.Itoa(created.Id), nil, created.Session())
 
 	return nil
 }
 
 func (wa *WhatsappAPI) GetWhatsappUser(c *fiber.Ctx) error {
-	user, err := wa.api.GetUsers()
+	var users []user.User
+	var err error
+	if owner, scoped := sessionOwner(c); scoped {
+		users, err = wa.api.GetUsersByOwner(owner)
+	} else {
+		users, err = wa.api.GetUsers()
+	}
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(user)
+	sessions := make([]user.Session, len(users))
+	for i := range users {
+		sessions[i] = users[i].Session()
+	}
+
+	return c.JSON(sessions)
 }
 
 func (wa *WhatsappAPI) GetWhatsappUserById(c *fiber.Ctx) error {
@@ -98,7 +151,7 @@ func (wa *WhatsappAPI) GetWhatsappUserById(c *fiber.Ctx) error {
 		return err
 	}
 
-	return c.JSON(user)
+	return c.JSON(user.Session())
 }
 
 func (wa *WhatsappAPI) UpdateUser(c *fiber.Ctx) error {
@@ -117,11 +170,16 @@ func (wa *WhatsappAPI) UpdateUser(c *fiber.Ctx) error {
 		return errors.New("failed to get user by id")
 	}
 
+	before := user.Session()
 	user.Name = payload.Name
+	if _, scoped := sessionOwner(c); !scoped && hasField(c, "owner_id") {
+		user.OwnerId = payload.OwnerId
+	}
 
 	if err := wa.api.UpdateUser(user); err != nil {
 		return errors.New("failed to update user")
 	}
+	audit.Change(c, "session.update", "session", c.Params("id"), before, user.Session())
 
 	return nil
 }
@@ -140,10 +198,86 @@ func (wa *WhatsappAPI) DeleteUser(c *fiber.Ctx) error {
 	if err := wa.api.DeleteUser(user); err != nil {
 		return errors.New("failed to delete user")
 	}
+	audit.Change(c, "session.delete", "session", c.Params("id"), user.Session(), nil)
 
 	return nil
 }
 
+// apiKeySession returns the session api keys are managed for, either from
+// the dashboard route or from the authenticated session
+func apiKeySession(c *fiber.Ctx) (int, error) {
+	if id := c.Params("id"); id != "" {
+		return strconv.Atoi(id)
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+	return strconv.Atoi(userInfo.Id)
+}
+
+func (wa *WhatsappAPI) GetApiKeys(c *fiber.Ctx) error {
+	session, err := apiKeySession(c)
+	if err != nil {
+		return errors.New("failed to convert id to int")
+	}
+
+	keys, err := wa.api.GetApiKeys(session)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(keys)
+}
+
+func (wa *WhatsappAPI) CreateApiKey(c *fiber.Ctx) error {
+	session, err := apiKeySession(c)
+	if err != nil {
+		return errors.New("failed to convert id to int")
+	}
+
+	payload := new(api.CreateApiKeyPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	key, err := wa.api.CreateApiKey(session, payload)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"message": "failed to create api key",
+			"error":   err.Error(),
+		})
+	}
+	audit.Change(c, "apikey.create", "apikey", strconv.FormatInt(key.Id, 10), nil, key.ApiKey)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "api key created, store it now as it will not be shown again",
+		"data":    key,
+	})
+}
+
+func (wa *WhatsappAPI) RevokeApiKey(c *fiber.Ctx) error {
+	session, err := apiKeySession(c)
+	if err != nil {
+		return errors.New("failed to convert id to int")
+	}
+
+	id, err := strconv.ParseInt(c.Params("key"), 10, 64)
+	if err != nil {
+		return errors.New("failed to convert key to int")
+	}
+
+	if err := wa.api.RevokeApiKey(session, id); err != nil {
+		return err
+	}
+	audit.Describe(c, "apikey.revoke", "apikey", c.Params("key"))
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "api key revoked",
+	})
+}
+
 func (wa *WhatsappAPI) Connect(c *fiber.Ctx) error {
 	payload := new(api.ConnectPayload)
 	if err := c.BodyParser(payload); err != nil {
@@ -161,6 +295,8 @@ func (wa *WhatsappAPI) Connect(c *fiber.Ctx) error {
 		})
 	}
 
+	audit.Change(c, "session.connect", "session", userInfo.Id, nil, fiber.Map{"events": userInfo.Events})
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "success connect",
@@ -181,6 +317,37 @@ func (wa *WhatsappAPI) Disconnect(c *fiber.Ctx) error {
 		return err
 	}
 
+	audit.Describe(c, "session.disconnect", "session", userInfo.Id)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) GetCallPolicy(c *fiber.Ctx) error {
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	policy, err := wa.api.GetCallPolicy(&userInfo)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(policy)
+}
+
+func (wa *WhatsappAPI) SetCallPolicy(c *fiber.Ctx) error {
+	payload := new(api.CallPolicyPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.SetCallPolicy(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "session.call_policy", "session", userInfo.Id, nil, payload)
+
 	return nil
 }
 
@@ -202,12 +369,15 @@ func (wa *WhatsappAPI) SetWebhook(c *fiber.Ctx) error {
 	}
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
+	previous := userInfo.Webhook
 
 	err := wa.api.SetWebhook(&userInfo, payload)
 	if err != nil {
 		return err
 	}
 
+	audit.Change(c, "session.webhook", "session", userInfo.Id, fiber.Map{"webhook": previous}, fiber.Map{"webhook": userInfo.Webhook})
+
 	return nil
 }
 
@@ -237,6 +407,8 @@ func (wa *WhatsappAPI) Logout(c *fiber.Ctx) error {
 		return err
 	}
 
+	audit.Describe(c, "session.logout", "session", userInfo.Id)
+
 	return nil
 }
 
@@ -267,7 +439,7 @@ func (wa *WhatsappAPI) SendDocument(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendDocument(&userInfo, payload)
+	_, err := wa.api.SendDocument(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -283,7 +455,7 @@ func (wa *WhatsappAPI) SendAudio(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendAudio(&userInfo, payload)
+	_, err := wa.api.SendAudio(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -299,7 +471,7 @@ func (wa *WhatsappAPI) SendImage(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendImage(&userInfo, payload)
+	_, err := wa.api.SendImage(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -315,7 +487,7 @@ func (wa *WhatsappAPI) SendSticker(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendSticker(&userInfo, payload)
+	_, err := wa.api.SendSticker(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -331,7 +503,7 @@ func (wa *WhatsappAPI) SendVideo(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendVideo(&userInfo, payload)
+	_, err := wa.api.SendVideo(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -347,7 +519,7 @@ func (wa *WhatsappAPI) SendContact(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendContact(&userInfo, payload)
+	_, err := wa.api.SendContact(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -363,7 +535,7 @@ func (wa *WhatsappAPI) SendLocation(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendLocation(&userInfo, payload)
+	_, err := wa.api.SendLocation(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -379,7 +551,7 @@ func (wa *WhatsappAPI) SendButton(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendButton(&userInfo, payload)
+	_, err := wa.api.SendButton(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -395,7 +567,7 @@ func (wa *WhatsappAPI) SendList(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendList(&userInfo, payload)
+	_, err := wa.api.SendList(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -411,7 +583,7 @@ func (wa *WhatsappAPI) SendText(c *fiber.Ctx) error {
 
 	userInfo := c.Locals("userinfo").(user.UserInfo)
 
-	_, err := wa.api.SendText(&userInfo, payload)
+	_, err := wa.api.SendText(c.UserContext(), &userInfo, payload)
 	if err != nil {
 		return err
 	}
@@ -494,3 +666,434 @@ func (wa *WhatsappAPI) SendChatPresence(c *fiber.Ctx) error {
 
 	return nil
 }
+
+func (wa *WhatsappAPI) MarkRead(c *fiber.Ctx) error {
+	payload := new(api.MarkReadPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.MarkRead(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (wa *WhatsappAPI) ArchiveChat(c *fiber.Ctx) error {
+	payload := new(api.ArchiveChatPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.ArchiveChat(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "chat.archive", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) PinChat(c *fiber.Ctx) error {
+	payload := new(api.PinChatPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.PinChat(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "chat.pin", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) MuteChat(c *fiber.Ctx) error {
+	payload := new(api.MuteChatPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.MuteChat(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "chat.mute", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) MarkChatUnread(c *fiber.Ctx) error {
+	payload := new(api.ChatPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.MarkChatUnread(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "chat.mark_unread", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) DeleteChat(c *fiber.Ctx) error {
+	payload := new(api.DeleteChatPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.DeleteChat(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "chat.delete", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) ClearChat(c *fiber.Ctx) error {
+	payload := new(api.ClearChatPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.ClearChat(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "chat.clear", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) SetPushName(c *fiber.Ctx) error {
+	payload := new(api.SetPushNamePayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.SetPushName(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "profile.push_name", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) SetAbout(c *fiber.Ctx) error {
+	payload := new(api.SetAboutPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.SetAbout(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "profile.about", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) SetProfilePicture(c *fiber.Ctx) error {
+	payload := new(api.SetProfilePicturePayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	pictureId, err := wa.api.SetProfilePicture(c.UserContext(), &userInfo, payload)
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"success": false,
+			"message": "failed to set profile picture",
+			"error":   err.Error(),
+		})
+	}
+
+	audit.Change(c, "profile.picture", "session", userInfo.Id, nil, fiber.Map{"picture": pictureId, "remove": payload.Remove})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "success set profile picture",
+		"data": fiber.Map{
+			"id": pictureId,
+		},
+	})
+}
+
+func (wa *WhatsappAPI) GetPrivacySettings(c *fiber.Ctx) error {
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	settings, err := wa.api.GetPrivacySettings(&userInfo)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(settings)
+}
+
+func (wa *WhatsappAPI) SetPrivacySetting(c *fiber.Ctx) error {
+	payload := new(api.SetPrivacyPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	settings, err := wa.api.SetPrivacySetting(&userInfo, payload)
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"success": false,
+			"message": "failed to set privacy setting",
+			"error":   err.Error(),
+		})
+	}
+
+	audit.Change(c, "privacy."+payload.Name, "session", userInfo.Id, nil, payload)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "success set privacy setting",
+		"data":    settings,
+	})
+}
+
+func (wa *WhatsappAPI) GetBlocklist(c *fiber.Ctx) error {
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	blocklist, err := wa.api.GetBlocklist(&userInfo)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(blocklist)
+}
+
+func (wa *WhatsappAPI) Block(c *fiber.Ctx) error {
+	payload := new(api.BlocklistPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	blocklist, err := wa.api.Block(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "blocklist.block", "session", userInfo.Id, nil, payload)
+
+	return c.JSON(blocklist)
+}
+
+func (wa *WhatsappAPI) Unblock(c *fiber.Ctx) error {
+	payload := new(api.BlocklistPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	blocklist, err := wa.api.Unblock(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "blocklist.unblock", "session", userInfo.Id, nil, payload)
+
+	return c.JSON(blocklist)
+}
+
+func (wa *WhatsappAPI) SendStatus(c *fiber.Ctx) error {
+	payload := new(api.SendStatusPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	resp, err := wa.api.SendStatus(c.UserContext(), &userInfo, payload)
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"success": false,
+			"message": "failed to post status",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "success post status",
+		"data": fiber.Map{
+			"id":        resp.ID,
+			"timestamp": resp.Timestamp,
+		},
+	})
+}
+
+func (wa *WhatsappAPI) CreateNewsletter(c *fiber.Ctx) error {
+	payload := new(api.CreateNewsletterPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	newsletter, err := wa.api.CreateNewsletter(&userInfo, payload)
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"success": false,
+			"message": "failed to create newsletter",
+			"error":   err.Error(),
+		})
+	}
+
+	audit.Change(c, "newsletter.create", "session", userInfo.Id, nil, fiber.Map{"jid": newsletter.ID.String(), "name": payload.Name})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "success create newsletter",
+		"data":    newsletter,
+	})
+}
+
+func (wa *WhatsappAPI) GetNewsletterInfo(c *fiber.Ctx) error {
+	payload := new(api.NewsletterInfoPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	newsletter, err := wa.api.GetNewsletterInfo(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(newsletter)
+}
+
+func (wa *WhatsappAPI) FollowNewsletter(c *fiber.Ctx) error {
+	payload := new(api.NewsletterPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.FollowNewsletter(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "newsletter.follow", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) UnfollowNewsletter(c *fiber.Ctx) error {
+	payload := new(api.NewsletterPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	err := wa.api.UnfollowNewsletter(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	audit.Change(c, "newsletter.unfollow", "session", userInfo.Id, nil, payload)
+
+	return nil
+}
+
+func (wa *WhatsappAPI) GetSubscribedNewsletters(c *fiber.Ctx) error {
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	newsletters, err := wa.api.GetSubscribedNewsletters(&userInfo)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(newsletters)
+}
+
+func (wa *WhatsappAPI) SendNewsletterMessage(c *fiber.Ctx) error {
+	payload := new(api.SendNewsletterMessagePayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	resp, err := wa.api.SendNewsletterMessage(c.UserContext(), &userInfo, payload)
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"success": false,
+			"message": "failed to publish newsletter message",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "success publish newsletter message",
+		"data": fiber.Map{
+			"id":        resp.ID,
+			"timestamp": resp.Timestamp,
+		},
+	})
+}
+
+func (wa *WhatsappAPI) GetNewsletterMessages(c *fiber.Ctx) error {
+	payload := new(api.NewsletterMessagesPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return err
+	}
+
+	userInfo := c.Locals("userinfo").(user.UserInfo)
+
+	messages, err := wa.api.GetNewsletterMessages(&userInfo, payload)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(messages)
+}
@@ -0,0 +1,81 @@
+package whatsapp
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/api"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+func TestUpdateUserOwner(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		log := databasetest.Logger()
+		users := user.NewRepository(db, log)
+		databasetest.Migrate(t, db, users)
+		wa := NewWhatsappAPI(api.New(log, nil, users, nil, nil, nil), nil, log, users)
+
+		var owner int64 = 7
+		tests := []struct {
+			name   string
+			scoped bool
+			body   string
+			owner  *int64
+		}{
+			{"rename keeps the owner", false, `{"name":"renamed","token":"t"}`, &owner},
+			{"owner is changed", false, `{"name":"renamed","owner_id":9}`, ptr(9)},
+			{"owner is removed", false, `{"name":"renamed","owner_id":null}`, nil},
+			{"scoped user cannot change the owner", true, `{"name":"renamed","owner_id":9}`, &owner},
+		}
+		for i, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				session := &user.User{Name: "session", Token: "token-" + strconv.Itoa(i), OwnerId: &owner}
+				if err := users.CreateUser(session); err != nil {
+					t.Fatalf("create: %v", err)
+				}
+
+				app := fiber.New()
+				app.Put("/session/:id", func(c *fiber.Ctx) error {
+					if tt.scoped {
+						c.Locals("sessionOwner", owner)
+					}
+					return c.Next()
+				}, wa.UpdateUser)
+
+				req := httptest.NewRequest(fiber.MethodPut, "/session/"+strconv.Itoa(session.Id), strings.NewReader(tt.body))
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != fiber.StatusOK {
+					t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+				}
+
+				updated, err := users.GetUserById(session.Id)
+				if err != nil {
+					t.Fatalf("get: %v", err)
+				}
+				if updated.Name != "renamed" {
+					t.Errorf("name = %q, want renamed", updated.Name)
+				}
+				switch {
+				case tt.owner == nil && updated.OwnerId != nil:
+					t.Errorf("owner = %d, want none", *updated.OwnerId)
+				case tt.owner != nil && (updated.OwnerId == nil || *updated.OwnerId != *tt.owner):
+					t.Errorf("owner = %v, want %d", updated.OwnerId, *tt.owner)
+				}
+			})
+		}
+	})
+}
+
+func ptr(id int64) *int64 {
+	return &id
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/health"
+	"github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/nugrhrizki/buzz/pkg/metrics"
+	"github.com/nugrhrizki/buzz/pkg/password"
+	"github.com/nugrhrizki/buzz/pkg/telemetry"
+	"github.com/nugrhrizki/buzz/pkg/token"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	whatsappApi "github.com/nugrhrizki/buzz/pkg/whatsapp/api"
+	whatsappApiKey "github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+	whatsappMessage "github.com/nugrhrizki/buzz/pkg/whatsapp/message"
+	whatsappUser "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+
+	"github.com/nugrhrizki/buzz/internal/authsession"
+	"github.com/nugrhrizki/buzz/internal/flag"
+	"github.com/nugrhrizki/buzz/internal/inbox"
+	"github.com/nugrhrizki/buzz/internal/lockout"
+	"github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/internal/user"
+)
+
+// Providers builds what the server and buzzctl share, the configuration is
+// supplied by the binary. fx only constructs what is asked for.
+var Providers = fx.Provide(
+	database.New,
+	log.New,
+	log.NewBuffer,
+	metrics.New,
+	telemetry.New,
+	health.New,
+	whatsappApi.New,
+	whatsapp.New,
+
+	role.NewRepository,
+	user.NewRepository,
+	password.NewPassword,
+	token.NewToken,
+	whatsappUser.NewRepository,
+	whatsappMessage.NewRepository,
+	whatsappApiKey.NewRepository,
+	inbox.NewRepository,
+	authsession.NewRepository,
+	lockout.NewRepository,
+	audit.NewRepository,
+	flag.NewRepository,
+	flag.NewEvaluator,
+)
+
+// Schema gathers every module that owns tables
+type Schema struct {
+	fx.In
+
+	Users    *whatsappUser.Repository
+	Messages *whatsappMessage.Repository
+	ApiKeys  *whatsappApiKey.Repository
+	Role     *role.Repository
+	User     *user.Repository
+	Logins   *authsession.Repository
+	Attempts *lockout.Repository
+	Inbox    *inbox.Repository
+	Audits   *audit.Repository
+	Flags    *flag.Repository
+}
+
+// Modules lists the modules in the order they migrate, tables come after
+// the ones they reference
+func (s Schema) Modules() []database.Migrate {
+	return []database.Migrate{
+		s.Users,
+		s.Messages,
+		s.ApiKeys,
+		s.Role,
+		s.User,
+		s.Logins,
+		s.Attempts,
+		s.Inbox,
+		s.Audits,
+		s.Flags,
+	}
+}
+
+// Seed creates the default role and admin when the database has none
+func (s Schema) Seed(db *database.Database) {
+	db.Seeder(s.Role, s.User)
+}
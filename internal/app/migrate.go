@@ -0,0 +1,58 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+const MigrateUsage = `commands:
+  status     list every migration and whether it is applied
+  up         apply all pending migrations
+  down [n]   revert the last n applied migrations, 1 by default`
+
+// Migrate runs a migrate subcommand, status is written to out
+func Migrate(db *database.Database, s Schema, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n\n%s", MigrateUsage)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := db.MigrationStatus(s.Modules()...)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODULE\tVERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%s\t%04d\t%s\t%s\t%s\n", status.Module, status.Version, status.Name, status.Status, appliedAt)
+		}
+		return w.Flush()
+
+	case "up":
+		return db.Migrate(s.Modules()...)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down takes a positive number of migrations, got %q", args[1])
+			}
+			steps = n
+		}
+		return db.MigrateDown(steps, s.Modules()...)
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], MigrateUsage)
+	}
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/nugrhrizki/buzz/internal/authsession"
+	"github.com/nugrhrizki/buzz/internal/flag"
+	"github.com/nugrhrizki/buzz/internal/inbox"
+	"github.com/nugrhrizki/buzz/internal/lockout"
+	"github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/internal/user"
+	"github.com/nugrhrizki/buzz/pkg/audit"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+	"github.com/nugrhrizki/buzz/pkg/password"
+	whatsappApiKey "github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+	whatsappMessage "github.com/nugrhrizki/buzz/pkg/whatsapp/message"
+	whatsappUser "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+func newSchema(db *database.Database) Schema {
+	log := databasetest.Logger()
+	return Schema{
+		Users:    whatsappUser.NewRepository(db, log),
+		Messages: whatsappMessage.NewRepository(db, log),
+		ApiKeys:  whatsappApiKey.NewRepository(db),
+		Role:     role.NewRepository(db),
+		User:     user.NewRepository(db, password.NewPassword(config.Default(), log)),
+		Logins:   authsession.NewRepository(db),
+		Attempts: lockout.NewRepository(db, config.Default()),
+		Inbox:    inbox.NewRepository(db),
+		Audits:   audit.NewRepository(db),
+		Flags:    flag.NewRepository(db),
+	}
+}
+
+// Every migration has to apply in order and revert back to an empty schema
+func TestMigrate(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		schema := newSchema(db)
+
+		count := func(status string) int {
+			t.Helper()
+			statuses, err := db.MigrationStatus(schema.Modules()...)
+			if err != nil {
+				t.Fatalf("status: %v", err)
+			}
+			n := 0
+			for _, s := range statuses {
+				if s.Status == status {
+					n++
+				}
+			}
+			return n
+		}
+
+		total := count(database.StatusPending)
+		if total == 0 {
+			t.Fatal("no migrations found")
+		}
+
+		steps := []struct {
+			args    []string
+			applied int
+		}{
+			{[]string{"up"}, total},
+			{[]string{"up"}, total},
+			{[]string{"down"}, total - 1},
+			{[]string{"up"}, total},
+			{[]string{"down", strconv.Itoa(total)}, 0},
+			{[]string{"up"}, total},
+		}
+		for _, tt := range steps {
+			if err := Migrate(db, schema, tt.args, io.Discard); err != nil {
+				t.Fatalf("migrate %v: %v", tt.args, err)
+			}
+			if applied := count(database.StatusApplied); applied != tt.applied {
+				t.Errorf("after migrate %v: %d applied, want %d", tt.args, applied, tt.applied)
+			}
+		}
+
+		schema.Seed(db)
+		if _, err := schema.User.GetUserByUsername("sadmin"); err != nil {
+			t.Errorf("seeded admin: %v", err)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package authsession
+
+import (
+	"embed"
+	"time"
+)
+
+// Session is a dashboard login. It holds the hash of the refresh token so a
+// login can be ended server side, access tokens carry its id as sid.
+type Session struct {
+	Id          int64      `json:"id"           db:"id"`
+	UserId      int64      `json:"user_id"      db:"user_id"`
+	RefreshHash string     `json:"-"            db:"refresh_hash"`
+	UserAgent   string     `json:"user_agent"   db:"user_agent"`
+	Ip          string     `json:"ip"           db:"ip"`
+	ExpiresAt   time.Time  `json:"expires_at"   db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"   db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"   db:"created_at"`
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
+
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
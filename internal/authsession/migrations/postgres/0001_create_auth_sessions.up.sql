@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS auth_sessions (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	refresh_hash TEXT NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS auth_sessions_refresh_hash_uindex ON auth_sessions (refresh_hash);
+CREATE INDEX IF NOT EXISTS auth_sessions_user_id_index ON auth_sessions (user_id);
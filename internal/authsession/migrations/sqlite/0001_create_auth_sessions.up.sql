@@ -0,0 +1,14 @@
+CREATE TABLE auth_sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	refresh_hash TEXT NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX auth_sessions_refresh_hash_uindex ON auth_sessions (refresh_hash);
+CREATE INDEX auth_sessions_user_id_index ON auth_sessions (user_id);
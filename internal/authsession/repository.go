@@ -0,0 +1,162 @@
+package authsession
+
+import (
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+type Repository struct {
+	db     *database.Database
+	active *cache.Cache
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{db, cache.New(time.Minute, 5*time.Minute)}
+}
+
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "authsession", migrations
+}
+
+func (r *Repository) CreateSession(session *Session) error {
+	return r.db.Get(
+		session,
+		`INSERT INTO auth_sessions (user_id, refresh_hash, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *`,
+		session.UserId,
+		session.RefreshHash,
+		session.UserAgent,
+		session.Ip,
+		session.ExpiresAt,
+	)
+}
+
+func (r *Repository) GetSessionByRefreshHash(hash string) (*Session, error) {
+	var session Session
+	err := r.db.Get(
+		&session,
+		"SELECT * FROM auth_sessions WHERE refresh_hash = $1",
+		hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *Repository) GetUserSessions(userId int64) ([]Session, error) {
+	sessions := []Session{}
+	err := r.db.Select(
+		&sessions,
+		`SELECT * FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY id DESC`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Rotate swaps the refresh token of a session, the old one stops working
+func (r *Repository) Rotate(session *Session, hash string) error {
+	_, err := r.db.Exec(
+		`UPDATE auth_sessions
+		SET refresh_hash = $1, last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $2`,
+		hash,
+		session.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	session.RefreshHash = hash
+	return nil
+}
+
+// IsActive tells whether an access token sid still belongs to a live session.
+// It is checked on every request so the answer is cached for a minute, a
+// revocation from this process drops it right away.
+func (r *Repository) IsActive(id int64) (bool, error) {
+	key := strconv.FormatInt(id, 10)
+	if active, found := r.active.Get(key); found {
+		return active.(bool), nil
+	}
+
+	var session Session
+	err := r.db.Get(
+		&session,
+		"SELECT * FROM auth_sessions WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	r.active.SetDefault(key, session.Active())
+	return session.Active(), nil
+}
+
+func (r *Repository) RevokeSession(id int64) error {
+	_, err := r.db.Exec(
+		"UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	r.active.Delete(strconv.FormatInt(id, 10))
+	return nil
+}
+
+// RevokeUserSessions logs a user out everywhere
+func (r *Repository) RevokeUserSessions(userId int64) error {
+	var ids []int64
+	err := r.db.Select(
+		&ids,
+		`UPDATE auth_sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING id`,
+		userId,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		r.active.Delete(strconv.FormatInt(id, 10))
+	}
+	return nil
+}
+
+// RevokeOtherSessions logs a user out everywhere except the given session
+func (r *Repository) RevokeOtherSessions(userId int64, keepId int64) error {
+	var ids []int64
+	err := r.db.Select(
+		&ids,
+		`UPDATE auth_sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		RETURNING id`,
+		userId,
+		keepId,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		r.active.Delete(strconv.FormatInt(id, 10))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package authsession
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/internal/user"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestRepository(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		roles := role.NewRepository(db)
+		users := user.NewRepository(db, nil)
+		sessions := NewRepository(db)
+		databasetest.Migrate(t, db, roles, users, sessions)
+		if err := roles.Seed(); err != nil {
+			t.Fatalf("seed roles: %v", err)
+		}
+		for _, username := range []string{"alice", "bob"} {
+			if err := users.CreateUser(&user.User{Name: username, Username: username, Password: "hash", RoleId: 1}); err != nil {
+				t.Fatalf("create %s: %v", username, err)
+			}
+		}
+
+		created := []*Session{
+			{UserId: 1, RefreshHash: "current", ExpiresAt: time.Now().Add(time.Hour)},
+			{UserId: 1, RefreshHash: "other", ExpiresAt: time.Now().Add(time.Hour)},
+			{UserId: 1, RefreshHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)},
+			{UserId: 2, RefreshHash: "bob", ExpiresAt: time.Now().Add(time.Hour)},
+		}
+		for _, session := range created {
+			session.UserAgent, session.Ip = "test", "127.0.0.1"
+			if err := sessions.CreateSession(session); err != nil {
+				t.Fatalf("create %s: %v", session.RefreshHash, err)
+			}
+			if session.Id == 0 || session.CreatedAt.IsZero() {
+				t.Errorf("created %s session was not returned: %+v", session.RefreshHash, session)
+			}
+		}
+		current, other, expired, bob := created[0], created[1], created[2], created[3]
+
+		// only sessions that have not expired are listed
+		listed, err := sessions.GetUserSessions(1)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(listed) != 2 || listed[0].Id != other.Id || listed[1].Id != current.Id {
+			t.Errorf("sessions of alice: got %+v", listed)
+		}
+
+		if err := sessions.Rotate(current, "rotated"); err != nil {
+			t.Fatalf("rotate: %v", err)
+		}
+		if _, err := sessions.GetSessionByRefreshHash("current"); err == nil {
+			t.Error("the refresh token before rotation should be gone")
+		}
+		rotated, err := sessions.GetSessionByRefreshHash("rotated")
+		if err != nil {
+			t.Fatalf("get rotated: %v", err)
+		}
+		if rotated.Id != current.Id || rotated.LastUsedAt == nil {
+			t.Errorf("rotated session: got %+v", rotated)
+		}
+
+		active := func(want map[*Session]bool) {
+			t.Helper()
+			for session, want := range want {
+				got, err := sessions.IsActive(session.Id)
+				if err != nil {
+					t.Fatalf("is active %s: %v", session.RefreshHash, err)
+				}
+				if got != want {
+					t.Errorf("session %s active = %v, want %v", session.RefreshHash, got, want)
+				}
+			}
+		}
+		active(map[*Session]bool{current: true, other: true, expired: false, bob: true})
+
+		if err := sessions.RevokeOtherSessions(1, current.Id); err != nil {
+			t.Fatalf("revoke others: %v", err)
+		}
+		active(map[*Session]bool{current: true, other: false, bob: true})
+
+		if err := sessions.RevokeSession(bob.Id); err != nil {
+			t.Fatalf("revoke: %v", err)
+		}
+		active(map[*Session]bool{current: true, bob: false})
+
+		if err := sessions.RevokeUserSessions(1); err != nil {
+			t.Fatalf("revoke all: %v", err)
+		}
+		active(map[*Session]bool{current: false})
+		if listed, _ := sessions.GetUserSessions(1); len(listed) != 0 {
+			t.Errorf("got %d sessions after revoking all, want 0", len(listed))
+		}
+	})
+}
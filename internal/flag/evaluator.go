@@ -0,0 +1,133 @@
+package flag
+
+import (
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/rs/zerolog"
+
+	"github.com/nugrhrizki/buzz/internal/user"
+	sessions "github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+// cacheTTL bounds how long another instance takes to see a changed flag
+const cacheTTL = 30 * time.Second
+
+const flagsKey = "flags"
+
+// Subject is what a flag is evaluated for, a session and the role of the
+// user the session is assigned to. Either can be zero.
+type Subject struct {
+	Session int
+	Role    int64
+}
+
+// Evaluator answers whether a flag is on. Flags and the subjects of
+// sessions are cached as they are checked on every message.
+type Evaluator struct {
+	flags    *Repository
+	sessions *sessions.Repository
+	users    *user.Repository
+	log      *zerolog.Logger
+
+	cache *cache.Cache
+}
+
+func NewEvaluator(
+	flags *Repository,
+	sessions *sessions.Repository,
+	users *user.Repository,
+	log *zerolog.Logger,
+) *Evaluator {
+	return &Evaluator{
+		flags:    flags,
+		sessions: sessions,
+		users:    users,
+		log:      log,
+
+		cache: cache.New(cacheTTL, 5*time.Minute),
+	}
+}
+
+// Enabled tells whether the flag is on for subject. Targeted sessions and
+// roles always get it, other sessions when they fall in the rollout.
+func (e *Evaluator) Enabled(name string, subject Subject) bool {
+	flag, found := e.load()[name]
+	if !found {
+		return Defaults[name]
+	}
+	if !flag.Enabled {
+		return false
+	}
+
+	if subject.Session != 0 && flag.Sessions.Has(int64(subject.Session)) {
+		return true
+	}
+	if subject.Role != 0 && flag.Roles.Has(subject.Role) {
+		return true
+	}
+
+	if subject.Session == 0 {
+		return flag.Rollout >= 100
+	}
+	return bucket(name, subject.Session) < flag.Rollout
+}
+
+// SessionEnabled tells whether the flag is on for a session
+func (e *Evaluator) SessionEnabled(name string, session int) bool {
+	return e.Enabled(name, e.Session(session))
+}
+
+// Session returns the subject of a session, with the role of its owner
+func (e *Evaluator) Session(id int) Subject {
+	key := "session:" + strconv.Itoa(id)
+	if subject, found := e.cache.Get(key); found {
+		return subject.(Subject)
+	}
+
+	subject := Subject{Session: id}
+	session, err := e.sessions.GetUserById(id)
+	if err == nil && session.OwnerId != nil {
+		if owner, err := e.users.GetUserById(int(*session.OwnerId)); err == nil {
+			subject.Role = owner.RoleId
+		}
+	}
+
+	e.cache.SetDefault(key, subject)
+	return subject
+}
+
+// Invalidate drops the cache, the next check sees the stored flags
+func (e *Evaluator) Invalidate() {
+	e.cache.Flush()
+}
+
+// load returns the flags by name. When they cannot be read every flag is
+// at its default until the next try.
+func (e *Evaluator) load() map[string]Flag {
+	if flags, found := e.cache.Get(flagsKey); found {
+		return flags.(map[string]Flag)
+	}
+
+	byName := map[string]Flag{}
+	flags, err := e.flags.GetFlags()
+	if err != nil {
+		e.log.Error().Err(err).Msg("Failed to load feature flags")
+	}
+	for _, flag := range flags {
+		byName[flag.Name] = flag
+	}
+
+	e.cache.SetDefault(flagsKey, byName)
+	return byName
+}
+
+// bucket places a session in one of 100 buckets, the same one for a flag
+// every time, so raising the rollout only adds sessions
+func bucket(name string, session int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + strconv.Itoa(session)))
+	return int(h.Sum32() % 100)
+}
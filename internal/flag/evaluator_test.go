@@ -0,0 +1,118 @@
+package flag
+
+import (
+	"testing"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestEnabled(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		flags := NewRepository(db)
+
+		// before the migration the flags cannot be read, every flag is at
+		// its default
+		unread := NewEvaluator(flags, nil, nil, databasetest.Logger())
+		if !unread.Enabled(MediaAutoDownload, Subject{Session: 1}) || unread.Enabled(HumanizedTyping, Subject{Session: 1}) {
+			t.Error("flags that cannot be read are not at their default")
+		}
+
+		databasetest.Migrate(t, db, flags)
+		for _, flag := range []*Flag{
+			{Name: "disabled", Rollout: 100, Sessions: Ids{1}, Roles: Ids{3}},
+			{Name: "targeted", Enabled: true, Sessions: Ids{1}, Roles: Ids{3}},
+			{Name: "half", Enabled: true, Rollout: 50},
+			{Name: "full", Enabled: true, Rollout: 100},
+			{Name: MediaAutoDownload, Enabled: false},
+		} {
+			if err := flags.CreateFlag(flag); err != nil {
+				t.Fatalf("create %s: %v", flag.Name, err)
+			}
+		}
+		evaluator := NewEvaluator(flags, nil, nil, databasetest.Logger())
+
+		// in and out are sessions inside and outside of the half rollout
+		in, out := 0, 0
+		for session := 1; in == 0 || out == 0; session++ {
+			if bucket("half", session) < 50 {
+				in = session
+			} else {
+				out = session
+			}
+		}
+
+		tests := []struct {
+			name    string
+			flag    string
+			subject Subject
+			want    bool
+		}{
+			{"disabled flag for a targeted session", "disabled", Subject{Session: 1}, false},
+			{"disabled flag for a targeted role", "disabled", Subject{Role: 3}, false},
+			{"targeted session", "targeted", Subject{Session: 1}, true},
+			{"targeted role", "targeted", Subject{Session: 2, Role: 3}, true},
+			{"targeted role without a session", "targeted", Subject{Role: 3}, true},
+			{"untargeted session", "targeted", Subject{Session: 2, Role: 4}, false},
+			{"session in the rollout", "half", Subject{Session: in}, true},
+			{"session out of the rollout", "half", Subject{Session: out}, false},
+			{"no session in a partial rollout", "half", Subject{}, false},
+			{"no session in a full rollout", "full", Subject{}, true},
+			{"stored flag overrides its default", MediaAutoDownload, Subject{Session: 1}, false},
+			{"unstored flag is at its default", HumanizedTyping, Subject{Session: 1}, Defaults[HumanizedTyping]},
+			{"unknown flag", "unknown", Subject{Session: 1}, false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := evaluator.Enabled(tt.flag, tt.subject); got != tt.want {
+					t.Errorf("Enabled(%s, %+v) = %v, want %v", tt.flag, tt.subject, got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestBucket(t *testing.T) {
+	const sessions = 1000
+
+	tests := []struct {
+		rollout int
+		min     int
+		max     int
+	}{
+		{0, 0, 0},
+		{10, 50, 150},
+		{25, 175, 325},
+		{50, 400, 600},
+		{90, 850, 950},
+		{100, sessions, sessions},
+	}
+	for _, name := range []string{MediaAutoDownload, HumanizedTyping} {
+		previous := map[int]bool{}
+		for _, tt := range tests {
+			current := map[int]bool{}
+			for session := 1; session <= sessions; session++ {
+				b := bucket(name, session)
+				if b < 0 || b >= 100 {
+					t.Fatalf("bucket(%s, %d) = %d, want 0 to 99", name, session, b)
+				}
+				if b != bucket(name, session) {
+					t.Fatalf("bucket(%s, %d) changed between calls", name, session)
+				}
+				if b < tt.rollout {
+					current[session] = true
+				}
+			}
+
+			if len(current) < tt.min || len(current) > tt.max {
+				t.Errorf("%s at %d%%: %d of %d sessions, want %d to %d", name, tt.rollout, len(current), sessions, tt.min, tt.max)
+			}
+			for session := range previous {
+				if !current[session] {
+					t.Errorf("%s: raising the rollout to %d%% dropped session %d", name, tt.rollout, session)
+				}
+			}
+			previous = current
+		}
+	}
+}
@@ -1 +1,103 @@
 package flag
+
+import (
+	"database/sql/driver"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Flags Buzz checks. A flag without a row in the database is at its default.
+const (
+	// MediaAutoDownload stores the media of received messages
+	MediaAutoDownload = "media_auto_download"
+	// HumanizedTyping shows the typing indicator before every message a
+	// session sends, as if the request asked for humanize
+	HumanizedTyping = "humanized_typing"
+)
+
+// Defaults are the values of the known flags until they are stored
+var Defaults = map[string]bool{
+	MediaAutoDownload: true,
+	HumanizedTyping:   false,
+}
+
+var (
+	ErrInvalidName    = errors.New("flag name should only contain lowercase letters, digits, dots, dashes and underscores")
+	ErrInvalidRollout = errors.New("rollout should be between 0 and 100")
+	ErrFlagExists     = errors.New("flag already exists")
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9_.-]+$`)
+
+// Flag turns a feature on for the sessions and roles it targets and for a
+// percentage of the other sessions. A disabled flag is off for everyone.
+type Flag struct {
+	Id          int64      `json:"id"          db:"id"`
+	Name        string     `json:"name"        db:"name"`
+	Description string     `json:"description" db:"description"`
+	Enabled     bool       `json:"enabled"     db:"enabled"`
+	Rollout     int        `json:"rollout"     db:"rollout"`
+	Sessions    Ids        `json:"sessions"    db:"sessions"`
+	Roles       Ids        `json:"roles"       db:"roles"`
+	CreatedAt   time.Time  `json:"created_at"  db:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"  db:"updated_at"`
+}
+
+func (f *Flag) Validate() error {
+	if !validName.MatchString(f.Name) {
+		return ErrInvalidName
+	}
+	if f.Rollout < 0 || f.Rollout > 100 {
+		return ErrInvalidRollout
+	}
+	return nil
+}
+
+// Ids is a list of ids stored as a JSON array
+type Ids []int64
+
+func (ids *Ids) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case nil:
+		*ids = Ids{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into ids", value)
+	}
+	return json.Unmarshal(data, ids)
+}
+
+func (ids Ids) Value() (driver.Value, error) {
+	if ids == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(ids)
+	return string(data), err
+}
+
+// MarshalJSON writes an empty list rather than null when no ids are set
+func (ids Ids) MarshalJSON() ([]byte, error) {
+	if ids == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]int64(ids))
+}
+
+func (ids Ids) Has(id int64) bool {
+	return slices.Contains(ids, id)
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
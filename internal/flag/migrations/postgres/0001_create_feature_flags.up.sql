@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS feature_flags (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	rollout INTEGER NOT NULL DEFAULT 0,
+	sessions TEXT NOT NULL DEFAULT '[]',
+	roles TEXT NOT NULL DEFAULT '[]',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS feature_flags_name_uindex ON feature_flags (name);
@@ -0,0 +1,13 @@
+CREATE TABLE feature_flags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	rollout INTEGER NOT NULL DEFAULT 0,
+	sessions TEXT NOT NULL DEFAULT '[]',
+	roles TEXT NOT NULL DEFAULT '[]',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX feature_flags_name_uindex ON feature_flags (name);
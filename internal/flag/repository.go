@@ -1 +1,128 @@
 package flag
+
+import (
+	"database/sql"
+	"io/fs"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{db}
+}
+
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "flag", migrations
+}
+
+func (r *Repository) CreateFlag(flag *Flag) error {
+	_, err := r.GetFlagByName(flag.Name)
+	switch err {
+	case sql.ErrNoRows:
+		break
+	case nil:
+		return ErrFlagExists
+	default:
+		return err
+	}
+
+	return r.db.Get(
+		flag,
+		`INSERT INTO feature_flags (name, description, enabled, rollout, sessions, roles)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *`,
+		flag.Name,
+		flag.Description,
+		flag.Enabled,
+		flag.Rollout,
+		flag.Sessions,
+		flag.Roles,
+	)
+}
+
+func (r *Repository) GetFlags() ([]Flag, error) {
+	flags := []Flag{}
+	err := r.db.Select(
+		&flags,
+		"SELECT * FROM feature_flags ORDER BY name ASC",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+func (r *Repository) GetFlagById(id int) (*Flag, error) {
+	var flag Flag
+	err := r.db.Get(
+		&flag,
+		"SELECT * FROM feature_flags WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+func (r *Repository) GetFlagByName(name string) (*Flag, error) {
+	var flag Flag
+	err := r.db.Get(
+		&flag,
+		"SELECT * FROM feature_flags WHERE name = $1",
+		name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+func (r *Repository) UpdateFlag(flag *Flag) error {
+	existing, err := r.GetFlagByName(flag.Name)
+	switch err {
+	case sql.ErrNoRows:
+		break
+	case nil:
+		if existing.Id != flag.Id {
+			return ErrFlagExists
+		}
+	default:
+		return err
+	}
+
+	_, err = r.db.Exec(
+		`UPDATE feature_flags
+		SET
+			name = $1,
+			description = $2,
+			enabled = $3,
+			rollout = $4,
+			sessions = $5,
+			roles = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $7`,
+		flag.Name,
+		flag.Description,
+		flag.Enabled,
+		flag.Rollout,
+		flag.Sessions,
+		flag.Roles,
+		flag.Id,
+	)
+	return err
+}
+
+// DeleteFlag removes the flag, a known flag goes back to its default
+func (r *Repository) DeleteFlag(flag *Flag) error {
+	_, err := r.db.Exec(
+		"DELETE FROM feature_flags WHERE id = $1",
+		flag.Id,
+	)
+	return err
+}
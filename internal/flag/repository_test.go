@@ -0,0 +1,81 @@
+package flag
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestRepository(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		flags := NewRepository(db)
+		databasetest.Migrate(t, db, flags)
+
+		created := []*Flag{
+			{Name: MediaAutoDownload, Enabled: true, Rollout: 100},
+			{Name: HumanizedTyping, Description: "typing", Rollout: 25, Sessions: Ids{1, 2}, Roles: Ids{3}},
+		}
+		for _, flag := range created {
+			if err := flags.CreateFlag(flag); err != nil {
+				t.Fatalf("create %s: %v", flag.Name, err)
+			}
+			if flag.Id == 0 || flag.CreatedAt.IsZero() {
+				t.Errorf("created %s flag was not returned: %+v", flag.Name, flag)
+			}
+		}
+		if err := flags.CreateFlag(&Flag{Name: HumanizedTyping}); !errors.Is(err, ErrFlagExists) {
+			t.Errorf("create a used name: got %v, want %v", err, ErrFlagExists)
+		}
+
+		typing, err := flags.GetFlagByName(HumanizedTyping)
+		if err != nil {
+			t.Fatalf("get by name: %v", err)
+		}
+		if typing.Rollout != 25 || !slices.Equal(typing.Sessions, Ids{1, 2}) || !slices.Equal(typing.Roles, Ids{3}) {
+			t.Errorf("get by name: got %+v", typing)
+		}
+
+		updates := []struct {
+			name   string
+			change func(flag *Flag)
+			err    error
+		}{
+			{"targets", func(flag *Flag) { flag.Enabled, flag.Sessions, flag.Roles = true, nil, Ids{4, 5} }, nil},
+			{"to a used name", func(flag *Flag) { flag.Name = MediaAutoDownload }, ErrFlagExists},
+		}
+		for _, tt := range updates {
+			flag := *typing
+			tt.change(&flag)
+			if err := flags.UpdateFlag(&flag); !errors.Is(err, tt.err) {
+				t.Errorf("update %s: got %v, want %v", tt.name, err, tt.err)
+			}
+		}
+
+		updated, err := flags.GetFlagById(int(typing.Id))
+		if err != nil {
+			t.Fatalf("get by id: %v", err)
+		}
+		if updated.Name != HumanizedTyping || !updated.Enabled || len(updated.Sessions) != 0 || !slices.Equal(updated.Roles, Ids{4, 5}) || updated.UpdatedAt == nil {
+			t.Errorf("update was not stored: %+v", updated)
+		}
+
+		all, err := flags.GetFlags()
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(all) != 2 || all[0].Name != HumanizedTyping || all[1].Name != MediaAutoDownload {
+			t.Errorf("flags: got %+v", all)
+		}
+
+		if err := flags.DeleteFlag(updated); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := flags.GetFlagByName(HumanizedTyping); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("deleted flag: got %v, want %v", err, sql.ErrNoRows)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package inbox
+
+import (
+	"embed"
+	"errors"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+const (
+	StatusOpen    = "open"
+	StatusPending = "pending"
+	StatusClosed  = "closed"
+)
+
+var ErrInvalidStatus = errors.New("status should be one of open, pending or closed")
+
+type Conversation struct {
+	WhatsappUserId int           `json:"whatsapp_user_id" db:"whatsapp_user_id"`
+	Chat           string        `json:"chat"             db:"chat"`
+	Status         string        `json:"status"           db:"status"`
+	AssigneeId     *int64        `json:"assignee_id"      db:"assignee_id"`
+	LastMessage    string        `json:"last_message"     db:"last_message"`
+	LastMessageAt  database.Time `json:"last_message_at"  db:"last_message_at"`
+	Unread         int64         `json:"unread"           db:"unread"`
+	UpdatedAt      *time.Time    `json:"updated_at"       db:"updated_at"`
+}
+
+type Note struct {
+	Id             int64     `json:"id"               db:"id"`
+	WhatsappUserId int       `json:"whatsapp_user_id" db:"whatsapp_user_id"`
+	Chat           string    `json:"chat"             db:"chat"`
+	AuthorId       int64     `json:"author_id"        db:"author_id"`
+	Body           string    `json:"body"             db:"body"`
+	CreatedAt      time.Time `json:"created_at"       db:"created_at"`
+}
+
+type CannedReply struct {
+	Id        int64      `json:"id"         db:"id"`
+	Shortcut  string     `json:"shortcut"   db:"shortcut"`
+	Title     string     `json:"title"      db:"title"`
+	Body      string     `json:"body"       db:"body"`
+	CreatedBy int64      `json:"created_by" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at" db:"deleted_at"`
+}
+
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusOpen, StatusPending, StatusClosed:
+		return true
+	}
+	return false
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS inbox_conversations (
+	id BIGSERIAL PRIMARY KEY,
+	whatsapp_user_id BIGINT NOT NULL,
+	chat TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'open',
+	assignee_id BIGINT REFERENCES users (id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS inbox_conversations_chat_uindex ON inbox_conversations (whatsapp_user_id, chat);
+
+CREATE TABLE IF NOT EXISTS inbox_notes (
+	id BIGSERIAL PRIMARY KEY,
+	whatsapp_user_id BIGINT NOT NULL,
+	chat TEXT NOT NULL,
+	author_id BIGINT NOT NULL REFERENCES users (id),
+	body TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS inbox_notes_chat_index ON inbox_notes (whatsapp_user_id, chat);
+
+CREATE TABLE IF NOT EXISTS inbox_canned_replies (
+	id BIGSERIAL PRIMARY KEY,
+	shortcut TEXT NOT NULL,
+	title TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_by BIGINT NOT NULL REFERENCES users (id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
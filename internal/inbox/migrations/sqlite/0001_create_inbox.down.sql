@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS inbox_canned_replies;
+DROP TABLE IF EXISTS inbox_notes;
+DROP TABLE IF EXISTS inbox_conversations;
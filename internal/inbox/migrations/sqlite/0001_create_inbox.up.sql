@@ -0,0 +1,33 @@
+CREATE TABLE inbox_conversations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	whatsapp_user_id BIGINT NOT NULL,
+	chat TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'open',
+	assignee_id BIGINT REFERENCES users (id),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX inbox_conversations_chat_uindex ON inbox_conversations (whatsapp_user_id, chat);
+
+CREATE TABLE inbox_notes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	whatsapp_user_id BIGINT NOT NULL,
+	chat TEXT NOT NULL,
+	author_id BIGINT NOT NULL REFERENCES users (id),
+	body TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX inbox_notes_chat_index ON inbox_notes (whatsapp_user_id, chat);
+
+CREATE TABLE inbox_canned_replies (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	shortcut TEXT NOT NULL,
+	title TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_by BIGINT NOT NULL REFERENCES users (id),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP,
+	deleted_at TIMESTAMP
+);
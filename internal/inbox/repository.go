@@ -0,0 +1,212 @@
+package inbox
+
+import (
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"io/fs"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{db}
+}
+
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "inbox", migrations
+}
+
+// Conversations are derived from stored messages, inbox_conversations only
+// holds what agents changed on top of them (status and assignment).
+const conversationQuery = `SELECT
+		m.whatsapp_user_id,
+		m.chat,
+		COALESCE(c.status, 'open') AS status,
+		c.assignee_id,
+		c.updated_at,
+		MAX(m.timestamp) AS last_message_at,
+		SUM(CASE WHEN m.from_me = FALSE AND m.read = FALSE THEN 1 ELSE 0 END) AS unread,
+		(
+			SELECT l.body FROM whatsapp_messages l
+			WHERE l.whatsapp_user_id = m.whatsapp_user_id AND l.chat = m.chat
+			ORDER BY l.timestamp DESC, l.id DESC
+			LIMIT 1
+		) AS last_message
+	FROM whatsapp_messages m
+	LEFT JOIN inbox_conversations c ON c.whatsapp_user_id = m.whatsapp_user_id AND c.chat = m.chat`
+
+type ConversationFilter struct {
+	WhatsappUserId int
+	Status         string
+	AssigneeId     int64
+}
+
+func (r *Repository) GetConversations(filter ConversationFilter) ([]Conversation, error) {
+	conversations := []Conversation{}
+	err := r.db.Select(
+		&conversations,
+		conversationQuery+`
+		WHERE m.whatsapp_user_id = $1
+			AND ($2 = '' OR COALESCE(c.status, 'open') = $2)
+			AND ($3 = 0 OR c.assignee_id = $3)
+		GROUP BY m.whatsapp_user_id, m.chat, c.status, c.assignee_id, c.updated_at
+		ORDER BY last_message_at DESC`,
+		filter.WhatsappUserId,
+		filter.Status,
+		filter.AssigneeId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+func (r *Repository) GetConversation(userId int, chat string) (*Conversation, error) {
+	var conversation Conversation
+	err := r.db.Get(
+		&conversation,
+		conversationQuery+`
+		WHERE m.whatsapp_user_id = $1 AND m.chat = $2
+		GROUP BY m.whatsapp_user_id, m.chat, c.status, c.assignee_id, c.updated_at`,
+		userId,
+		chat,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (r *Repository) SetStatus(userId int, chat string, status string) error {
+	if !ValidStatus(status) {
+		return ErrInvalidStatus
+	}
+
+	_, err := r.db.Exec(
+		`INSERT INTO inbox_conversations (whatsapp_user_id, chat, status) VALUES ($1, $2, $3)
+		ON CONFLICT (whatsapp_user_id, chat)
+		DO UPDATE SET status = EXCLUDED.status, updated_at = CURRENT_TIMESTAMP`,
+		userId,
+		chat,
+		status,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) Assign(userId int, chat string, assigneeId *int64) error {
+	_, err := r.db.Exec(
+		`INSERT INTO inbox_conversations (whatsapp_user_id, chat, assignee_id) VALUES ($1, $2, $3)
+		ON CONFLICT (whatsapp_user_id, chat)
+		DO UPDATE SET assignee_id = EXCLUDED.assignee_id, updated_at = CURRENT_TIMESTAMP`,
+		userId,
+		chat,
+		assigneeId,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) CreateNote(note *Note) error {
+	_, err := r.db.Exec(
+		"INSERT INTO inbox_notes (whatsapp_user_id, chat, author_id, body) VALUES ($1, $2, $3, $4)",
+		note.WhatsappUserId,
+		note.Chat,
+		note.AuthorId,
+		note.Body,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetNotes(userId int, chat string) ([]Note, error) {
+	notes := []Note{}
+	err := r.db.Select(
+		&notes,
+		"SELECT * FROM inbox_notes WHERE whatsapp_user_id = $1 AND chat = $2 ORDER BY created_at ASC",
+		userId,
+		chat,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (r *Repository) CreateCannedReply(reply *CannedReply) error {
+	_, err := r.db.Exec(
+		"INSERT INTO inbox_canned_replies (shortcut, title, body, created_by) VALUES ($1, $2, $3, $4)",
+		reply.Shortcut,
+		reply.Title,
+		reply.Body,
+		reply.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetCannedReplies() ([]CannedReply, error) {
+	replies := []CannedReply{}
+	err := r.db.Select(
+		&replies,
+		"SELECT * FROM inbox_canned_replies WHERE deleted_at IS NULL ORDER BY shortcut ASC",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+func (r *Repository) GetCannedReplyById(id int) (*CannedReply, error) {
+	var reply CannedReply
+	err := r.db.Get(
+		&reply,
+		"SELECT * FROM inbox_canned_replies WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+func (r *Repository) UpdateCannedReply(reply *CannedReply) error {
+	_, err := r.db.Exec(
+		`UPDATE inbox_canned_replies
+		SET
+			shortcut = $1,
+			title = $2,
+			body = $3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $4`,
+		reply.Shortcut,
+		reply.Title,
+		reply.Body,
+		reply.Id,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) DeleteCannedReply(reply *CannedReply) error {
+	_, err := r.db.Exec(
+		"UPDATE inbox_canned_replies SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1",
+		reply.Id,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/internal/user"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/message"
+)
+
+// newRepository migrates the inbox with the messages it is built from and
+// the dashboard users it references, alice has id 1 and bob id 2
+func newRepository(t *testing.T, db *database.Database) (*Repository, *message.Repository) {
+	roles := role.NewRepository(db)
+	users := user.NewRepository(db, nil)
+	messages := message.NewRepository(db, databasetest.Logger())
+	inbox := NewRepository(db)
+	databasetest.Migrate(t, db, messages, roles, users, inbox)
+
+	if err := roles.Seed(); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+	for _, username := range []string{"alice", "bob"} {
+		if err := users.CreateUser(&user.User{Name: username, Username: username, Password: "hash", RoleId: 1}); err != nil {
+			t.Fatalf("create %s: %v", username, err)
+		}
+	}
+	return inbox, messages
+}
+
+func TestConversations(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		inbox, messages := newRepository(t, db)
+
+		// times are given outside of UTC to check they come back as the same instant
+		zone := time.FixedZone("WIB", 7*60*60)
+		at := func(minute int) time.Time {
+			return time.Date(2026, 1, 2, 10, minute, 0, 0, zone)
+		}
+		stored := []message.Message{
+			{WhatsappUserId: 1, MessageId: "a1", Chat: "a", Body: "hello", Timestamp: at(1)},
+			{WhatsappUserId: 1, MessageId: "a2", Chat: "a", Body: "anyone?", Timestamp: at(2)},
+			{WhatsappUserId: 1, MessageId: "a3", Chat: "a", Body: "hi there", FromMe: true, Read: true, Timestamp: at(3)},
+			{WhatsappUserId: 1, MessageId: "b1", Chat: "b", Body: "thanks", Timestamp: at(5)},
+			{WhatsappUserId: 1, MessageId: "c1", Chat: "c", Body: "read already", Read: true, Timestamp: at(0)},
+			{WhatsappUserId: 2, MessageId: "d1", Chat: "a", Body: "other session", Timestamp: at(9)},
+		}
+		for i := range stored {
+			if err := messages.CreateMessage(context.Background(), &stored[i]); err != nil {
+				t.Fatalf("store %s: %v", stored[i].MessageId, err)
+			}
+		}
+
+		var bob int64 = 2
+		if err := inbox.SetStatus(1, "b", StatusClosed); err != nil {
+			t.Fatalf("set status: %v", err)
+		}
+		if err := inbox.SetStatus(1, "b", "archived"); !errors.Is(err, ErrInvalidStatus) {
+			t.Errorf("set an unknown status: got %v, want %v", err, ErrInvalidStatus)
+		}
+		if err := inbox.Assign(1, "a", &bob); err != nil {
+			t.Fatalf("assign: %v", err)
+		}
+		if err := inbox.Assign(1, "c", &bob); err != nil {
+			t.Fatalf("assign: %v", err)
+		}
+		if err := inbox.Assign(1, "c", nil); err != nil {
+			t.Fatalf("unassign: %v", err)
+		}
+
+		tests := []struct {
+			name   string
+			filter ConversationFilter
+			chats  []string
+		}{
+			{"session", ConversationFilter{WhatsappUserId: 1}, []string{"b", "a", "c"}},
+			{"other session", ConversationFilter{WhatsappUserId: 2}, []string{"a"}},
+			{"open", ConversationFilter{WhatsappUserId: 1, Status: StatusOpen}, []string{"a", "c"}},
+			{"closed", ConversationFilter{WhatsappUserId: 1, Status: StatusClosed}, []string{"b"}},
+			{"assigned", ConversationFilter{WhatsappUserId: 1, AssigneeId: bob}, []string{"a"}},
+			{"assigned and closed", ConversationFilter{WhatsappUserId: 1, Status: StatusClosed, AssigneeId: bob}, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				conversations, err := inbox.GetConversations(tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var chats []string
+				for _, conversation := range conversations {
+					chats = append(chats, conversation.Chat)
+				}
+				if len(chats) != len(tt.chats) {
+					t.Fatalf("got %v, want %v", chats, tt.chats)
+				}
+				for i := range chats {
+					if chats[i] != tt.chats[i] {
+						t.Fatalf("got %v, want %v", chats, tt.chats)
+					}
+				}
+			})
+		}
+
+		conversation, err := inbox.GetConversation(1, "a")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if conversation.Status != StatusOpen ||
+			conversation.AssigneeId == nil || *conversation.AssigneeId != bob ||
+			conversation.Unread != 2 ||
+			conversation.LastMessage != "hi there" ||
+			!conversation.LastMessageAt.Equal(at(3)) {
+			t.Errorf("conversation a: got %+v", conversation)
+		}
+		if unassigned, _ := inbox.GetConversation(1, "c"); unassigned.AssigneeId != nil || unassigned.Unread != 0 || unassigned.UpdatedAt == nil {
+			t.Errorf("conversation c: got %+v", unassigned)
+		}
+		if _, err := inbox.GetConversation(1, "missing"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("conversation without messages: got %v, want %v", err, sql.ErrNoRows)
+		}
+	})
+}
+
+func TestNotes(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		inbox, _ := newRepository(t, db)
+
+		for _, note := range []Note{
+			{WhatsappUserId: 1, Chat: "a", AuthorId: 1, Body: "first"},
+			{WhatsappUserId: 1, Chat: "a", AuthorId: 2, Body: "second"},
+			{WhatsappUserId: 1, Chat: "b", AuthorId: 1, Body: "elsewhere"},
+		} {
+			if err := inbox.CreateNote(&note); err != nil {
+				t.Fatalf("create note: %v", err)
+			}
+		}
+		if err := inbox.CreateNote(&Note{WhatsappUserId: 1, Chat: "a", AuthorId: 99, Body: "ghost"}); err == nil {
+			t.Error("a note by an unknown author should be refused")
+		}
+
+		notes, err := inbox.GetNotes(1, "a")
+		if err != nil {
+			t.Fatalf("get notes: %v", err)
+		}
+		if len(notes) != 2 || notes[0].Body != "first" || notes[1].AuthorId != 2 {
+			t.Errorf("notes of a: got %+v", notes)
+		}
+	})
+}
+
+func TestCannedReplies(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		inbox, _ := newRepository(t, db)
+
+		for _, reply := range []CannedReply{
+			{Shortcut: "thanks", Title: "Thanks", Body: "Thank you!", CreatedBy: 1},
+			{Shortcut: "hello", Title: "Hello", Body: "Hi, how can we help?", CreatedBy: 2},
+		} {
+			if err := inbox.CreateCannedReply(&reply); err != nil {
+				t.Fatalf("create %s: %v", reply.Shortcut, err)
+			}
+		}
+
+		replies, err := inbox.GetCannedReplies()
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(replies) != 2 || replies[0].Shortcut != "hello" || replies[1].Shortcut != "thanks" {
+			t.Fatalf("replies: got %+v", replies)
+		}
+
+		hello := replies[0]
+		hello.Body = "Hello!"
+		if err := inbox.UpdateCannedReply(&hello); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		updated, err := inbox.GetCannedReplyById(int(hello.Id))
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if updated.Body != "Hello!" || updated.UpdatedAt == nil {
+			t.Errorf("update was not stored: %+v", updated)
+		}
+
+		if err := inbox.DeleteCannedReply(&hello); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := inbox.GetCannedReplyById(int(hello.Id)); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("deleted reply: got %v, want %v", err, sql.ErrNoRows)
+		}
+		if replies, _ := inbox.GetCannedReplies(); len(replies) != 1 {
+			t.Errorf("got %d replies after deleting one, want 1", len(replies))
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package lockout
+
+import (
+	"embed"
+	"time"
+)
+
+// Attempt counts failed logins for a key, either "user:<username>" or
+// "ip:<address>". Failures older than the configured window are forgotten.
+type Attempt struct {
+	Key           string     `json:"key"             db:"key"`
+	Failures      int        `json:"failures"        db:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"    db:"locked_until"`
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
+
+func UserKey(username string) string {
+	return "user:" + username
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (a *Attempt) Locked() bool {
+	return a.LockedUntil != nil && time.Now().Before(*a.LockedUntil)
+}
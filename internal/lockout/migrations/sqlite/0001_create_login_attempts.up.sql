@@ -0,0 +1,6 @@
+CREATE TABLE login_attempts (
+	key TEXT PRIMARY KEY,
+	failures INTEGER NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	locked_until TIMESTAMP
+);
@@ -0,0 +1,108 @@
+package lockout
+
+import (
+	"database/sql"
+	"io/fs"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+type Repository struct {
+	db     *database.Database
+	config *config.Config
+}
+
+func NewRepository(db *database.Database, config *config.Config) *Repository {
+	return &Repository{db, config}
+}
+
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "lockout", migrations
+}
+
+// LockedUntil returns when the latest lock on any of the keys ends, or nil
+// when none of them is locked
+func (r *Repository) LockedUntil(keys ...string) (*time.Time, error) {
+	var until *time.Time
+	for _, key := range keys {
+		var attempt Attempt
+		err := r.db.Get(&attempt, "SELECT * FROM login_attempts WHERE key = $1", key)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if attempt.Locked() && (until == nil || attempt.LockedUntil.After(*until)) {
+			until = attempt.LockedUntil
+		}
+	}
+	return until, nil
+}
+
+// Fail records a failed login for key and locks it once max failures are
+// reached within the failure window. It reports whether this failure locked it.
+func (r *Repository) Fail(key string, max int) (*Attempt, bool, error) {
+	var attempt Attempt
+	err := r.db.Get(
+		&attempt,
+		`INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < $2 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = CURRENT_TIMESTAMP
+		RETURNING *`,
+		key,
+		time.Now().Add(-r.config.Login.FailureWindow),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if attempt.Failures < max || attempt.Locked() {
+		return &attempt, false, nil
+	}
+
+	until := time.Now().Add(r.config.Login.LockoutDuration)
+	attempt.LockedUntil = &until
+	_, err = r.db.Exec(
+		"UPDATE login_attempts SET locked_until = $1 WHERE key = $2",
+		until,
+		key,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &attempt, true, nil
+}
+
+// Reset forgets the failures and lock of key, after a successful login or
+// when an admin unlocks an account
+func (r *Repository) Reset(key string) error {
+	_, err := r.db.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
+
+// Delay is how long to hold the response to a failed login back, it doubles
+// with every failure up to the configured maximum
+func (r *Repository) Delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	delay := r.config.Login.DelayStep
+	for i := 1; i < failures && delay < r.config.Login.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.config.Login.MaxDelay {
+		return r.config.Login.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,98 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestFail(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		conf := config.Default()
+		conf.Login.FailureWindow = time.Minute
+		conf.Login.LockoutDuration = time.Hour
+		attempts := NewRepository(db, conf)
+		databasetest.Migrate(t, db, attempts)
+
+		key := UserKey("alice")
+		steps := []struct {
+			name     string
+			backdate time.Duration
+			failures int
+			locks    bool
+		}{
+			{"first failure", 0, 1, false},
+			{"second failure", 0, 2, false},
+			{"after the window", 2 * time.Minute, 1, false},
+			{"within the window", 30 * time.Second, 2, false},
+			{"reaching the limit", 0, 3, true},
+			{"while locked", 0, 4, false},
+		}
+		for _, tt := range steps {
+			// pretend the previous failure happened a while ago
+			if tt.backdate > 0 {
+				_, err := db.Exec(
+					"UPDATE login_attempts SET last_failure_at = $1 WHERE key = $2",
+					time.Now().Add(-tt.backdate),
+					key,
+				)
+				if err != nil {
+					t.Fatalf("%s: backdate: %v", tt.name, err)
+				}
+			}
+
+			attempt, locked, err := attempts.Fail(key, 3)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if attempt.Failures != tt.failures || locked != tt.locks {
+				t.Errorf("%s: got %d failures, locked %v, want %d, %v", tt.name, attempt.Failures, locked, tt.failures, tt.locks)
+			}
+		}
+
+		until, err := attempts.LockedUntil(IPKey("127.0.0.1"), key)
+		if err != nil {
+			t.Fatalf("locked until: %v", err)
+		}
+		if until == nil || until.Before(time.Now().Add(59*time.Minute)) {
+			t.Errorf("locked until: got %v, want about an hour from now", until)
+		}
+
+		if err := attempts.Reset(key); err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		if until, err := attempts.LockedUntil(key); err != nil || until != nil {
+			t.Errorf("locked until after a reset: got %v, %v", until, err)
+		}
+		if attempt, _, _ := attempts.Fail(key, 3); attempt.Failures != 1 {
+			t.Errorf("failure after a reset: got %d failures, want 1", attempt.Failures)
+		}
+	})
+}
+
+func TestDelay(t *testing.T) {
+	conf := config.Default()
+	conf.Login.DelayStep = time.Second
+	conf.Login.MaxDelay = 5 * time.Second
+	attempts := NewRepository(nil, conf)
+
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{20, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := attempts.Delay(tt.failures); got != tt.delay {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.delay)
+		}
+	}
+}
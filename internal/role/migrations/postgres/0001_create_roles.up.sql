@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS roles (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	actions TEXT NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
@@ -0,0 +1 @@
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS require_2fa BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,140 @@
+package role
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Permissions are stored in the role Actions column as a JSON object of
+// resource:action strings, e.g. {"user:create": true, "inbox:*": true}.
+// "*" grants everything and "resource:*" grants every action on a resource.
+type Permissions map[string]bool
+
+const PermissionAll = "*"
+
+const (
+	PermissionUserCreate = "user:create"
+	PermissionUserRead   = "user:read"
+	PermissionUserUpdate = "user:update"
+	PermissionUserDelete = "user:delete"
+
+	PermissionRoleCreate = "role:create"
+	PermissionRoleRead   = "role:read"
+	PermissionRoleUpdate = "role:update"
+	PermissionRoleDelete = "role:delete"
+
+	PermissionSessionCreate = "session:create"
+	PermissionSessionRead   = "session:read"
+	PermissionSessionUpdate = "session:update"
+	PermissionSessionDelete = "session:delete"
+	PermissionSessionSend   = "session:send"
+	// PermissionSessionAll lifts the ownership scope, without it a user only
+	// sees the sessions assigned to them
+	PermissionSessionAll = "session:all"
+
+	PermissionInboxRead   = "inbox:read"
+	PermissionInboxReply  = "inbox:reply"
+	PermissionInboxManage = "inbox:manage"
+
+	PermissionAuditRead = "audit:read"
+
+	PermissionLogRead = "log:read"
+
+	PermissionFlagCreate = "flag:create"
+	PermissionFlagRead   = "flag:read"
+	PermissionFlagUpdate = "flag:update"
+	PermissionFlagDelete = "flag:delete"
+)
+
+var AllPermissions = []string{
+	PermissionUserCreate,
+	PermissionUserRead,
+	PermissionUserUpdate,
+	PermissionUserDelete,
+	PermissionRoleCreate,
+	PermissionRoleRead,
+	PermissionRoleUpdate,
+	PermissionRoleDelete,
+	PermissionSessionCreate,
+	PermissionSessionRead,
+	PermissionSessionUpdate,
+	PermissionSessionDelete,
+	PermissionSessionSend,
+	PermissionSessionAll,
+	PermissionInboxRead,
+	PermissionInboxReply,
+	PermissionInboxManage,
+	PermissionAuditRead,
+	PermissionLogRead,
+	PermissionFlagCreate,
+	PermissionFlagRead,
+	PermissionFlagUpdate,
+	PermissionFlagDelete,
+}
+
+var ErrInvalidPermission = errors.New("unknown permission")
+
+func ParsePermissions(actions string) (Permissions, error) {
+	permissions := Permissions{}
+	if actions == "" {
+		return permissions, nil
+	}
+
+	if err := json.Unmarshal([]byte(actions), &permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// ValidatePermissions makes sure a role only grants permissions that exist,
+// so a typo does not silently leave a role without access
+func ValidatePermissions(actions string) error {
+	permissions, err := ParsePermissions(actions)
+	if err != nil {
+		return err
+	}
+
+	for permission := range permissions {
+		if permission == PermissionAll {
+			continue
+		}
+		if resource, found := strings.CutSuffix(permission, ":*"); found {
+			if !knownResource(resource) {
+				return ErrInvalidPermission
+			}
+			continue
+		}
+		if !knownPermission(permission) {
+			return ErrInvalidPermission
+		}
+	}
+	return nil
+}
+
+func (p Permissions) Can(permission string) bool {
+	if p[PermissionAll] || p[permission] {
+		return true
+	}
+
+	resource, _, found := strings.Cut(permission, ":")
+	return found && p[resource+":*"]
+}
+
+func knownPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func knownResource(resource string) bool {
+	for _, p := range AllPermissions {
+		if strings.HasPrefix(p, resource+":") {
+			return true
+		}
+	}
+	return false
+}
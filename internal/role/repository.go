@@ -3,26 +3,32 @@ package role
 import (
 	"database/sql"
 	"errors"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/patrickmn/go-cache"
 
 	"github.com/nugrhrizki/buzz/pkg/database"
 )
 
 type Repository struct {
-	db *database.Database
+	db          *database.Database
+	permissions *cache.Cache
 }
 
 func NewRepository(db *database.Database) *Repository {
-	return &Repository{db}
+	return &Repository{db, cache.New(time.Minute, 5*time.Minute)}
 }
 
-func (r *Repository) Migration() string {
-	return New()
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "role", migrations
 }
 
 func (r *Repository) createRole() error {
 	newUser := Role{
 		Name:    "Super Admin",
-		Actions: "{}",
+		Actions: `{"*":true}`,
 	}
 
 	if err := r.CreateRole(&newUser); err != nil {
@@ -44,7 +50,12 @@ func (r *Repository) Seed() error {
 		return r.createRole()
 	}
 
-	return nil
+	// Super Admin used to be seeded without any actions, grant it everything
+	// now that actions are enforced so existing installs are not locked out
+	_, err := r.db.Exec(
+		`UPDATE roles SET actions = '{"*":true}' WHERE name = 'Super Admin' AND actions = '{}'`,
+	)
+	return err
 }
 
 func (r *Repository) CreateRole(role *Role) error {
@@ -60,9 +71,10 @@ func (r *Repository) CreateRole(role *Role) error {
 	}
 
 	_, err = r.db.Exec(
-		"INSERT INTO roles (name, actions) VALUES ($1, $2)",
+		"INSERT INTO roles (name, actions, require_2fa) VALUES ($1, $2, $3)",
 		role.Name,
 		role.Actions,
+		role.Require2fa,
 	)
 	if err != nil {
 		return err
@@ -110,12 +122,14 @@ func (r *Repository) GetRoles() ([]Role, error) {
 }
 
 func (r *Repository) UpdateRole(role *Role) error {
-	_, err := r.GetRoleByRolename(role.Name)
+	existing, err := r.GetRoleByRolename(role.Name)
 	switch err {
 	case sql.ErrNoRows:
 		break
 	case nil:
-		return errors.New("role name already used")
+		if existing.Id != role.Id {
+			return errors.New("role name already used")
+		}
 	default:
 		return err
 	}
@@ -125,16 +139,20 @@ func (r *Repository) UpdateRole(role *Role) error {
 		SET
 			name = $1,
 			actions = $2,
+			require_2fa = $3,
 			updated_at = CURRENT_TIMESTAMP
 		WHERE
-			id = $3`,
+			id = $4`,
 		role.Name,
 		role.Actions,
+		role.Require2fa,
 		role.Id,
 	)
 	if err != nil {
 		return err
 	}
+
+	r.permissions.Delete(strconv.FormatInt(role.Id, 10))
 	return nil
 }
 
@@ -146,6 +164,8 @@ func (r *Repository) DeleteRole(role *Role) error {
 	if err != nil {
 		return err
 	}
+
+	r.permissions.Delete(strconv.FormatInt(role.Id, 10))
 	return nil
 }
 
@@ -159,3 +179,25 @@ func (r *Repository) HardDeleteRole(role *Role) error {
 	}
 	return nil
 }
+
+// GetPermissions resolves a role to its permissions. Results are cached for a
+// short while as this runs on every authenticated request.
+func (r *Repository) GetPermissions(id int) (Permissions, error) {
+	key := strconv.Itoa(id)
+	if permissions, found := r.permissions.Get(key); found {
+		return permissions.(Permissions), nil
+	}
+
+	role, err := r.GetRoleById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := ParsePermissions(role.Actions)
+	if err != nil {
+		return nil, err
+	}
+
+	r.permissions.SetDefault(key, permissions)
+	return permissions, nil
+}
@@ -0,0 +1,87 @@
+package role
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestRepository(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		roles := NewRepository(db)
+		databasetest.Migrate(t, db, roles)
+
+		if err := roles.Seed(); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+		if err := roles.CreateRole(&Role{Name: "Agent", Actions: `{"inbox:*":true}`}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if err := roles.CreateRole(&Role{Name: "Agent", Actions: "{}"}); err == nil {
+			t.Error("creating a role with a used name should fail")
+		}
+
+		agent, err := roles.GetRoleByRolename("Agent")
+		if err != nil {
+			t.Fatalf("get by name: %v", err)
+		}
+
+		agent.Require2fa = true
+		agent.Actions = `{"inbox:read":true}`
+		if err := roles.UpdateRole(agent); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		updated, err := roles.GetRoleById(int(agent.Id))
+		if err != nil {
+			t.Fatalf("get by id: %v", err)
+		}
+		if !updated.Require2fa || updated.Actions != agent.Actions || updated.UpdatedAt == nil {
+			t.Errorf("update was not stored: %+v", updated)
+		}
+
+		taken := *agent
+		taken.Name = "Super Admin"
+		if err := roles.UpdateRole(&taken); err == nil {
+			t.Error("renaming a role to a used name should fail")
+		}
+
+		permissions := []struct {
+			role       int64
+			permission string
+			can        bool
+		}{
+			{1, PermissionFlagDelete, true},
+			{agent.Id, PermissionInboxRead, true},
+			{agent.Id, PermissionInboxReply, false},
+		}
+		for _, tt := range permissions {
+			granted, err := roles.GetPermissions(int(tt.role))
+			if err != nil {
+				t.Fatalf("permissions of %d: %v", tt.role, err)
+			}
+			if granted.Can(tt.permission) != tt.can {
+				t.Errorf("role %d can %s = %v, want %v", tt.role, tt.permission, !tt.can, tt.can)
+			}
+		}
+
+		if err := roles.DeleteRole(agent); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := roles.GetRoleById(int(agent.Id)); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("deleted role: got %v, want %v", err, sql.ErrNoRows)
+		}
+		if _, err := roles.GetPermissions(int(agent.Id)); err == nil {
+			t.Error("a deleted role should have no permissions")
+		}
+		all, err := roles.GetRoles()
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(all) != 1 || all[0].Name != "Super Admin" {
+			t.Errorf("roles left: %+v", all)
+		}
+	})
+}
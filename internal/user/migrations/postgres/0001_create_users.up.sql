@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	username TEXT NOT NULL,
+	password TEXT NOT NULL,
+	confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+	whatsapp TEXT,
+	email TEXT,
+	role_id INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_uindex ON users (username);
+
+-- Databases created before versioned migrations may already have the key
+DO $$ BEGIN
+	ALTER TABLE users ADD CONSTRAINT users_role_id_fk FOREIGN KEY (role_id) REFERENCES roles (id);
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
@@ -0,0 +1,15 @@
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	username TEXT NOT NULL,
+	password TEXT NOT NULL,
+	confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+	whatsapp TEXT,
+	email TEXT,
+	role_id INTEGER NOT NULL REFERENCES roles (id),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP,
+	deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX users_username_uindex ON users (username);
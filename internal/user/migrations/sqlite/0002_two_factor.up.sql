@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE user_recovery_codes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	hash TEXT NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_recovery_codes_user_id_index ON user_recovery_codes (user_id);
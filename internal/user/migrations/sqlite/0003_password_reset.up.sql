@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE password_resets (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id BIGINT NOT NULL REFERENCES users (id),
+	hash TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX password_resets_hash_uindex ON password_resets (hash);
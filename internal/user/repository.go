@@ -3,6 +3,7 @@ package user
 import (
 	"database/sql"
 	"errors"
+	"io/fs"
 
 	"github.com/nugrhrizki/buzz/pkg/database"
 	"github.com/nugrhrizki/buzz/pkg/password"
@@ -17,21 +18,24 @@ func NewRepository(db *database.Database, password *password.Password) *Reposito
 	return &Repository{db, password}
 }
 
-func (r *Repository) Migration() string {
-	return New()
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "user", migrations
 }
 
+const seedPassword = "rahasia"
+
 func (r *Repository) createUser() error {
-	hashedPassword, err := r.password.GenerateHashPassword("rahasia")
+	hashedPassword, err := r.password.GenerateHashPassword(seedPassword)
 	if err != nil {
 		return err
 	}
 
 	newUser := User{
-		Name:     "Super Admin",
-		Username: "sadmin",
-		Password: hashedPassword,
-		RoleId:   1,
+		Name:       "Super Admin",
+		Username:   "sadmin",
+		Password:   hashedPassword,
+		RoleId:     1,
+		MustChange: true,
 	}
 
 	if err := r.CreateUser(&newUser); err != nil {
@@ -41,6 +45,23 @@ func (r *Repository) createUser() error {
 	return nil
 }
 
+// flagSeededPassword makes installs that still run the seeded admin with its
+// well known password change it on the next login
+func (r *Repository) flagSeededPassword() error {
+	admin, err := r.GetUserByUsername("sadmin")
+	if err != nil || admin.MustChange {
+		return nil
+	}
+
+	match, err := r.password.CompareHashPassword(seedPassword, admin.Password)
+	if err != nil || !match {
+		return err
+	}
+
+	_, err = r.db.Exec("UPDATE users SET must_change_password = TRUE WHERE id = $1", admin.Id)
+	return err
+}
+
 func (r *Repository) Seed() error {
 	users := []User{}
 	if err := r.db.DB.Select(&users, "SELECT * FROM users ORDER BY id ASC LIMIT 1"); err != nil {
@@ -53,7 +74,7 @@ func (r *Repository) Seed() error {
 		return r.createUser()
 	}
 
-	return nil
+	return r.flagSeededPassword()
 }
 
 func (r *Repository) CreateUser(user *User) error {
@@ -69,11 +90,12 @@ func (r *Repository) CreateUser(user *User) error {
 	}
 
 	_, err = r.db.Exec(
-		"INSERT INTO users (name, username, password, role_id) VALUES ($1, $2, $3, $4)",
+		"INSERT INTO users (name, username, password, role_id, must_change_password) VALUES ($1, $2, $3, $4, $5)",
 		user.Name,
 		user.Username,
 		user.Password,
 		user.RoleId,
+		user.MustChange,
 	)
 	if err != nil {
 		return err
@@ -121,12 +143,14 @@ func (r *Repository) GetUsers() ([]User, error) {
 }
 
 func (r *Repository) UpdateUser(user *User) error {
-	_, err := r.GetUserByUsername(user.Username)
+	existing, err := r.GetUserByUsername(user.Username)
 	switch err {
 	case sql.ErrNoRows:
 		break
 	case nil:
-		return errors.New("username already used")
+		if existing.Id != user.Id {
+			return errors.New("username already used")
+		}
 	default:
 		return err
 	}
@@ -140,7 +164,7 @@ func (r *Repository) UpdateUser(user *User) error {
 			confirmed = $4,
 			whatsapp = $5,
 			email = $6,
-			role_id = $7
+			role_id = $7,
 			updated_at = CURRENT_TIMESTAMP
 		WHERE
 			id = $8`,
@@ -180,3 +204,153 @@ func (r *Repository) HardDeleteUser(user *User) error {
 	}
 	return nil
 }
+
+// SetTotpSecret stores a secret waiting to be confirmed, two-factor stays off
+// until EnableTotp is called
+func (r *Repository) SetTotpSecret(id int64, secret string) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET totp_secret = $1, totp_enabled = FALSE, totp_last_step = 0 WHERE id = $2",
+		secret,
+		id,
+	)
+	return err
+}
+
+func (r *Repository) EnableTotp(id int64) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET totp_enabled = TRUE WHERE id = $1 AND totp_secret IS NOT NULL",
+		id,
+	)
+	return err
+}
+
+func (r *Repository) DisableTotp(id int64) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0 WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1", id)
+	return err
+}
+
+// UseTotpStep records the step of an accepted code. It reports false when
+// that step or a later one was already used, so a code cannot be replayed.
+func (r *Repository) UseTotpStep(id int64, step int64) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1",
+		step,
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) ReplaceRecoveryCodes(id int64, hashes []string) error {
+	tx, err := r.db.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1", id); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		_, err := tx.Exec(
+			"INSERT INTO user_recovery_codes (user_id, hash) VALUES ($1, $2)",
+			id,
+			hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) GetUnusedRecoveryCodes(id int64) ([]RecoveryCode, error) {
+	codes := []RecoveryCode{}
+	err := r.db.Select(
+		&codes,
+		"SELECT * FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode marks a code as used, it reports false when it already was
+func (r *Repository) UseRecoveryCode(id int64) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// SetPassword stores a new password hash. mustChange asks the user to pick
+// another one on their next login, for passwords somebody else chose.
+func (r *Repository) SetPassword(id int64, hash string, mustChange bool) error {
+	_, err := r.db.Exec(
+		`UPDATE users
+		SET password = $1, must_change_password = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`,
+		hash,
+		mustChange,
+		id,
+	)
+	return err
+}
+
+func (r *Repository) CreatePasswordReset(reset *PasswordReset) error {
+	return r.db.Get(
+		reset,
+		`INSERT INTO password_resets (user_id, hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING *`,
+		reset.UserId,
+		reset.Hash,
+		reset.ExpiresAt,
+	)
+}
+
+// UsePasswordReset redeems a reset token. It only succeeds once, and only
+// before the token expires.
+func (r *Repository) UsePasswordReset(hash string) (*PasswordReset, error) {
+	var reset PasswordReset
+	err := r.db.Get(
+		&reset,
+		`UPDATE password_resets
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING *`,
+		hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
@@ -0,0 +1,234 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nugrhrizki/buzz/internal/role"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+	"github.com/nugrhrizki/buzz/pkg/password"
+)
+
+// newRepository migrates the users and the roles they reference, with the
+// default role seeded
+func newRepository(t *testing.T, db *database.Database) *Repository {
+	roles := role.NewRepository(db)
+	users := NewRepository(db, password.NewPassword(config.Default(), databasetest.Logger()))
+	databasetest.Migrate(t, db, roles, users)
+	if err := roles.Seed(); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+	return users
+}
+
+func TestRepository(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		users := newRepository(t, db)
+
+		if err := users.Seed(); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+		admin, err := users.GetUserByUsername("sadmin")
+		if err != nil {
+			t.Fatalf("seeded admin: %v", err)
+		}
+		if !admin.MustChange {
+			t.Error("the seeded admin should have to change its password")
+		}
+
+		if err := users.CreateUser(&User{Name: "Agent", Username: "agent", Password: "hash", RoleId: 1}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if err := users.CreateUser(&User{Name: "Other", Username: "agent", Password: "hash", RoleId: 1}); err == nil {
+			t.Error("creating a user with a used username should fail")
+		}
+
+		agent, err := users.GetUserByUsername("agent")
+		if err != nil {
+			t.Fatalf("get by username: %v", err)
+		}
+
+		email := "agent@example.com"
+		agent.Name = "Agent Smith"
+		agent.Email = &email
+		agent.Confirmed = true
+		if err := users.UpdateUser(agent); err != nil {
+			t.Fatalf("update keeping the username: %v", err)
+		}
+		updated, err := users.GetUserById(int(agent.Id))
+		if err != nil {
+			t.Fatalf("get by id: %v", err)
+		}
+		if updated.Name != "Agent Smith" || updated.Email == nil || *updated.Email != email || !updated.Confirmed || updated.UpdatedAt == nil {
+			t.Errorf("update was not stored: %+v", updated)
+		}
+
+		taken := *agent
+		taken.Username = "sadmin"
+		if err := users.UpdateUser(&taken); err == nil {
+			t.Error("renaming a user to a used username should fail")
+		}
+
+		if err := users.SetPassword(agent.Id, "new-hash", true); err != nil {
+			t.Fatalf("set password: %v", err)
+		}
+		if updated, _ := users.GetUserById(int(agent.Id)); updated.Password != "new-hash" || !updated.MustChange {
+			t.Errorf("password was not stored: %+v", updated)
+		}
+
+		if err := users.DeleteUser(agent); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		lookups := map[string]func() (*User, error){
+			"by id":       func() (*User, error) { return users.GetUserById(int(agent.Id)) },
+			"by username": func() (*User, error) { return users.GetUserByUsername("agent") },
+		}
+		for name, lookup := range lookups {
+			if _, err := lookup(); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("deleted user %s: got %v, want %v", name, err, sql.ErrNoRows)
+			}
+		}
+		all, err := users.GetUsers()
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(all) != 1 || all[0].Username != "sadmin" {
+			t.Errorf("users left: %+v", all)
+		}
+	})
+}
+
+func TestTwoFactor(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		users := newRepository(t, db)
+		if err := users.CreateUser(&User{Name: "Agent", Username: "agent", Password: "hash", RoleId: 1}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		agent, _ := users.GetUserByUsername("agent")
+
+		if err := users.EnableTotp(agent.Id); err != nil {
+			t.Fatalf("enable: %v", err)
+		}
+		if agent, _ = users.GetUserById(int(agent.Id)); agent.TotpEnabled {
+			t.Error("two-factor should stay off without a secret")
+		}
+
+		if err := users.SetTotpSecret(agent.Id, "SECRET"); err != nil {
+			t.Fatalf("set secret: %v", err)
+		}
+		if err := users.EnableTotp(agent.Id); err != nil {
+			t.Fatalf("enable: %v", err)
+		}
+		if agent, _ = users.GetUserById(int(agent.Id)); !agent.TotpEnabled || agent.TotpSecret == nil {
+			t.Errorf("two-factor was not enabled: %+v", agent)
+		}
+
+		steps := []struct {
+			step int64
+			used bool
+		}{
+			{100, true},
+			{100, false},
+			{99, false},
+			{101, true},
+		}
+		for _, tt := range steps {
+			used, err := users.UseTotpStep(agent.Id, tt.step)
+			if err != nil {
+				t.Fatalf("use step %d: %v", tt.step, err)
+			}
+			if used != tt.used {
+				t.Errorf("use step %d = %v, want %v", tt.step, used, tt.used)
+			}
+		}
+
+		if err := users.ReplaceRecoveryCodes(agent.Id, []string{"a", "b"}); err != nil {
+			t.Fatalf("first codes: %v", err)
+		}
+		if err := users.ReplaceRecoveryCodes(agent.Id, []string{"c", "d", "e"}); err != nil {
+			t.Fatalf("replace codes: %v", err)
+		}
+		codes, err := users.GetUnusedRecoveryCodes(agent.Id)
+		if err != nil {
+			t.Fatalf("codes: %v", err)
+		}
+		if len(codes) != 3 {
+			t.Fatalf("got %d codes, want 3", len(codes))
+		}
+		for i, want := range []bool{true, false} {
+			used, err := users.UseRecoveryCode(codes[0].Id)
+			if err != nil {
+				t.Fatalf("use code: %v", err)
+			}
+			if used != want {
+				t.Errorf("use code, attempt %d = %v, want %v", i+1, used, want)
+			}
+		}
+		if codes, _ := users.GetUnusedRecoveryCodes(agent.Id); len(codes) != 2 {
+			t.Errorf("got %d unused codes, want 2", len(codes))
+		}
+
+		if err := users.DisableTotp(agent.Id); err != nil {
+			t.Fatalf("disable: %v", err)
+		}
+		if agent, _ = users.GetUserById(int(agent.Id)); agent.TotpEnabled || agent.TotpSecret != nil {
+			t.Errorf("two-factor was not disabled: %+v", agent)
+		}
+		if codes, _ := users.GetUnusedRecoveryCodes(agent.Id); len(codes) != 0 {
+			t.Errorf("disabling should drop the recovery codes, %d left", len(codes))
+		}
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		users := newRepository(t, db)
+		if err := users.CreateUser(&User{Name: "Agent", Username: "agent", Password: "hash", RoleId: 1}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		agent, _ := users.GetUserByUsername("agent")
+
+		resets := []struct {
+			hash      string
+			expiresAt time.Time
+		}{
+			{"valid", time.Now().Add(time.Hour)},
+			{"expired", time.Now().Add(-time.Minute)},
+		}
+		for _, tt := range resets {
+			reset := &PasswordReset{UserId: agent.Id, Hash: tt.hash, ExpiresAt: tt.expiresAt}
+			if err := users.CreatePasswordReset(reset); err != nil {
+				t.Fatalf("create %s reset: %v", tt.hash, err)
+			}
+			if reset.Id == 0 || reset.CreatedAt.IsZero() {
+				t.Errorf("created %s reset was not returned: %+v", tt.hash, reset)
+			}
+		}
+
+		uses := []struct {
+			hash string
+			ok   bool
+		}{
+			{"valid", true},
+			{"valid", false},
+			{"expired", false},
+			{"unknown", false},
+		}
+		for _, tt := range uses {
+			reset, err := users.UsePasswordReset(tt.hash)
+			if tt.ok {
+				if err != nil || reset.UserId != agent.Id || reset.UsedAt == nil {
+					t.Errorf("use %s reset: got %+v, %v", tt.hash, reset, err)
+				}
+				continue
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("use %s reset: got %v, want %v", tt.hash, err, sql.ErrNoRows)
+			}
+		}
+	})
+}
@@ -1,36 +1,50 @@
 package user
 
-import "time"
+import (
+	"embed"
+	"time"
+)
 
 type User struct {
+	Id           int64      `json:"id"                   db:"id"`
+	Name         string     `json:"name"                 db:"name"`
+	Username     string     `json:"username"             db:"username"`
+	Password     string     `json:"password"             db:"password"`
+	Confirmed    bool       `json:"confirmed"            db:"confirmed"`
+	Whatsapp     *string    `json:"whatsapp"             db:"whatsapp"`
+	Email        *string    `json:"email"                db:"email"`
+	RoleId       int64      `json:"role_id"              db:"role_id"`
+	TotpSecret   *string    `json:"-"                    db:"totp_secret"`
+	TotpEnabled  bool       `json:"totp_enabled"         db:"totp_enabled"`
+	TotpLastStep int64      `json:"-"                    db:"totp_last_step"`
+	MustChange   bool       `json:"must_change_password" db:"must_change_password"`
+	CreatedAt    time.Time  `json:"created_at"           db:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"           db:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at"           db:"deleted_at"`
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
+
+// RecoveryCode is a single use code to log in without the authenticator app,
+// only its hash is kept
+type RecoveryCode struct {
 	Id        int64      `json:"id"         db:"id"`
-	Name      string     `json:"name"       db:"name"`
-	Username  string     `json:"username"   db:"username"`
-	Password  string     `json:"password"   db:"password"`
-	Confirmed bool       `json:"confirmed"  db:"confirmed"`
-	Whatsapp  *string    `json:"whatsapp"   db:"whatsapp"`
-	Email     *string    `json:"email"      db:"email"`
-	RoleId    int64      `json:"role_id"    db:"role_id"`
+	UserId    int64      `json:"user_id"    db:"user_id"`
+	Hash      string     `json:"-"          db:"hash"`
+	UsedAt    *time.Time `json:"used_at"    db:"used_at"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at" db:"deleted_at"`
 }
 
-func New() string {
-	return `CREATE TABLE IF NOT EXISTS users (
-		id BIGSERIAL PRIMARY KEY,
-		name TEXT NOT NULL,
-		username TEXT NOT NULL,
-		password TEXT NOT NULL,
-		confirmed BOOLEAN NOT NULL DEFAULT FALSE,
-		whatsapp TEXT,
-		email TEXT,
-		role_id INTEGER NOT NULL,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMPTZ,
-		deleted_at TIMESTAMPTZ
-	);
-	
-	CREATE UNIQUE INDEX IF NOT EXISTS users_username_uindex ON users (username);
-	ALTER TABLE users ADD CONSTRAINT users_role_id_fk FOREIGN KEY (role_id) REFERENCES roles (id);`
+// PasswordReset is a one time token an admin issued so a user can set a new
+// password, only its hash is kept
+type PasswordReset struct {
+	Id        int64      `json:"id"         db:"id"`
+	UserId    int64      `json:"user_id"    db:"user_id"`
+	Hash      string     `json:"-"          db:"hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"    db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 }
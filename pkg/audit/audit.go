@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"embed"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Actors are whoever made a change: a dashboard user, an api key, a session
+// token or nobody logged in at all
+const (
+	ActorUser      = "user"
+	ActorApiKey    = "apikey"
+	ActorSession   = "session"
+	ActorAnonymous = "anonymous"
+	ActorSystem    = "system"
+)
+
+type Actor struct {
+	Type string
+	Id   string
+	Name string
+}
+
+type Event struct {
+	Id         int64              `db:"id"          json:"id"`
+	ActorType  string             `db:"actor_type"  json:"actor_type"`
+	ActorId    string             `db:"actor_id"    json:"actor_id"`
+	ActorName  string             `db:"actor_name"  json:"actor_name"`
+	Action     string             `db:"action"      json:"action"`
+	TargetType string             `db:"target_type" json:"target_type"`
+	TargetId   string             `db:"target_id"   json:"target_id"`
+	Method     string             `db:"method"      json:"method"`
+	Path       string             `db:"path"        json:"path"`
+	Status     int                `db:"status"      json:"status"`
+	Ip         string             `db:"ip"          json:"ip"`
+	UserAgent  string             `db:"user_agent"  json:"user_agent"`
+	Before     types.NullJSONText `db:"before"      json:"before"`
+	After      types.NullJSONText `db:"after"       json:"after"`
+	CreatedAt  time.Time          `db:"created_at"  json:"created_at"`
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
+
+// secretFields never end up in an audit event, whatever struct they are on
+var secretFields = map[string]bool{
+	"password":    true,
+	"token":       true,
+	"hash":        true,
+	"secret":      true,
+	"totp_secret": true,
+	"qrcode":      true,
+}
+
+// snapshot flattens a value to its JSON fields without the secret ones
+func snapshot(v any) map[string]any {
+	if v == nil {
+		return nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	fields := map[string]any{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		// Not an object, keep the plain value
+		var value any
+		if json.Unmarshal(raw, &value) != nil {
+			return nil
+		}
+		return map[string]any{"value": value}
+	}
+
+	for field := range fields {
+		if secretFields[field] {
+			delete(fields, field)
+		}
+	}
+	return fields
+}
+
+func jsonText(fields map[string]any) types.NullJSONText {
+	if fields == nil {
+		return types.NullJSONText{}
+	}
+
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return types.NullJSONText{}
+	}
+	return types.NullJSONText{JSONText: raw, Valid: true}
+}
+
+// Diff keeps the fields that differ between two states of a target. A nil
+// before is a creation and a nil after a deletion, both keep every field.
+func Diff(before, after any) (types.NullJSONText, types.NullJSONText) {
+	return diff(snapshot(before), snapshot(after))
+}
+
+func diff(was, now map[string]any) (types.NullJSONText, types.NullJSONText) {
+	if was != nil && now != nil {
+		for field, value := range was {
+			if reflect.DeepEqual(value, now[field]) {
+				delete(was, field)
+				delete(now, field)
+			}
+		}
+	}
+	return jsonText(was), jsonText(now)
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+const (
+	localsKey = "audit"
+	skipKey   = "audit.skip"
+)
+
+// change is what a handler tells the middleware it did
+type change struct {
+	action     string
+	targetType string
+	targetId   string
+	before     map[string]any
+	after      map[string]any
+}
+
+func changesOf(c *fiber.Ctx) []change {
+	changes, _ := c.Locals(localsKey).([]change)
+	return changes
+}
+
+// Describe names what a request did to which target. The middleware records
+// it once the handler returns, a request can describe several changes.
+func Describe(c *fiber.Ctx, action string, targetType string, targetId string) {
+	Change(c, action, targetType, targetId, nil, nil)
+}
+
+// Change is Describe with the state of the target before and after the
+// request, only the fields that changed are kept. The states are copied
+// right away so handlers may keep modifying them.
+func Change(c *fiber.Ctx, action string, targetType string, targetId string, before any, after any) {
+	c.Locals(localsKey, append(changesOf(c), change{
+		action:     action,
+		targetType: targetType,
+		targetId:   targetId,
+		before:     snapshot(before),
+		after:      snapshot(after),
+	}))
+}
+
+// Skip is a route handler that keeps a request out of the audit log, for
+// routes that only relay traffic, like sending a message
+func Skip(c *fiber.Ctx) error {
+	c.Locals(skipKey, true)
+	return c.Next()
+}
+
+// mutating methods are recorded even when the handler did not describe them
+var mutating = map[string]bool{
+	fiber.MethodPost:   true,
+	fiber.MethodPut:    true,
+	fiber.MethodPatch:  true,
+	fiber.MethodDelete: true,
+}
+
+// Middleware records an audit event for every request that changed
+// something. actor tells who made the request once the handler ran, it
+// returns false for requests that are only recorded when a handler
+// describes them.
+func (r *Repository) Middleware(log *zerolog.Logger, actor func(c *fiber.Ctx) (Actor, bool)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if skip, _ := c.Locals(skipKey).(bool); skip {
+			return err
+		}
+
+		changes := changesOf(c)
+		who, generic := actor(c)
+		if len(changes) == 0 {
+			if !generic || !mutating[c.Method()] {
+				return err
+			}
+			changes = []change{{action: c.Method() + " " + c.Route().Path}}
+		}
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		for _, change := range changes {
+			event := &Event{
+				ActorType:  who.Type,
+				ActorId:    who.Id,
+				ActorName:  who.Name,
+				Action:     change.action,
+				TargetType: change.targetType,
+				TargetId:   change.targetId,
+				Method:     c.Method(),
+				Path:       c.Path(),
+				Status:     status,
+				Ip:         c.IP(),
+				UserAgent:  c.Get(fiber.HeaderUserAgent),
+			}
+			event.Before, event.After = diff(change.before, change.after)
+
+			if recordErr := r.Record(event); recordErr != nil {
+				log.Error().Err(recordErr).Str("action", change.action).Msg("Failed to record audit event")
+			}
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		audits := NewRepository(db)
+		databasetest.Migrate(t, db, audits)
+
+		key := Actor{Type: ActorApiKey, Id: "3", Name: "bz_abc"}
+		user := Actor{Type: ActorUser, Id: "1", Name: "admin"}
+		app := fiber.New()
+		app.Use(audits.Middleware(databasetest.Logger(), func(c *fiber.Ctx) (Actor, bool) {
+			if c.Get("token") != "" {
+				return key, false
+			}
+			return user, true
+		}))
+		describe := func(c *fiber.Ctx) error {
+			Describe(c, "session.logout", "session", "5")
+			return nil
+		}
+		app.Post("/logout", describe)
+		app.Post("/send", Skip, describe)
+		app.Post("/other", func(c *fiber.Ctx) error { return nil })
+
+		tests := []struct {
+			name   string
+			path   string
+			token  string
+			action string
+			actor  Actor
+		}{
+			{"api key change", "/logout", "key", "session.logout", key},
+			{"dashboard change", "/logout", "", "session.logout", user},
+			{"api key without a change", "/other", "key", "", Actor{}},
+			{"dashboard without a change", "/other", "", "POST /other", user},
+			{"skipped for api keys", "/send", "key", "", Actor{}},
+			{"skipped for the dashboard", "/send", "", "", Actor{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := db.Exec("DELETE FROM audit_events"); err != nil {
+					t.Fatal(err)
+				}
+
+				req := httptest.NewRequest(fiber.MethodPost, tt.path, nil)
+				req.Header.Set(fiber.HeaderUserAgent, "test")
+				if tt.token != "" {
+					req.Header.Set("token", tt.token)
+				}
+				if _, err := app.Test(req); err != nil {
+					t.Fatal(err)
+				}
+
+				events, _, err := audits.GetEvents(Filter{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.action == "" {
+					if len(events) != 0 {
+						t.Errorf("got %d events, want none", len(events))
+					}
+					return
+				}
+				if len(events) != 1 {
+					t.Fatalf("got %d events, want 1", len(events))
+				}
+
+				event := events[0]
+				if event.Action != tt.action || event.ActorType != tt.actor.Type || event.ActorId != tt.actor.Id || event.ActorName != tt.actor.Name {
+					t.Errorf("got %s by %s %s %s, want %s by %+v", event.Action, event.ActorType, event.ActorId, event.ActorName, tt.action, tt.actor)
+				}
+				if event.Method != fiber.MethodPost || event.Path != tt.path || event.UserAgent != "test" || event.Ip == "" {
+					t.Errorf("request was not recorded: %+v", event)
+				}
+			})
+		}
+	})
+}
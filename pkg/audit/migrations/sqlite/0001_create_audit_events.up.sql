@@ -0,0 +1,21 @@
+CREATE TABLE audit_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor_type TEXT NOT NULL,
+	actor_id TEXT NOT NULL DEFAULT '',
+	actor_name TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL,
+	target_type TEXT NOT NULL DEFAULT '',
+	target_id TEXT NOT NULL DEFAULT '',
+	method TEXT NOT NULL DEFAULT '',
+	path TEXT NOT NULL DEFAULT '',
+	status INT NOT NULL DEFAULT 0,
+	ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	before TEXT,
+	after TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX audit_events_created_at_index ON audit_events (created_at);
+CREATE INDEX audit_events_actor_index ON audit_events (actor_type, actor_id);
+CREATE INDEX audit_events_target_index ON audit_events (target_type, target_id);
+CREATE INDEX audit_events_action_index ON audit_events (action);
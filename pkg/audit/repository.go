@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+type Repository struct {
+	db *database.Database
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "audit", migrations
+}
+
+func (r *Repository) Record(event *Event) error {
+	return r.db.Get(
+		event,
+		`INSERT INTO audit_events
+			(actor_type, actor_id, actor_name, action, target_type, target_id,
+			method, path, status, ip, user_agent, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING *`,
+		event.ActorType,
+		event.ActorId,
+		event.ActorName,
+		event.Action,
+		event.TargetType,
+		event.TargetId,
+		event.Method,
+		event.Path,
+		event.Status,
+		event.Ip,
+		event.UserAgent,
+		event.Before,
+		event.After,
+	)
+}
+
+// likeEscaper makes the wildcards in a LIKE pattern match themselves
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Filter narrows the audit events down, empty fields match everything.
+// Action matches a prefix so "user." finds every change to users.
+type Filter struct {
+	ActorType  string
+	ActorId    string
+	Action     string
+	TargetType string
+	TargetId   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+func (f *Filter) where() (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if f.ActorType != "" {
+		add("actor_type = ?", f.ActorType)
+	}
+	if f.ActorId != "" {
+		add("actor_id = ?", f.ActorId)
+	}
+	if f.Action != "" {
+		add(`action LIKE ? || '%' ESCAPE '\'`, likeEscaper.Replace(f.Action))
+	}
+	if f.TargetType != "" {
+		add("target_type = ?", f.TargetType)
+	}
+	if f.TargetId != "" {
+		add("target_id = ?", f.TargetId)
+	}
+	if f.From != nil {
+		add("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < ?", *f.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetEvents returns the matching events, newest first, and how many match
+// in total
+func (r *Repository) GetEvents(filter Filter) ([]Event, int, error) {
+	where, args := filter.where()
+
+	var total int
+	if err := r.db.Get(&total, "SELECT COUNT(*) FROM audit_events"+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT * FROM audit_events" + where + " ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += " OFFSET $" + strconv.Itoa(len(args))
+	}
+
+	events := []Event{}
+	if err := r.db.Select(&events, query, args...); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
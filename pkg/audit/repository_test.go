@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestGetEvents(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		audits := NewRepository(db)
+		databasetest.Migrate(t, db, audits)
+
+		now := time.Now()
+		recorded := []struct {
+			event Event
+			age   time.Duration
+		}{
+			{Event{ActorType: ActorUser, ActorId: "1", Action: "user.create", TargetType: "user", TargetId: "bob"}, 3 * time.Hour},
+			{Event{ActorType: ActorUser, ActorId: "1", Action: "user.update", TargetType: "user", TargetId: "bob"}, 2 * time.Hour},
+			{Event{ActorType: ActorUser, ActorId: "2", Action: "userXupdate", TargetType: "user", TargetId: "eve"}, time.Hour},
+			{Event{ActorType: ActorSession, ActorId: "5", Action: "apikey.create", TargetType: "apikey", TargetId: "9"}, 30 * time.Minute},
+			{Event{ActorType: ActorSystem, Action: "100%.done", TargetType: "job"}, 0},
+		}
+		for i := range recorded {
+			event := &recorded[i].event
+			event.Before, event.After = Diff(nil, map[string]any{"n": i})
+			if err := audits.Record(event); err != nil {
+				t.Fatalf("record %s: %v", event.Action, err)
+			}
+			if event.Id == 0 || event.CreatedAt.IsZero() {
+				t.Errorf("recorded %s event was not returned: %+v", event.Action, event)
+			}
+			_, err := db.Exec("UPDATE audit_events SET created_at = $1 WHERE id = $2", now.Add(-recorded[i].age), event.Id)
+			if err != nil {
+				t.Fatalf("backdate %s: %v", event.Action, err)
+			}
+		}
+
+		from, to := now.Add(-150*time.Minute), now.Add(-45*time.Minute)
+		tests := []struct {
+			name    string
+			filter  Filter
+			total   int
+			actions []string
+		}{
+			{"everything", Filter{}, 5, []string{"100%.done", "apikey.create", "userXupdate", "user.update", "user.create"}},
+			{"actor", Filter{ActorType: ActorUser, ActorId: "1"}, 2, []string{"user.update", "user.create"}},
+			{"action prefix", Filter{Action: "user."}, 2, []string{"user.update", "user.create"}},
+			{"underscore is literal", Filter{Action: "user_"}, 0, nil},
+			{"percent is literal", Filter{Action: "%"}, 0, nil},
+			{"action with a percent", Filter{Action: "100%"}, 1, []string{"100%.done"}},
+			{"backslash is literal", Filter{Action: `user\`}, 0, nil},
+			{"target", Filter{TargetType: "user", TargetId: "bob"}, 2, []string{"user.update", "user.create"}},
+			{"time range", Filter{From: &from, To: &to}, 2, []string{"userXupdate", "user.update"}},
+			{"page", Filter{Limit: 2, Offset: 1}, 5, []string{"apikey.create", "userXupdate"}},
+			{"filtered page", Filter{ActorType: ActorUser, Limit: 1, Offset: 1}, 3, []string{"user.update"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				events, total, err := audits.GetEvents(tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if total != tt.total {
+					t.Errorf("total = %d, want %d", total, tt.total)
+				}
+				var actions []string
+				for _, event := range events {
+					actions = append(actions, event.Action)
+				}
+				if len(actions) != len(tt.actions) {
+					t.Fatalf("got %v, want %v", actions, tt.actions)
+				}
+				for i := range actions {
+					if actions[i] != tt.actions[i] {
+						t.Fatalf("got %v, want %v", actions, tt.actions)
+					}
+				}
+			})
+		}
+
+		events, _, err := audits.GetEvents(Filter{Action: "apikey.create"})
+		if err != nil || len(events) != 1 {
+			t.Fatalf("get apikey.create: got %d events, %v", len(events), err)
+		}
+		var after map[string]any
+		if err := json.Unmarshal(events[0].After.JSONText, &after); err != nil || after["n"] != float64(3) {
+			t.Errorf("after of apikey.create: got %s, %v", events[0].After.JSONText, err)
+		}
+		if events[0].Before.Valid {
+			t.Errorf("before of apikey.create: got %s, want null", events[0].Before.JSONText)
+		}
+	})
+}
@@ -1 +1,332 @@
 package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config holds every setting of Buzz. Values come from the defaults, then
+// the config file, then BUZZ_* environment variables and finally flags,
+// each overriding the one before.
+type Config struct {
+	Server   Server   `yaml:"server"   toml:"server"`
+	Database Database `yaml:"database" toml:"database"`
+	Password Password `yaml:"password" toml:"password"`
+	Auth     Auth     `yaml:"auth"     toml:"auth"`
+	Login    Login    `yaml:"login"    toml:"login"`
+	Whatsapp Whatsapp `yaml:"whatsapp" toml:"whatsapp"`
+	Metrics  Metrics  `yaml:"metrics"  toml:"metrics"`
+	Tracing  Tracing  `yaml:"tracing"  toml:"tracing"`
+	Health   Health   `yaml:"health"   toml:"health"`
+	Log      Log      `yaml:"log"      toml:"log"`
+}
+
+type Server struct {
+	Port     int    `yaml:"port"     toml:"port"`
+	Prefork  bool   `yaml:"prefork"  toml:"prefork"`
+	Timezone string `yaml:"timezone" toml:"timezone"`
+	Dev      bool   `yaml:"dev"      toml:"dev"`
+
+	// CorsOrigins may call the api from a browser with credentials, the
+	// vite dev server is allowed in development mode when none are set
+	CorsOrigins []string `yaml:"cors_origins" toml:"cors_origins"`
+}
+
+type Database struct {
+	// Driver is postgres or sqlite, the dsn defaults to a local database
+	// of the driver
+	Driver string `yaml:"driver" toml:"driver"`
+	DSN    string `yaml:"dsn"    toml:"dsn"`
+}
+
+// Password tunes the argon2 hashes and the password rules
+type Password struct {
+	Memory      uint32        `yaml:"memory"      toml:"memory"`
+	Iterations  uint32        `yaml:"iterations"  toml:"iterations"`
+	Parallelism uint8         `yaml:"parallelism" toml:"parallelism"`
+	KeyLength   uint32        `yaml:"key_length"  toml:"key_length"`
+	SaltLength  uint32        `yaml:"salt_length" toml:"salt_length"`
+	MinLength   int           `yaml:"min_length"  toml:"min_length"`
+	ResetTTL    time.Duration `yaml:"reset_ttl"   toml:"reset_ttl"`
+}
+
+type Auth struct {
+	Secret string `yaml:"secret" toml:"secret"`
+
+	// JwtKeys holds every key a token may be signed with, by kid, so keys
+	// can be rotated without logging everyone out. New tokens use JwtKeyId.
+	// Without keys the secret is used under the "default" kid.
+	JwtKeys         map[string]string `yaml:"jwt_keys"          toml:"jwt_keys"`
+	JwtKeyId        string            `yaml:"jwt_kid"           toml:"jwt_kid"`
+	AccessTokenTTL  time.Duration     `yaml:"access_token_ttl"  toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration     `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+
+	// The login cookies, secure should be on whenever Buzz is served over
+	// https
+	CookieSecure   bool   `yaml:"cookie_secure"   toml:"cookie_secure"`
+	CookieSameSite string `yaml:"cookie_samesite" toml:"cookie_samesite"`
+	CookieDomain   string `yaml:"cookie_domain"   toml:"cookie_domain"`
+}
+
+// Login limits failed logins. They are counted per username and per ip
+// within the window, reaching the maximum locks the key for the lockout
+// duration.
+type Login struct {
+	MaxFailures     int           `yaml:"max_failures"     toml:"max_failures"`
+	IPMaxFailures   int           `yaml:"ip_max_failures"  toml:"ip_max_failures"`
+	FailureWindow   time.Duration `yaml:"failure_window"   toml:"failure_window"`
+	LockoutDuration time.Duration `yaml:"lockout_duration" toml:"lockout_duration"`
+	DelayStep       time.Duration `yaml:"delay_step"       toml:"delay_step"`
+	MaxDelay        time.Duration `yaml:"max_delay"        toml:"max_delay"`
+}
+
+type Whatsapp struct {
+	// SystemSessionId is the WhatsApp session used to message dashboard
+	// users, e.g. password reset tokens. Zero turns those messages off.
+	SystemSessionId int `yaml:"system_session" toml:"system_session"`
+
+	// MediaPath is where received media and history syncs are stored,
+	// files next to the executable by default
+	MediaPath      string        `yaml:"media_path"      toml:"media_path"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" toml:"webhook_timeout"`
+}
+
+// Metrics exposes Prometheus metrics on /metrics, it is off unless enabled.
+// With a token set the scraper has to send it as a bearer token.
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled"`
+	Token   string `yaml:"token"   toml:"token"`
+}
+
+// Tracing exports OpenTelemetry traces of requests, sends, queries and
+// webhooks. The exporter is none, stdout or otlp, which sends them over http
+// to the endpoint, host:port of a collector.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter"     toml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"     toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"     toml:"insecure"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// Health configures /readyz. Each check gets the timeout to answer. With
+// sessions on, a session that should be connected but is not fails it.
+type Health struct {
+	Timeout  time.Duration `yaml:"timeout"  toml:"timeout"`
+	Sessions bool          `yaml:"sessions" toml:"sessions"`
+}
+
+// Log sets how much is logged and how. The format is console or json.
+// Redaction hides tokens, passwords and message bodies and masks phone
+// numbers. WhatsmeowLevel applies to the logs of the WhatsApp library.
+//
+// The latest BufferSize events are kept for the log api. With File set the
+// log is also written there as json, rotated once it reaches FileMaxSize
+// megabytes, keeping FileMaxBackups old files for at most FileMaxAge days.
+type Log struct {
+	Level          string `yaml:"level"           toml:"level"`
+	Format         string `yaml:"format"          toml:"format"`
+	Redact         bool   `yaml:"redact"          toml:"redact"`
+	WhatsmeowLevel string `yaml:"whatsmeow_level" toml:"whatsmeow_level"`
+
+	BufferSize     int    `yaml:"buffer_size"      toml:"buffer_size"`
+	File           string `yaml:"file"             toml:"file"`
+	FileMaxSize    int    `yaml:"file_max_size"    toml:"file_max_size"`
+	FileMaxBackups int    `yaml:"file_max_backups" toml:"file_max_backups"`
+	FileMaxAge     int    `yaml:"file_max_age"     toml:"file_max_age"`
+}
+
+// devSecret signs tokens in dev mode when no secret is set. Like the secret
+// of the example config, it is refused outside dev mode.
+const devSecret = "secret"
+
+// insecureSecrets are publicly known, tokens signed with them can be forged
+var insecureSecrets = map[string]bool{
+	devSecret:   true,
+	"change-me": true,
+}
+
+// Default returns the configuration used when nothing overrides it
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:     3000,
+			Timezone: "Asia/Jakarta",
+		},
+		Database: Database{
+			Driver: "postgres",
+		},
+		Password: Password{
+			Memory:      64 * 1024,
+			Iterations:  3,
+			Parallelism: 2,
+			KeyLength:   32,
+			SaltLength:  16,
+			MinLength:   8,
+			ResetTTL:    30 * time.Minute,
+		},
+		Auth: Auth{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+			CookieSameSite:  "lax",
+		},
+		Login: Login{
+			MaxFailures:     5,
+			IPMaxFailures:   20,
+			FailureWindow:   15 * time.Minute,
+			LockoutDuration: 15 * time.Minute,
+			DelayStep:       500 * time.Millisecond,
+			MaxDelay:        5 * time.Second,
+		},
+		Whatsapp: Whatsapp{
+			WebhookTimeout: 5 * time.Second,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			ServiceName: "buzz",
+			SampleRatio: 1,
+		},
+		Health: Health{
+			Timeout: 2 * time.Second,
+		},
+		Log: Log{
+			Level:          "info",
+			Format:         "console",
+			Redact:         true,
+			WhatsmeowLevel: "warn",
+			BufferSize:     1000,
+			FileMaxSize:    100,
+			FileMaxBackups: 5,
+			FileMaxAge:     30,
+		},
+	}
+}
+
+// defaultDSN is the database used for a driver when no dsn is set
+var defaultDSN = map[string]string{
+	"postgres": "user=postgres password=localdb dbname=db_whatsapp sslmode=disable",
+	"sqlite":   "file:buzz.db",
+}
+
+// defaultFiles are looked for in the working directory when no config file
+// is given
+var defaultFiles = []string{"buzz.yaml", "buzz.yml", "buzz.toml"}
+
+// Load builds the configuration from the command line arguments and returns
+// the arguments left after the flags. The config file is read from -config,
+// BUZZ_CONFIG or the first of buzz.yaml, buzz.yml and buzz.toml found.
+func Load(name string, args []string) (*Config, []string, error) {
+	config := Default()
+	options := config.options()
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("BUZZ_CONFIG"), "config file, yaml or toml")
+	var set []func() error
+	for _, option := range options {
+		option := option
+		flags.Var(&flagValue{option: option, set: &set}, option.flag(), option.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	path := *file
+	if path == "" {
+		for _, candidate := range defaultFiles {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+	if path != "" {
+		if err := config.readFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, option := range options {
+		value, found := os.LookupEnv(option.env)
+		if !found || value == "" {
+			continue
+		}
+		if err := option.set(value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", option.env, err)
+		}
+	}
+
+	for _, apply := range set {
+		if err := apply(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := config.resolve(); err != nil {
+		return nil, nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return config, flags.Args(), nil
+}
+
+// resolve fills in the values that depend on others
+func (c *Config) resolve() error {
+	if c.Database.DSN == "" {
+		c.Database.DSN = defaultDSN[c.Database.Driver]
+	}
+
+	if c.Server.Dev && c.Auth.Secret == "" {
+		c.Auth.Secret = devSecret
+	}
+	if len(c.Auth.JwtKeys) == 0 {
+		c.Auth.JwtKeys = map[string]string{"default": c.Auth.Secret}
+		c.Auth.JwtKeyId = "default"
+	}
+	if c.Auth.JwtKeyId == "" && len(c.Auth.JwtKeys) == 1 {
+		for kid := range c.Auth.JwtKeys {
+			c.Auth.JwtKeyId = kid
+		}
+	}
+
+	if c.Server.Dev && len(c.Server.CorsOrigins) == 0 {
+		c.Server.CorsOrigins = []string{"http://localhost:5173"}
+	}
+
+	if c.Whatsapp.MediaPath == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		c.Whatsapp.MediaPath = filepath.Join(filepath.Dir(executable), "files")
+	}
+	return nil
+}
+
+var ErrUnknownFormat = errors.New("config file should end in .yaml, .yml or .toml")
+
+func (c *Config) readFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = decodeYAML(raw, c)
+	case ".toml":
+		err = decodeTOML(raw, c)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Unknown keys are rejected so a typo does not silently keep the default
+
+func decodeYAML(raw []byte, config *Config) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func decodeTOML(raw []byte, config *Config) error {
+	meta, err := toml.Decode(string(raw), config)
+	if err != nil {
+		return err
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return fmt.Errorf("unknown keys %s", strings.Join(keys, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// option binds a setting to its key in the config file, its environment
+// variable and its flag, named after the variable: BUZZ_LOGIN_MAX_FAILURES
+// is -login-max-failures
+type option struct {
+	key   string
+	env   string
+	usage string
+	value any
+}
+
+func (c *Config) options() []option {
+	return []option{
+		{"server.port", "BUZZ_PORT", "port to listen on", &c.Server.Port},
+		{"server.prefork", "BUZZ_PREFORK", "enable prefork", &c.Server.Prefork},
+		{"server.timezone", "BUZZ_TZ", "timezone", &c.Server.Timezone},
+		{"server.dev", "BUZZ_DEV", "enable development mode", &c.Server.Dev},
+		{"server.cors_origins", "BUZZ_CORS_ORIGINS", "origins allowed to call the api, separated by commas", &c.Server.CorsOrigins},
+
+		{"database.driver", "BUZZ_DB_DRIVER", "database driver, postgres or sqlite", &c.Database.Driver},
+		{"database.dsn", "BUZZ_DB_DSN", "database connection string", &c.Database.DSN},
+
+		{"password.memory", "BUZZ_ARGON_MEMORY", "argon2 memory in KiB", &c.Password.Memory},
+		{"password.iterations", "BUZZ_ARGON_ITERATIONS", "argon2 iterations", &c.Password.Iterations},
+		{"password.parallelism", "BUZZ_ARGON_PARALLELISM", "argon2 threads", &c.Password.Parallelism},
+		{"password.key_length", "BUZZ_ARGON_KEY_LENGTH", "argon2 key length in bytes", &c.Password.KeyLength},
+		{"password.salt_length", "BUZZ_ARGON_SALT_LENGTH", "argon2 salt length in bytes", &c.Password.SaltLength},
+		{"password.min_length", "BUZZ_MIN_PASSWORD_LENGTH", "minimum password length", &c.Password.MinLength},
+		{"password.reset_ttl", "BUZZ_PASSWORD_RESET_TTL", "how long a password reset token is valid", &c.Password.ResetTTL},
+
+		{"auth.secret", "BUZZ_SECRET", "secret of the password hashes and of tokens without jwt keys", &c.Auth.Secret},
+		{"auth.jwt_keys", "BUZZ_JWT_KEYS", "token signing keys as kid:key pairs separated by commas", jwtKeys{&c.Auth}},
+		{"auth.jwt_kid", "BUZZ_JWT_KID", "kid of the key new tokens are signed with", &c.Auth.JwtKeyId},
+		{"auth.access_token_ttl", "BUZZ_ACCESS_TOKEN_TTL", "how long an access token is valid", &c.Auth.AccessTokenTTL},
+		{"auth.refresh_token_ttl", "BUZZ_REFRESH_TOKEN_TTL", "how long a login lasts without activity", &c.Auth.RefreshTokenTTL},
+		{"auth.cookie_secure", "BUZZ_COOKIE_SECURE", "only send the login cookies over https", &c.Auth.CookieSecure},
+		{"auth.cookie_samesite", "BUZZ_COOKIE_SAMESITE", "samesite of the login cookies, lax, strict or none", &c.Auth.CookieSameSite},
+		{"auth.cookie_domain", "BUZZ_COOKIE_DOMAIN", "domain of the login cookies", &c.Auth.CookieDomain},
+
+		{"login.max_failures", "BUZZ_LOGIN_MAX_FAILURES", "failed logins of a username before it is locked", &c.Login.MaxFailures},
+		{"login.ip_max_failures", "BUZZ_LOGIN_IP_MAX_FAILURES", "failed logins from an ip before it is locked", &c.Login.IPMaxFailures},
+		{"login.failure_window", "BUZZ_LOGIN_FAILURE_WINDOW", "window failed logins are counted in", &c.Login.FailureWindow},
+		{"login.lockout_duration", "BUZZ_LOGIN_LOCKOUT_DURATION", "how long a locked login stays locked", &c.Login.LockoutDuration},
+		{"login.delay_step", "BUZZ_LOGIN_DELAY_STEP", "delay added to a response per failed login", &c.Login.DelayStep},
+		{"login.max_delay", "BUZZ_LOGIN_MAX_DELAY", "longest delay of a failed login", &c.Login.MaxDelay},
+
+		{"whatsapp.system_session", "BUZZ_SYSTEM_SESSION", "whatsapp session that messages dashboard users, 0 for none", &c.Whatsapp.SystemSessionId},
+		{"whatsapp.media_path", "BUZZ_MEDIA_PATH", "directory received media is stored in", &c.Whatsapp.MediaPath},
+		{"whatsapp.webhook_timeout", "BUZZ_WEBHOOK_TIMEOUT", "how long to wait on a webhook", &c.Whatsapp.WebhookTimeout},
+
+		{"metrics.enabled", "BUZZ_METRICS", "serve prometheus metrics on /metrics", &c.Metrics.Enabled},
+		{"metrics.token", "BUZZ_METRICS_TOKEN", "bearer token a scraper has to send for /metrics", &c.Metrics.Token},
+
+		{"tracing.exporter", "BUZZ_TRACING_EXPORTER", "where traces go, none, stdout or otlp", &c.Tracing.Exporter},
+		{"tracing.endpoint", "BUZZ_TRACING_ENDPOINT", "host:port of the otlp http collector", &c.Tracing.Endpoint},
+		{"tracing.insecure", "BUZZ_TRACING_INSECURE", "send traces to the collector without tls", &c.Tracing.Insecure},
+		{"tracing.service_name", "BUZZ_TRACING_SERVICE_NAME", "service name traces are reported under", &c.Tracing.ServiceName},
+		{"tracing.sample_ratio", "BUZZ_TRACING_SAMPLE_RATIO", "share of new traces to keep, between 0 and 1", &c.Tracing.SampleRatio},
+
+		{"health.timeout", "BUZZ_HEALTH_TIMEOUT", "how long a readiness check may take", &c.Health.Timeout},
+		{"health.sessions", "BUZZ_HEALTH_SESSIONS", "fail readiness when a session that should be connected is not", &c.Health.Sessions},
+
+		{"log.level", "BUZZ_LOG_LEVEL", "lowest level logged, trace, debug, info, warn or error", &c.Log.Level},
+		{"log.format", "BUZZ_LOG_FORMAT", "log format, console or json", &c.Log.Format},
+		{"log.redact", "BUZZ_LOG_REDACT", "hide tokens, message bodies and phone numbers in logs", &c.Log.Redact},
+		{"log.whatsmeow_level", "BUZZ_LOG_WHATSMEOW_LEVEL", "lowest level logged by the whatsapp library", &c.Log.WhatsmeowLevel},
+		{"log.buffer_size", "BUZZ_LOG_BUFFER_SIZE", "log events kept in memory for the log api", &c.Log.BufferSize},
+		{"log.file", "BUZZ_LOG_FILE", "file the log is also written to as json, empty for none", &c.Log.File},
+		{"log.file_max_size", "BUZZ_LOG_FILE_MAX_SIZE", "megabytes a log file grows to before it is rotated", &c.Log.FileMaxSize},
+		{"log.file_max_backups", "BUZZ_LOG_FILE_MAX_BACKUPS", "rotated log files to keep", &c.Log.FileMaxBackups},
+		{"log.file_max_age", "BUZZ_LOG_FILE_MAX_AGE", "days to keep rotated log files", &c.Log.FileMaxAge},
+	}
+}
+
+func (o option) flag() string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(o.env, "BUZZ_")), "_", "-")
+}
+
+// name is how errors refer to the option
+func (o option) name() string {
+	return fmt.Sprintf("%s (%s)", o.key, o.env)
+}
+
+// set parses s into the value of the option
+func (o option) set(s string) error {
+	switch value := o.value.(type) {
+	case *string:
+		*value = s
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		*value = b
+	case *int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		*value = n
+	case *uint8:
+		n, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return fmt.Errorf("%q is not a number between 0 and 255", s)
+		}
+		*value = uint8(n)
+	case *uint32:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%q is not a positive number", s)
+		}
+		*value = uint32(n)
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		*value = f
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 90s or 15m", s)
+		}
+		*value = d
+	case *[]string:
+		*value = nil
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*value = append(*value, item)
+			}
+		}
+	case jwtKeys:
+		return value.set(s)
+	default:
+		return fmt.Errorf("unsupported option type %T", o.value)
+	}
+	return nil
+}
+
+// jwtKeys reads kid:key pairs separated by commas. The first kid signs new
+// tokens unless the configured kid is one of them.
+type jwtKeys struct {
+	auth *Auth
+}
+
+func (k jwtKeys) set(s string) error {
+	keys := map[string]string{}
+	first := ""
+	for _, pair := range strings.Split(s, ",") {
+		kid, key, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || kid == "" || key == "" {
+			return fmt.Errorf("%q is not a list of kid:key pairs", s)
+		}
+		if first == "" {
+			first = kid
+		}
+		keys[kid] = key
+	}
+
+	k.auth.JwtKeys = keys
+	if _, found := keys[k.auth.JwtKeyId]; !found {
+		k.auth.JwtKeyId = first
+	}
+	return nil
+}
+
+// flagValue defers a flag until the file and the environment are read, so
+// flags override them whatever order they are parsed in
+type flagValue struct {
+	option option
+	set    *[]func() error
+}
+
+func (f *flagValue) String() string {
+	return ""
+}
+
+func (f *flagValue) Set(s string) error {
+	*f.set = append(*f.set, func() error {
+		if err := f.option.set(s); err != nil {
+			return fmt.Errorf("-%s: %w", f.option.flag(), err)
+		}
+		return nil
+	})
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	_, ok := f.option.value.(*bool)
+	return ok
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalid = errors.New("invalid configuration")
+
+// logLevels are the levels zerolog knows
+var logLevels = map[string]bool{
+	"trace":    true,
+	"debug":    true,
+	"info":     true,
+	"warn":     true,
+	"error":    true,
+	"fatal":    true,
+	"panic":    true,
+	"disabled": true,
+}
+
+// Validate reports every value Buzz cannot run with at once
+func (c *Config) Validate() error {
+	names := map[string]string{}
+	for _, option := range c.options() {
+		names[option.key] = option.name()
+	}
+
+	var problems []string
+	check := func(ok bool, key string, format string, args ...any) {
+		if !ok {
+			problems = append(problems, names[key]+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port", "should be between 1 and 65535, got %d", c.Server.Port)
+	_, err := time.LoadLocation(c.Server.Timezone)
+	check(err == nil, "server.timezone", "unknown timezone %q", c.Server.Timezone)
+	for _, origin := range c.Server.CorsOrigins {
+		u, err := url.Parse(origin)
+		check(
+			err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && strings.TrimSuffix(u.Path, "/") == "",
+			"server.cors_origins", "%q should be a scheme and host like https://buzz.example.com", origin,
+		)
+	}
+
+	_, known := defaultDSN[c.Database.Driver]
+	check(known, "database.driver", "should be postgres or sqlite, got %q", c.Database.Driver)
+	check(!known || c.Database.DSN != "", "database.dsn", "should not be empty")
+
+	check(c.Password.Memory >= 8*uint32(c.Password.Parallelism), "password.memory", "should be at least 8 KiB per thread")
+	check(c.Password.Iterations > 0, "password.iterations", "should be at least 1")
+	check(c.Password.Parallelism > 0, "password.parallelism", "should be at least 1")
+	check(c.Password.KeyLength >= 16, "password.key_length", "should be at least 16 bytes")
+	check(c.Password.SaltLength >= 8, "password.salt_length", "should be at least 8 bytes")
+	check(c.Password.MinLength > 0, "password.min_length", "should be at least 1")
+	check(c.Password.ResetTTL > 0, "password.reset_ttl", "should be positive")
+
+	check(c.Auth.Secret != "", "auth.secret", "should not be empty, set it to a long random value")
+	check(c.Server.Dev || !insecureSecrets[c.Auth.Secret], "auth.secret", "is publicly known, set it to a long random value")
+	for kid, key := range c.Auth.JwtKeys {
+		if kid == "default" && key == c.Auth.Secret {
+			// the key of the secret, checked above
+			continue
+		}
+		check(kid != "" && key != "", "auth.jwt_keys", "kid and key should not be empty")
+		check(c.Server.Dev || key == "" || !insecureSecrets[key], "auth.jwt_keys", "key %q is publicly known, set it to a long random value", kid)
+	}
+	_, found := c.Auth.JwtKeys[c.Auth.JwtKeyId]
+	check(found, "auth.jwt_kid", "should be one of the jwt keys, got %q", c.Auth.JwtKeyId)
+	check(c.Auth.AccessTokenTTL > 0, "auth.access_token_ttl", "should be positive")
+	check(c.Auth.RefreshTokenTTL > c.Auth.AccessTokenTTL, "auth.refresh_token_ttl", "should be longer than the access token ttl")
+	sameSite := strings.ToLower(c.Auth.CookieSameSite)
+	check(sameSite == "lax" || sameSite == "strict" || sameSite == "none", "auth.cookie_samesite", "should be lax, strict or none, got %q", c.Auth.CookieSameSite)
+	check(sameSite != "none" || c.Auth.CookieSecure, "auth.cookie_samesite", "none needs auth.cookie_secure, browsers drop the cookies otherwise")
+
+	check(c.Login.MaxFailures > 0, "login.max_failures", "should be at least 1")
+	check(c.Login.IPMaxFailures > 0, "login.ip_max_failures", "should be at least 1")
+	check(c.Login.FailureWindow > 0, "login.failure_window", "should be positive")
+	check(c.Login.LockoutDuration > 0, "login.lockout_duration", "should be positive")
+	check(c.Login.DelayStep >= 0, "login.delay_step", "should not be negative")
+	check(c.Login.MaxDelay >= c.Login.DelayStep, "login.max_delay", "should not be shorter than the delay step")
+
+	check(c.Whatsapp.SystemSessionId >= 0, "whatsapp.system_session", "should not be negative")
+	check(c.Whatsapp.MediaPath != "", "whatsapp.media_path", "should not be empty")
+	check(c.Whatsapp.WebhookTimeout > 0, "whatsapp.webhook_timeout", "should be positive")
+
+	exporter := c.Tracing.Exporter
+	check(exporter == "none" || exporter == "stdout" || exporter == "otlp", "tracing.exporter", "should be none, stdout or otlp, got %q", exporter)
+	check(exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint", "should not be empty with the otlp exporter")
+	check(c.Tracing.ServiceName != "", "tracing.service_name", "should not be empty")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "should be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	check(c.Health.Timeout > 0, "health.timeout", "should be positive")
+
+	check(logLevels[c.Log.Level], "log.level", "should be trace, debug, info, warn, error or disabled, got %q", c.Log.Level)
+	check(c.Log.Format == "console" || c.Log.Format == "json", "log.format", "should be console or json, got %q", c.Log.Format)
+	check(logLevels[c.Log.WhatsmeowLevel], "log.whatsmeow_level", "should be trace, debug, info, warn, error or disabled, got %q", c.Log.WhatsmeowLevel)
+	check(c.Log.BufferSize >= 0, "log.buffer_size", "should not be negative")
+	check(c.Log.File == "" || c.Log.FileMaxSize > 0, "log.file_max_size", "should be at least 1")
+	check(c.Log.FileMaxBackups >= 0, "log.file_max_backups", "should not be negative")
+	check(c.Log.FileMaxAge >= 0, "log.file_max_age", "should not be negative")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n  %s", ErrInvalid, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
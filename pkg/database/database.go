@@ -1,43 +1,132 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	"github.com/nugrhrizki/buzz/pkg/env"
+	"github.com/nugrhrizki/buzz/pkg/config"
 	"github.com/rs/zerolog"
+	"modernc.org/sqlite"
+)
+
+// Dialects Buzz can store its tables in, named after their database/sql
+// driver
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
 )
 
 type Database struct {
-	DB  *sqlx.DB
-	log *zerolog.Logger
+	DB      *sqlx.DB
+	dialect string
+	log     *zerolog.Logger
 }
 
-func New(env *env.Env, log *zerolog.Logger) *Database {
+func New(config *config.Config, log *zerolog.Logger) *Database {
+	dsn := config.Database.DSN
+	if config.Database.Driver == SQLite {
+		dsn = sqliteDSN(dsn)
+	}
+
 	db, err := sqlx.Connect(
-		env.DB_DRIVER,
-		env.DB_DSN,
+		config.Database.Driver,
+		dsn,
 	)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 	return &Database{
-		DB:  db,
-		log: log,
+		DB:      db,
+		dialect: config.Database.Driver,
+		log:     log,
+	}
+}
+
+func init() {
+	// sha256 is built into postgres, migrations hashing secrets need it on
+	// sqlite too. It returns the hex digest, like encode(sha256(x), 'hex').
+	sqlite.MustRegisterDeterministicScalarFunction("sha256", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		var data []byte
+		switch v := args[0].(type) {
+		case string:
+			data = []byte(v)
+		case []byte:
+			data = v
+		case nil:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("sha256 cannot hash %T", v)
+		}
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	})
+}
+
+// sqliteDSN turns on what Buzz relies on unless the dsn already sets
+// pragmas: foreign keys, waiting on locks instead of failing, WAL so readers
+// do not block the writer, and times written in a format sqlite can compare
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "_pragma=") {
+		return dsn
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+}
+
+// Dialect is the database Buzz runs on, Postgres or SQLite
+func (d *Database) Dialect() string {
+	return d.dialect
+}
+
+// args stores times in UTC on sqlite, which keeps them as text and compares
+// them as such, so they line up with CURRENT_TIMESTAMP
+func (d *Database) args(args []interface{}) []interface{} {
+	if d.dialect != SQLite {
+		return args
+	}
+
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			converted[i] = t.UTC()
+		case *time.Time:
+			if t != nil {
+				converted[i] = t.UTC()
+			} else {
+				converted[i] = nil
+			}
+		default:
+			converted[i] = arg
+		}
 	}
+	return converted
 }
 
+// Get, Select and Exec trace like their Context versions, each query starts a
+// trace of its own
 func (d *Database) Get(dest interface{}, query string, args ...interface{}) error {
-	return d.DB.Get(dest, query, args...)
+	return d.GetContext(context.Background(), dest, query, args...)
 }
 
 func (d *Database) Select(dest interface{}, query string, args ...interface{}) error {
-	return d.DB.Select(dest, query, args...)
+	return d.SelectContext(context.Background(), dest, query, args...)
 }
 
 func (d *Database) Query(query string, args ...interface{}) (*sqlx.Rows, error) {
-	return d.DB.Queryx(query, args...)
+	return d.DB.Queryx(query, d.args(args)...)
 }
 
 func (d *Database) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
@@ -45,7 +134,7 @@ func (d *Database) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error)
 }
 
 func (d *Database) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return d.DB.Exec(query, args...)
+	return d.ExecContext(context.Background(), query, args...)
 }
 
 func (d *Database) NamedExec(query string, arg interface{}) (sql.Result, error) {
@@ -64,25 +153,13 @@ func (d *Database) Rollback(tx *sql.Tx) error {
 	return tx.Rollback()
 }
 
-func (d *Database) Close() {
-	d.DB.Close()
+// Ping checks the database can still be reached
+func (d *Database) Ping(ctx context.Context) error {
+	return d.DB.PingContext(ctx)
 }
 
-type Migrate interface {
-	Migration() string
-}
-
-func (d *Database) Migrate(migration ...Migrate) {
-	schemas := make([]string, len(migration))
-	for i, m := range migration {
-		schemas[i] = m.Migration()
-	}
-
-	for _, schema := range schemas {
-		if _, err := d.DB.Exec(schema); err != nil {
-			d.log.Warn().Err(err).Msg("failed to migrate")
-		}
-	}
+func (d *Database) Close() {
+	d.DB.Close()
 }
 
 type Seeder interface {
@@ -0,0 +1,148 @@
+package database_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestPlaceholders(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		// numbered placeholders bind by number, whatever order they appear in
+		var joined string
+		if err := db.Get(&joined, "SELECT CAST($2 AS TEXT) || CAST($1 AS TEXT) || CAST($2 AS TEXT)", "a", "b"); err != nil {
+			t.Fatal(err)
+		}
+		if joined != "bab" {
+			t.Errorf("got %q, want %q", joined, "bab")
+		}
+	})
+}
+
+// Times given as arguments have to compare with the ones the database
+// writes, whatever zone they are in
+func TestTimeArguments(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		column := "TIMESTAMP"
+		if db.Dialect() == database.Postgres {
+			column = "TIMESTAMPTZ"
+		}
+		if _, err := db.Exec("CREATE TABLE events (at " + column + " NOT NULL DEFAULT CURRENT_TIMESTAMP)"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("INSERT INTO events DEFAULT VALUES"); err != nil {
+			t.Fatal(err)
+		}
+
+		zone := time.FixedZone("WIB", 7*60*60)
+		tests := []struct {
+			name  string
+			since time.Time
+			found bool
+		}{
+			{"an hour ago", time.Now().Add(-time.Hour), true},
+			{"an hour ago elsewhere", time.Now().Add(-time.Hour).In(zone), true},
+			{"in an hour", time.Now().Add(time.Hour), false},
+			{"in an hour elsewhere", time.Now().Add(time.Hour).In(zone), false},
+		}
+		for _, tt := range tests {
+			var count int
+			if err := db.Get(&count, "SELECT COUNT(*) FROM events WHERE at > $1", tt.since); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if (count == 1) != tt.found {
+				t.Errorf("%s: found %d events", tt.name, count)
+			}
+		}
+
+		var latest database.Time
+		if err := db.Get(&latest, "SELECT MAX(at) FROM events"); err != nil {
+			t.Fatalf("max: %v", err)
+		}
+		if time.Since(latest.Time).Abs() > time.Minute {
+			t.Errorf("max: got %v, want about now", latest.Time)
+		}
+	})
+}
+
+// module is a module whose scripts are the same on every dialect
+type module struct {
+	name    string
+	scripts fstest.MapFS
+}
+
+func (m module) Migrations() (string, fs.FS) {
+	return m.name, m.scripts
+}
+
+func newModule(scripts map[string]string) module {
+	files := fstest.MapFS{}
+	for _, dialect := range []string{database.Postgres, database.SQLite} {
+		for name, script := range scripts {
+			files["migrations/"+dialect+"/"+name] = &fstest.MapFile{Data: []byte(script)}
+		}
+	}
+	return module{"notes", files}
+}
+
+func TestMigrate(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		notes := newModule(map[string]string{
+			"0001_create_notes.up.sql":   "CREATE TABLE notes (body TEXT NOT NULL)",
+			"0001_create_notes.down.sql": "DROP TABLE notes",
+			"0002_add_author.up.sql":     "ALTER TABLE notes ADD COLUMN author TEXT",
+			"0002_add_author.down.sql":   "ALTER TABLE notes DROP COLUMN author",
+		})
+
+		statuses := func(want ...string) {
+			t.Helper()
+			got, err := db.MigrationStatus(notes)
+			if err != nil {
+				t.Fatalf("status: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d statuses, want %v", len(got), want)
+			}
+			for i := range want {
+				if got[i].Status != want[i] {
+					t.Errorf("migration %d: got %s, want %s", got[i].Version, got[i].Status, want[i])
+				}
+			}
+		}
+
+		statuses(database.StatusPending, database.StatusPending)
+		if err := db.Migrate(notes); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		if err := db.Migrate(notes); err != nil {
+			t.Fatalf("migrate again: %v", err)
+		}
+		statuses(database.StatusApplied, database.StatusApplied)
+		if _, err := db.Exec("INSERT INTO notes (body, author) VALUES ($1, $2)", "hi", "alice"); err != nil {
+			t.Errorf("insert after migrating: %v", err)
+		}
+
+		if err := db.MigrateDown(1, notes); err != nil {
+			t.Fatalf("migrate down: %v", err)
+		}
+		statuses(database.StatusApplied, database.StatusPending)
+		if _, err := db.Exec("INSERT INTO notes (body, author) VALUES ($1, $2)", "hi", "alice"); err == nil {
+			t.Error("the reverted column is still there")
+		}
+
+		changed := newModule(map[string]string{
+			"0001_create_notes.up.sql": "CREATE TABLE notes (body TEXT)",
+		})
+		if err := db.Migrate(changed); !errors.Is(err, database.ErrChecksumMismatch) {
+			t.Errorf("migrate a changed script: got %v, want %v", err, database.ErrChecksumMismatch)
+		}
+		if err := db.MigrateDown(1, changed); !errors.Is(err, database.ErrNoDownScript) {
+			t.Errorf("migrate down without a script: got %v, want %v", err, database.ErrNoDownScript)
+		}
+	})
+}
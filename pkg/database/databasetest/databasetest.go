@@ -0,0 +1,100 @@
+// Package databasetest runs repository tests on every dialect Buzz supports.
+// SQLite always runs, on a file of its own. Postgres runs when
+// BUZZ_TEST_POSTGRES_DSN is set, each test gets a schema of its own in that
+// database which is dropped afterwards.
+package databasetest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+// PostgresEnv names the variable holding the dsn of the Postgres database
+const PostgresEnv = "BUZZ_TEST_POSTGRES_DSN"
+
+// Run calls test once per dialect, each time with an empty database
+func Run(t *testing.T, test func(t *testing.T, db *database.Database)) {
+	t.Helper()
+
+	t.Run(database.SQLite, func(t *testing.T) {
+		test(t, open(t, database.SQLite, "file:"+filepath.Join(t.TempDir(), "buzz.db")))
+	})
+
+	t.Run(database.Postgres, func(t *testing.T) {
+		dsn := os.Getenv(PostgresEnv)
+		if dsn == "" {
+			t.Skip(PostgresEnv + " is not set")
+		}
+		test(t, open(t, database.Postgres, schema(t, dsn)))
+	})
+}
+
+// Migrate runs the migrations of modules, in order, on db
+func Migrate(t *testing.T, db *database.Database, modules ...database.Migrate) {
+	t.Helper()
+	if err := db.Migrate(modules...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
+
+// Logger discards what repositories log
+func Logger() *zerolog.Logger {
+	log := zerolog.Nop()
+	return &log
+}
+
+// open connects the way the server does. database.New exits when it cannot
+// connect, the postgres dsn was already tried by schema.
+func open(t *testing.T, driver string, dsn string) *database.Database {
+	t.Helper()
+
+	db := database.New(&config.Config{
+		Database: config.Database{Driver: driver, DSN: dsn},
+	}, Logger())
+	t.Cleanup(db.Close)
+	return db
+}
+
+// schema creates a schema for one test and returns a dsn using it
+func schema(t *testing.T, dsn string) string {
+	t.Helper()
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	name := "buzz_test_" + hex.EncodeToString(suffix)
+
+	admin, err := sqlx.Connect(database.Postgres, dsn)
+	if err != nil {
+		t.Fatalf("cannot connect to postgres: %v", err)
+	}
+	if _, err := admin.Exec("CREATE SCHEMA " + name); err != nil {
+		admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + name + " CASCADE")
+		admin.Close()
+	})
+
+	// lib/pq hands unknown options to the server as session settings
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + name
+	}
+	return dsn + " search_path=" + name
+}
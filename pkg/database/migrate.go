@@ -0,0 +1,346 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrate is implemented by repositories that own tables. Migrations returns
+// the module name and its scripts for every dialect,
+// migrations/<dialect>/<version>_<name>.up.sql with an optional matching
+// .down.sql. Each dialect should hold the same versions.
+type Migrate interface {
+	Migrations() (string, fs.FS)
+}
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrMissingScript    = errors.New("applied migration has no script")
+	ErrNoDownScript     = errors.New("migration cannot be rolled back")
+	ErrBadScriptName    = errors.New("migration script should be named <version>_<name>.up.sql or .down.sql")
+	ErrUnknownDialect   = errors.New("database dialect is not supported")
+)
+
+var schemaMigrations = map[string]string{
+	Postgres: `CREATE TABLE IF NOT EXISTS schema_migrations (
+	id BIGSERIAL PRIMARY KEY,
+	module TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (module, version)
+)`,
+	SQLite: `CREATE TABLE IF NOT EXISTS schema_migrations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	module TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (module, version)
+)`,
+}
+
+// Migration is one versioned change to the schema of a module
+type Migration struct {
+	Module  string
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%s %04d_%s", m.Module, m.Version, m.Name)
+}
+
+type AppliedMigration struct {
+	Id        int64     `db:"id"`
+	Module    string    `db:"module"`
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+const (
+	StatusApplied = "applied"
+	StatusPending = "pending"
+	StatusChanged = "changed"
+	StatusMissing = "missing"
+)
+
+type MigrationStatus struct {
+	Module    string
+	Version   int
+	Name      string
+	Status    string
+	AppliedAt *time.Time
+}
+
+// load reads the scripts of a module for a dialect sorted by version
+func load(m Migrate, dialect string) ([]Migration, error) {
+	module, fsys := m.Migrations()
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		kind := path.Ext(strings.TrimSuffix(file, ".sql"))
+		if entry.IsDir() || !strings.HasSuffix(file, ".sql") || (kind != ".up" && kind != ".down") {
+			return nil, fmt.Errorf("%s %s: %w", module, file, ErrBadScriptName)
+		}
+
+		version, name, ok := strings.Cut(strings.TrimSuffix(file, kind+".sql"), "_")
+		number, err := strconv.Atoi(version)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("%s %s: %w", module, file, ErrBadScriptName)
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, found := byVersion[number]
+		if !found {
+			migration = &Migration{Module: module, Version: number, Name: name}
+			byVersion[number] = migration
+		}
+		if kind == ".up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("%s: %w", migration, ErrBadScriptName)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (d *Database) applied() (map[string]AppliedMigration, error) {
+	schema, found := schemaMigrations[d.dialect]
+	if !found {
+		return nil, fmt.Errorf("%s: %w", d.dialect, ErrUnknownDialect)
+	}
+	if _, err := d.DB.Exec(schema); err != nil {
+		return nil, err
+	}
+
+	var rows []AppliedMigration
+	if err := d.DB.Select(&rows, "SELECT * FROM schema_migrations ORDER BY id"); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]AppliedMigration, len(rows))
+	for _, row := range rows {
+		applied[key(row.Module, row.Version)] = row
+	}
+	return applied, nil
+}
+
+func key(module string, version int) string {
+	return module + "/" + strconv.Itoa(version)
+}
+
+// Migrate applies every pending migration, module by module in the given
+// order. Each migration runs in its own transaction. It refuses to run when
+// an applied migration was modified since.
+func (d *Database) Migrate(modules ...Migrate) error {
+	applied, err := d.applied()
+	if err != nil {
+		return err
+	}
+
+	var pending []Migration
+	for _, m := range modules {
+		migrations, err := load(m, d.dialect)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			row, done := applied[key(migration.Module, migration.Version)]
+			if !done {
+				pending = append(pending, migration)
+				continue
+			}
+			if row.Checksum != migration.Checksum() {
+				return fmt.Errorf("%s: %w", &migration, ErrChecksumMismatch)
+			}
+		}
+	}
+
+	for _, migration := range pending {
+		if err := d.apply(migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Database) apply(migration Migration) error {
+	tx, err := d.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration.Up); err != nil {
+		return fmt.Errorf("%s: %w", &migration, err)
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (module, version, name, checksum) VALUES ($1, $2, $3, $4)",
+		migration.Module,
+		migration.Version,
+		migration.Name,
+		migration.Checksum(),
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	d.log.Info().Str("migration", migration.String()).Msg("applied migration")
+	return nil
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first
+func (d *Database) MigrateDown(steps int, modules ...Migrate) error {
+	if _, err := d.applied(); err != nil {
+		return err
+	}
+
+	scripts := map[string]Migration{}
+	for _, m := range modules {
+		migrations, err := load(m, d.dialect)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			scripts[key(migration.Module, migration.Version)] = migration
+		}
+	}
+
+	var rows []AppliedMigration
+	if err := d.DB.Select(&rows, "SELECT * FROM schema_migrations ORDER BY id DESC LIMIT $1", steps); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		migration, found := scripts[key(row.Module, row.Version)]
+		switch {
+		case !found:
+			return fmt.Errorf("%s %04d_%s: %w", row.Module, row.Version, row.Name, ErrMissingScript)
+		case migration.Down == "":
+			return fmt.Errorf("%s: %w", &migration, ErrNoDownScript)
+		}
+
+		if err := d.revert(migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Database) revert(migration Migration) error {
+	tx, err := d.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration.Down); err != nil {
+		return fmt.Errorf("%s: %w", &migration, err)
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM schema_migrations WHERE module = $1 AND version = $2",
+		migration.Module,
+		migration.Version,
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	d.log.Info().Str("migration", migration.String()).Msg("reverted migration")
+	return nil
+}
+
+// MigrationStatus lists every known migration with whether it is applied,
+// pending, changed since it was applied or applied without a script
+func (d *Database) MigrationStatus(modules ...Migrate) ([]MigrationStatus, error) {
+	applied, err := d.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range modules {
+		migrations, err := load(m, d.dialect)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, migration := range migrations {
+			status := MigrationStatus{
+				Module:  migration.Module,
+				Version: migration.Version,
+				Name:    migration.Name,
+				Status:  StatusPending,
+			}
+
+			k := key(migration.Module, migration.Version)
+			if row, done := applied[k]; done {
+				status.Status = StatusApplied
+				if row.Checksum != migration.Checksum() {
+					status.Status = StatusChanged
+				}
+				status.AppliedAt = &row.AppliedAt
+				delete(applied, k)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Module:    row.Module,
+			Version:   row.Version,
+			Name:      row.Name,
+			Status:    StatusMissing,
+			AppliedAt: &appliedAt,
+		})
+	}
+	return statuses, nil
+}
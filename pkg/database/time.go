@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// timeFormats are the layouts sqlite hands back times in when it cannot tell
+// a column holds them, e.g. the result of MAX() on a timestamp column
+var timeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Time is a time.Time that also scans from text, for computed columns that
+// both dialects should return as times
+type Time struct {
+	time.Time
+}
+
+func (t *Time) Scan(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into a time", value)
+}
+
+func (t *Time) parse(value string) error {
+	for _, format := range timeFormats {
+		if parsed, err := time.Parse(format, value); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a time", value)
+}
+
+func (t Time) Value() (driver.Value, error) {
+	return t.Time, nil
+}
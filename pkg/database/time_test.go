@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeScan(t *testing.T) {
+	want := time.Date(2026, 1, 2, 3, 4, 5, 600000000, time.UTC)
+
+	tests := []struct {
+		name  string
+		value any
+		want  time.Time
+		err   bool
+	}{
+		{"time", want, want, false},
+		{"sqlite text", "2026-01-02 03:04:05.6+00:00", want, false},
+		{"sqlite bytes", []byte("2026-01-02 03:04:05.6+00:00"), want, false},
+		{"offset", "2026-01-02 10:04:05.6+07:00", want, false},
+		{"iso", "2026-01-02T03:04:05.6+00:00", want, false},
+		{"without zone", "2026-01-02 03:04:05.6", want, false},
+		{"current timestamp", "2026-01-02 03:04:05", want.Truncate(time.Second), false},
+		{"null", nil, time.Time{}, false},
+		{"not a time", "yesterday", time.Time{}, true},
+		{"number", int64(1767323045), time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Time
+			err := got.Scan(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("Scan(%v) error = %v, want error %v", tt.value, err, tt.err)
+			}
+			if !tt.err && !got.Equal(tt.want) {
+				t.Errorf("Scan(%v) = %v, want %v", tt.value, got.Time, tt.want)
+			}
+		})
+	}
+}
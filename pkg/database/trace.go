@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nugrhrizki/buzz/pkg/telemetry"
+)
+
+var tracer = otel.Tracer("github.com/nugrhrizki/buzz/pkg/database")
+
+// startSpan traces a query, as part of the operation traced in ctx if there
+// is one. Queries outside of one start a trace of their own, which the
+// sampler keeps the same share of as requests.
+func (d *Database) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return tracer.Start(ctx, strings.ToUpper(operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(d.dialect),
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+// endSpan ends the span of a query, finding no rows is not a failure
+func endSpan(span trace.Span, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	telemetry.End(span, err)
+}
+
+func (d *Database) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := d.startSpan(ctx, query)
+	err := d.DB.GetContext(ctx, dest, query, d.args(args)...)
+	endSpan(span, err)
+	return err
+}
+
+func (d *Database) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := d.startSpan(ctx, query)
+	err := d.DB.SelectContext(ctx, dest, query, d.args(args)...)
+	endSpan(span, err)
+	return err
+}
+
+func (d *Database) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := d.startSpan(ctx, query)
+	result, err := d.DB.ExecContext(ctx, query, d.args(args)...)
+	endSpan(span, err)
+	return result, err
+}
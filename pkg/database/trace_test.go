@@ -0,0 +1,64 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestTrace(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+		ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+		defer request.End()
+
+		var one int
+		tests := []struct {
+			name      string
+			operation string
+			query     func() error
+			parent    trace.SpanID
+		}{
+			{"get", "SELECT", func() error { return db.Get(&one, "SELECT 1") }, trace.SpanID{}},
+			{"select", "SELECT", func() error { return db.Select(&[]int{}, "SELECT 1") }, trace.SpanID{}},
+			{"exec", "CREATE", func() error {
+				_, err := db.Exec("CREATE TABLE traced (id INTEGER)")
+				return err
+			}, trace.SpanID{}},
+			{"get in a request", "SELECT", func() error { return db.GetContext(ctx, &one, "SELECT 1") }, request.SpanContext().SpanID()},
+			{"exec in a request", "INSERT", func() error {
+				_, err := db.ExecContext(ctx, "INSERT INTO traced (id) VALUES ($1)", 1)
+				return err
+			}, request.SpanContext().SpanID()},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ended := len(recorder.Ended())
+				if err := tt.query(); err != nil {
+					t.Fatal(err)
+				}
+
+				spans := recorder.Ended()[ended:]
+				if len(spans) != 1 {
+					t.Fatalf("got %d spans, want 1", len(spans))
+				}
+				if spans[0].Name() != tt.operation {
+					t.Errorf("name = %s, want %s", spans[0].Name(), tt.operation)
+				}
+				if spans[0].Parent().SpanID() != tt.parent {
+					t.Errorf("parent = %s, want %s", spans[0].Parent().SpanID(), tt.parent)
+				}
+			})
+		}
+	})
+}
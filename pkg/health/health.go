@@ -0,0 +1,155 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+)
+
+// Statuses of a check
+const (
+	Ok   = "ok"
+	Fail = "fail"
+)
+
+var ErrTimeout = errors.New("check timed out")
+
+// Check is the outcome of one readiness check
+type Check struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+	Details any     `json:"details,omitempty"`
+}
+
+// Health checks what Buzz needs to serve requests
+type Health struct {
+	db       *database.Database
+	whatsapp *whatsapp.Whatsapp
+
+	timeout  time.Duration
+	sessions bool
+}
+
+func New(db *database.Database, whatsapp *whatsapp.Whatsapp, config *config.Config) *Health {
+	return &Health{
+		db:       db,
+		whatsapp: whatsapp,
+
+		timeout:  config.Health.Timeout,
+		sessions: config.Health.Sessions,
+	}
+}
+
+type probe struct {
+	name string
+	run  func(ctx context.Context) (any, error)
+}
+
+func (h *Health) probes() []probe {
+	probes := []probe{
+		{"database", func(ctx context.Context) (any, error) {
+			return nil, h.db.Ping(ctx)
+		}},
+		{"whatsapp_store", func(ctx context.Context) (any, error) {
+			return nil, h.whatsapp.CheckStore()
+		}},
+		{"media_storage", func(ctx context.Context) (any, error) {
+			return nil, h.whatsapp.CheckMedia()
+		}},
+	}
+	if h.sessions {
+		probes = append(probes, probe{"whatsapp_sessions", h.checkSessions})
+	}
+	return probes
+}
+
+// checkSessions fails when a session marked connected is not connected and
+// logged in
+func (h *Health) checkSessions(ctx context.Context) (any, error) {
+	states, err := h.whatsapp.SessionStates()
+	if err != nil {
+		return nil, err
+	}
+
+	down := 0
+	for _, state := range states {
+		if !state.Connected || !state.LoggedIn {
+			down++
+		}
+	}
+	if down > 0 {
+		return states, fmt.Errorf("%d of %d sessions are down", down, len(states))
+	}
+	return states, nil
+}
+
+// Ready runs every check at once, each within the timeout, and tells
+// whether all of them passed
+func (h *Health) Ready(ctx context.Context) ([]Check, bool) {
+	probes := h.probes()
+	checks := make([]Check, len(probes))
+
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p probe) {
+			defer wg.Done()
+			checks[i] = h.run(ctx, p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, check := range checks {
+		if check.Status != Ok {
+			ready = false
+		}
+	}
+	return checks, ready
+}
+
+// run gives up on a check after the timeout, the store and the media path
+// can not be cancelled so their goroutine is left to finish on its own
+func (h *Health) run(ctx context.Context, p probe) Check {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	type result struct {
+		details any
+		err     error
+	}
+	done := make(chan result, 1)
+
+	start := time.Now()
+	go func() {
+		details, err := p.run(ctx)
+		done <- result{details, err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		r.err = ErrTimeout
+	}
+
+	check := Check{
+		Name:    p.name,
+		Status:  Ok,
+		Latency: float64(time.Since(start).Microseconds()) / 1000,
+		Details: r.details,
+	}
+	if r.err != nil {
+		check.Status = Fail
+		check.Error = r.err.Error()
+	}
+	return check
+}
@@ -0,0 +1,38 @@
+package health
+
+import "github.com/gofiber/fiber/v2"
+
+// Livez answers as long as the process serves requests. It checks nothing
+// else, a database outage should not get Buzz restarted.
+func (h *Health) Livez(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Live",
+		"message": "Buzz is running",
+	})
+}
+
+// Readyz runs the checks and answers 503 when one failed, so Buzz is taken
+// out of rotation until it can serve requests again
+func (h *Health) Readyz(c *fiber.Ctx) error {
+	checks, ready := h.Ready(c.UserContext())
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"title":   "Not ready",
+			"message": "One or more checks failed",
+			"data": fiber.Map{
+				"checks": checks,
+			},
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"title":   "Ready",
+		"message": "All checks passed",
+		"data": fiber.Map{
+			"checks": checks,
+		},
+	})
+}
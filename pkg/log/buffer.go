@@ -0,0 +1,182 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+)
+
+// Entry is a log event kept in the buffer
+type Entry struct {
+	Id      uint64         `json:"id"`
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields"`
+
+	raw   string
+	level zerolog.Level
+}
+
+// Filter selects entries. Level is the lowest level returned, the other
+// fields match everything when empty. Text is looked for in the message and
+// the fields.
+type Filter struct {
+	Level   zerolog.Level
+	Session string
+	From    *time.Time
+	To      *time.Time
+	Text    string
+	Limit   int
+}
+
+// Buffer keeps the latest log events in memory and passes new ones on to
+// whoever tails the log
+type Buffer struct {
+	mu      sync.RWMutex
+	entries []Entry
+	next    int
+	full    bool
+	lastId  uint64
+
+	subscribers map[chan Entry]struct{}
+	closed      bool
+}
+
+func NewBuffer(config *config.Config) *Buffer {
+	return &Buffer{
+		entries:     make([]Entry, config.Log.BufferSize),
+		subscribers: make(map[chan Entry]struct{}),
+	}
+}
+
+// Write stores an event written by zerolog, it never fails so the other
+// outputs of the logger are not held up
+func (b *Buffer) Write(p []byte) (int, error) {
+	var event map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		return len(p), nil
+	}
+
+	entry := Entry{
+		Time:   time.Now(),
+		Level:  zerolog.NoLevel.String(),
+		Fields: event,
+		raw:    strings.ToLower(string(p)),
+		level:  zerolog.NoLevel,
+	}
+	if level, ok := event[zerolog.LevelFieldName].(string); ok {
+		entry.Level = level
+		if parsed, err := zerolog.ParseLevel(level); err == nil {
+			entry.level = parsed
+		}
+	}
+	if message, ok := event[zerolog.MessageFieldName].(string); ok {
+		entry.Message = message
+	}
+	delete(event, zerolog.LevelFieldName)
+	delete(event, zerolog.MessageFieldName)
+	delete(event, zerolog.TimestampFieldName)
+
+	b.mu.Lock()
+	b.lastId++
+	entry.Id = b.lastId
+	if len(b.entries) > 0 {
+		b.entries[b.next] = entry
+		b.next = (b.next + 1) % len(b.entries)
+		b.full = b.full || b.next == 0
+	}
+	for subscriber := range b.subscribers {
+		// a reader that falls behind misses events rather than blocking logging
+		select {
+		case subscriber <- entry:
+		default:
+		}
+	}
+	b.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Entries returns the buffered entries matching the filter, newest first
+func (b *Buffer) Entries(f Filter) []Entry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+
+	entries := []Entry{}
+	for i := 1; i <= count; i++ {
+		entry := b.entries[(b.next-i+len(b.entries))%len(b.entries)]
+		if !f.Match(&entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if f.Limit > 0 && len(entries) == f.Limit {
+			break
+		}
+	}
+	return entries
+}
+
+// Subscribe returns a channel receiving every new entry until cancel is
+// called. The channel is closed when the buffer is.
+func (b *Buffer) Subscribe() (<-chan Entry, func()) {
+	subscriber := make(chan Entry, 256)
+
+	b.mu.Lock()
+	if b.closed {
+		close(subscriber)
+	} else {
+		b.subscribers[subscriber] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return subscriber, func() {
+		b.mu.Lock()
+		delete(b.subscribers, subscriber)
+		b.mu.Unlock()
+	}
+}
+
+// Close ends every subscription, the server waits on open tails otherwise
+// when it shuts down
+func (b *Buffer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for subscriber := range b.subscribers {
+		close(subscriber)
+		delete(b.subscribers, subscriber)
+	}
+}
+
+// Match tells whether the entry passes the filter
+func (f *Filter) Match(entry *Entry) bool {
+	if entry.level < f.Level {
+		return false
+	}
+	if f.Session != "" && fmt.Sprint(entry.Fields["session"]) != f.Session {
+		return false
+	}
+	if f.From != nil && entry.Time.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && entry.Time.After(*f.To) {
+		return false
+	}
+	return f.Text == "" || strings.Contains(entry.raw, strings.ToLower(f.Text))
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type loggerKey struct{}
+
+// Middleware gives every request a logger carrying its request id, and the
+// trace id when it is traced. It expects requestid.New() to run first.
+func Middleware(log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		logger := log.With()
+		if id, ok := c.Locals(requestid.ConfigDefault.ContextKey).(string); ok {
+			logger = logger.Str("request_id", id)
+		}
+		if span := trace.SpanContextFromContext(c.UserContext()); span.IsValid() {
+			logger = logger.Str("trace_id", span.TraceID().String())
+		}
+
+		requestLog := logger.Logger()
+		c.SetUserContext(context.WithValue(c.UserContext(), loggerKey{}, &requestLog))
+		return c.Next()
+	}
+}
+
+// Request returns the logger of the request, or fallback outside one
+func Request(c *fiber.Ctx, fallback *zerolog.Logger) *zerolog.Logger {
+	if log, ok := c.UserContext().Value(loggerKey{}).(*zerolog.Logger); ok {
+		return log
+	}
+	return fallback
+}
@@ -1,15 +1,67 @@
 package log
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+)
+
+// Formats logs can be written in
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
 )
 
-func New() *zerolog.Logger {
-	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
-	log := zerolog.New(output).With().Timestamp().Str("role", filepath.Base(os.Args[0])).Logger()
+func New(config *config.Config, buffer *Buffer) *zerolog.Logger {
+	return NewTo(config, os.Stdout, buffer)
+}
+
+// NewTo builds the logger described by the configuration, writing to out.
+// Events are kept in buffer too unless it is nil.
+func NewTo(config *config.Config, out io.Writer, buffer *Buffer) *zerolog.Logger {
+	if config.Log.Format == FormatConsole {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
+	}
+
+	// the buffer and the file take the json zerolog writes
+	outputs := []io.Writer{out}
+	if buffer != nil {
+		outputs = append(outputs, buffer)
+	}
+	if config.Log.File != "" {
+		outputs = append(outputs, &lumberjack.Logger{
+			Filename:   config.Log.File,
+			MaxSize:    config.Log.FileMaxSize,
+			MaxBackups: config.Log.FileMaxBackups,
+			MaxAge:     config.Log.FileMaxAge,
+		})
+	}
+	if len(outputs) > 1 {
+		out = zerolog.MultiLevelWriter(outputs...)
+	}
+
+	if config.Log.Redact {
+		out = &redactor{out: out}
+	}
+
+	log := zerolog.New(out).
+		Level(parseLevel(config.Log.Level, zerolog.InfoLevel)).
+		With().Timestamp().Str("role", filepath.Base(os.Args[0])).Logger()
 	return &log
 }
+
+// parseLevel falls back to def for levels zerolog does not know, the
+// configuration has been validated by then
+func parseLevel(level string, def zerolog.Level) zerolog.Level {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil || level == "" {
+		return def
+	}
+	return parsed
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretFields never show their value
+var secretFields = map[string]bool{
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"authorization": true,
+	"cookie":        true,
+	"apikey":        true,
+	"api_key":       true,
+}
+
+// bodyFields hold what people wrote, which stays out of the logs
+var bodyFields = map[string]bool{
+	"body":         true,
+	"text":         true,
+	"caption":      true,
+	"conversation": true,
+}
+
+// phoneNumber matches the numbers in phone numbers and jids, e.g.
+// 6281234567890@s.whatsapp.net
+var phoneNumber = regexp.MustCompile(`\+?\b\d{8,15}\b`)
+
+// redactor rewrites every event before it reaches out: secrets and message
+// bodies are replaced and phone numbers keep their last four digits only
+type redactor struct {
+	out io.Writer
+}
+
+func (r *redactor) Write(p []byte) (int, error) {
+	var event map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		// not an event zerolog wrote, pass it on as is
+		return r.out.Write(p)
+	}
+
+	for key, value := range event {
+		event[key] = redact(key, value)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(event); err != nil {
+		return r.out.Write(p)
+	}
+
+	if _, err := r.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func redact(key string, value any) any {
+	name := strings.ToLower(key)
+
+	switch v := value.(type) {
+	case string:
+		if v != "" && (secretFields[name] || bodyFields[name]) {
+			return redacted
+		}
+		return maskPhoneNumbers(v)
+	case map[string]any:
+		for k, inner := range v {
+			v[k] = redact(k, inner)
+		}
+		return v
+	case []any:
+		for i, inner := range v {
+			v[i] = redact(key, inner)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func maskPhoneNumbers(s string) string {
+	return phoneNumber.ReplaceAllStringFunc(s, func(number string) string {
+		keep := len(number) - 4
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return '*'
+			}
+			return r
+		}, number[:keep]) + number[keep:]
+	})
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"github.com/rs/zerolog"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// whatsmeowLogger hands the logs of whatsmeow to zerolog
+type whatsmeowLogger struct {
+	log    zerolog.Logger
+	module string
+}
+
+// Whatsmeow returns a whatsmeow logger writing to log under module. level
+// keeps whatsmeow quieter than Buzz, it logs every frame at debug.
+func Whatsmeow(log *zerolog.Logger, level string, module string) waLog.Logger {
+	min := parseLevel(level, zerolog.WarnLevel)
+	if log.GetLevel() > min {
+		min = log.GetLevel()
+	}
+	return &whatsmeowLogger{
+		log:    log.Level(min),
+		module: module,
+	}
+}
+
+func (l *whatsmeowLogger) Errorf(msg string, args ...interface{}) {
+	l.log.Error().Str("module", l.module).Msgf(msg, args...)
+}
+
+func (l *whatsmeowLogger) Warnf(msg string, args ...interface{}) {
+	l.log.Warn().Str("module", l.module).Msgf(msg, args...)
+}
+
+func (l *whatsmeowLogger) Infof(msg string, args ...interface{}) {
+	l.log.Info().Str("module", l.module).Msgf(msg, args...)
+}
+
+func (l *whatsmeowLogger) Debugf(msg string, args ...interface{}) {
+	l.log.Debug().Str("module", l.module).Msgf(msg, args...)
+}
+
+func (l *whatsmeowLogger) Sub(module string) waLog.Logger {
+	return &whatsmeowLogger{
+		log:    l.log,
+		module: l.module + "/" + module,
+	}
+}
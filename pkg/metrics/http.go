@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Enabled tells whether /metrics should be served
+func (m *Metrics) Enabled() bool {
+	return m.enabled
+}
+
+// Middleware times every request under the route it matched, so paths with
+// ids in them share a series
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		// requests no route matched end on a middleware, keep their paths out
+		route := c.Route().Path
+		if c.Route().Method == "USE" {
+			route = "unmatched"
+		}
+
+		m.httpDuration.
+			WithLabelValues(c.Method(), route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// Handler serves the metrics, asking for the configured bearer token if any
+func (m *Metrics) Handler() fiber.Handler {
+	serve := adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+
+	return func(c *fiber.Ctx) error {
+		if m.token != "" {
+			given := c.Get(fiber.HeaderAuthorization)
+			if subtle.ConstantTimeCompare([]byte(given), []byte("Bearer "+m.token)) != 1 {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"status":  "error",
+					"title":   "Unauthorized",
+					"message": "A valid metrics token is required",
+				})
+			}
+		}
+		return serve(c)
+	}
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+// Directions of a message
+const (
+	Sent     = "sent"
+	Received = "received"
+)
+
+// Outcomes label whether something worked, pairings also time out
+const (
+	Success = "success"
+	Failure = "failure"
+	Timeout = "timeout"
+)
+
+// Metrics holds every Buzz metric. Labels only take values from small fixed
+// sets or the session id, never phone numbers, urls or message ids.
+type Metrics struct {
+	registry *prometheus.Registry
+	enabled  bool
+	token    string
+
+	sessionConnected *prometheus.GaugeVec
+	sessionLoggedIn  *prometheus.GaugeVec
+	messages         *prometheus.CounterVec
+	webhookDuration  *prometheus.HistogramVec
+	webhookFailures  *prometheus.CounterVec
+	pairings         *prometheus.CounterVec
+	reconnects       *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+}
+
+func New(db *database.Database, config *config.Config) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		enabled:  config.Metrics.Enabled,
+		token:    config.Metrics.Token,
+
+		sessionConnected: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "buzz_whatsapp_session_connected",
+			Help: "Whether the websocket of a session is connected.",
+		}, []string{"session"}),
+		sessionLoggedIn: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "buzz_whatsapp_session_logged_in",
+			Help: "Whether a session is logged in to WhatsApp.",
+		}, []string{"session"}),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "buzz_whatsapp_messages_total",
+			Help: "Messages sent and received by type and outcome.",
+		}, []string{"direction", "type", "outcome"}),
+		webhookDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "buzz_webhook_delivery_duration_seconds",
+			Help:    "Time taken to deliver a webhook by outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"outcome"}),
+		webhookFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "buzz_webhook_failures_total",
+			Help: "Webhooks that could not be delivered, by reason: error when the request failed, status when the receiver answered with an error.",
+		}, []string{"reason"}),
+		pairings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "buzz_whatsapp_qr_pairings_total",
+			Help: "QR pairings by outcome.",
+		}, []string{"outcome"}),
+		reconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "buzz_whatsapp_reconnects_total",
+			Help: "Automatic reconnects after a lost connection, by outcome: attempt for every try, failure for every try that failed.",
+		}, []string{"outcome"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "buzz_http_request_duration_seconds",
+			Help:    "Time taken to answer HTTP requests by method, route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db.DB.DB, db.Dialect()),
+		m.sessionConnected,
+		m.sessionLoggedIn,
+		m.messages,
+		m.webhookDuration,
+		m.webhookFailures,
+		m.pairings,
+		m.reconnects,
+		m.httpDuration,
+	)
+
+	return m
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SessionState records whether a session is connected and logged in
+func (m *Metrics) SessionState(id int, connected bool, loggedIn bool) {
+	session := strconv.Itoa(id)
+	m.sessionConnected.WithLabelValues(session).Set(boolValue(connected))
+	m.sessionLoggedIn.WithLabelValues(session).Set(boolValue(loggedIn))
+}
+
+// ForgetSession drops the series of a deleted session
+func (m *Metrics) ForgetSession(id int) {
+	session := strconv.Itoa(id)
+	m.sessionConnected.DeleteLabelValues(session)
+	m.sessionLoggedIn.DeleteLabelValues(session)
+}
+
+// Message counts a message, kind is one of the types of
+// whatsapp.MessageContent
+func (m *Metrics) Message(direction string, kind string, err error) {
+	outcome := Success
+	if err != nil {
+		outcome = Failure
+	}
+	m.messages.WithLabelValues(direction, kind, outcome).Inc()
+}
+
+// Webhook records a webhook delivery. err is set when the request failed,
+// failed when the receiver answered with an error status.
+func (m *Metrics) Webhook(took time.Duration, err error, failed bool) {
+	outcome := Success
+	switch {
+	case err != nil:
+		outcome = Failure
+		m.webhookFailures.WithLabelValues("error").Inc()
+	case failed:
+		outcome = Failure
+		m.webhookFailures.WithLabelValues("status").Inc()
+	}
+	m.webhookDuration.WithLabelValues(outcome).Observe(took.Seconds())
+}
+
+func (m *Metrics) Pairing(outcome string) {
+	m.pairings.WithLabelValues(outcome).Inc()
+}
+
+// Reconnect counts an automatic reconnect attempt, err is set when it failed
+func (m *Metrics) Reconnect(err error) {
+	m.reconnects.WithLabelValues("attempt").Inc()
+	if err != nil {
+		m.reconnects.WithLabelValues(Failure).Inc()
+	}
+}
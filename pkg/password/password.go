@@ -8,7 +8,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/nugrhrizki/buzz/pkg/env"
+	"github.com/nugrhrizki/buzz/pkg/config"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/argon2"
 )
@@ -29,14 +29,14 @@ type Password struct {
 	log *zerolog.Logger
 }
 
-func NewPassword(env *env.Env, log *zerolog.Logger) *Password {
+func NewPassword(config *config.Config, log *zerolog.Logger) *Password {
 	return &Password{
-		memory:      env.Memory,
-		iterations:  env.Iterations,
-		parallelism: env.Parallelism,
-		keyLength:   env.KeyLength,
-		saltLength:  env.SaltLength,
-		secret:      env.Secret,
+		memory:      config.Password.Memory,
+		iterations:  config.Password.Iterations,
+		parallelism: config.Password.Parallelism,
+		keyLength:   config.Password.KeyLength,
+		saltLength:  config.Password.SaltLength,
+		secret:      config.Auth.Secret,
 
 		log: log,
 	}
@@ -93,6 +93,21 @@ func (p *Password) CompareHashPassword(password, encodedHash string) (match bool
 	return false, nil
 }
 
+// CompareDummy spends as long as CompareHashPassword on a hash made with the
+// current settings and matches nothing. Use it when there is no hash to
+// compare with, answering sooner would tell the caller so.
+func (p *Password) CompareDummy(password string) {
+	salt := make([]byte, p.saltLength)
+	argon2.IDKey(
+		[]byte(password),
+		salt,
+		p.iterations,
+		p.memory,
+		p.parallelism,
+		p.keyLength,
+	)
+}
+
 func decodeHash(encodedHash string) (argon *Password, salt, hash []byte, err error) {
 	vals := strings.Split(encodedHash, "$")
 	if len(vals) != 6 {
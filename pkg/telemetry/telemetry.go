@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+)
+
+// Exporters traces can be sent to
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Telemetry sets up OpenTelemetry tracing. Packages trace through the global
+// tracer provider, which drops every span until an exporter is configured.
+type Telemetry struct {
+	enabled bool
+}
+
+func New(lc fx.Lifecycle, config *config.Config, log *zerolog.Logger) *Telemetry {
+	// trace context is read even when Buzz does not export spans, so the
+	// logs of a request carry the trace id of its caller. Webhooks start a
+	// trace of their own, linked to the send of the message they are about.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if config.Tracing.Exporter == ExporterNone {
+		return &Telemetry{}
+	}
+
+	exporter, err := newExporter(config.Tracing)
+	if err != nil {
+		log.Fatal().Err(err).Str("exporter", config.Tracing.Exporter).Msg("failed to create trace exporter")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(config.Tracing.ServiceName),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warn().Err(err).Msg("OpenTelemetry error")
+	}))
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return provider.Shutdown(ctx)
+		},
+	})
+
+	log.Info().Str("exporter", config.Tracing.Exporter).Msg("tracing enabled")
+	return &Telemetry{enabled: true}
+}
+
+func newExporter(config config.Tracing) (sdktrace.SpanExporter, error) {
+	if config.Exporter == ExporterStdout {
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
+
+// Enabled tells whether spans are exported
+func (t *Telemetry) Enabled() bool {
+	return t.enabled
+}
+
+// End marks the span as failed when err is set and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject adds the trace context of ctx to outgoing request headers
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
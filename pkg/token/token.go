@@ -0,0 +1,56 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+)
+
+type Token struct {
+	keys  map[string][]byte
+	keyId string
+}
+
+func NewToken(config *config.Config) *Token {
+	keys := make(map[string][]byte, len(config.Auth.JwtKeys))
+	for kid, key := range config.Auth.JwtKeys {
+		keys[kid] = []byte(key)
+	}
+
+	return &Token{
+		keys:  keys,
+		keyId: config.Auth.JwtKeyId,
+	}
+}
+
+// Keys returns every key tokens are accepted with, by kid
+func (t *Token) Keys() map[string][]byte {
+	return t.keys
+}
+
+// Sign signs claims with the active key and records its kid in the header
+func (t *Token) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = t.keyId
+	return token.SignedString(t.keys[t.keyId])
+}
+
+// NewRefreshToken returns an opaque refresh token and the hash to store for it
+func NewRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// secret is the RFC 6238 appendix B SHA-1 seed, "12345678901234567890"
+var secret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// The appendix B codes are eight digits long, ours are their last six
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.code {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted a secret that is not base32")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		c, err := Code(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name string
+		code string
+		step int64
+		ok   bool
+	}{
+		{"current step", code(current), current, true},
+		{"one step behind", code(current - 1), current - 1, true},
+		{"one step ahead", code(current + 1), current + 1, true},
+		{"two steps behind", code(current - 2), 0, false},
+		{"two steps ahead", code(current + 2), 0, false},
+		{"surrounding spaces", " " + code(current) + " ", current, true},
+		{"too short", code(current)[1:], 0, false},
+		{"wrong code", "000000", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(secret, tt.code, now)
+			if ok != tt.ok || step != tt.step {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.step, tt.ok)
+			}
+		})
+	}
+}
+
+// A code stays valid for the whole window, so callers refuse replays by the
+// step Validate returns. It has to be the step the code was made for, however
+// late in the window it comes in.
+func TestValidateReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	c, err := Code(secret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last int64
+	used := func(at time.Time) bool {
+		step, ok := Validate(secret, c, at)
+		if !ok || step <= last {
+			return false
+		}
+		last = step
+		return true
+	}
+
+	if !used(now) {
+		t.Fatal("the code was refused the first time")
+	}
+	for _, at := range []time.Time{now, now.Add(Period)} {
+		if used(at) {
+			t.Errorf("the code was accepted again at %v", at)
+		}
+	}
+
+	next, err := Code(secret, Step(now)+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step, ok := Validate(secret, next, now.Add(Period)); !ok || step <= last {
+		t.Errorf("the code of the next step was refused: %d, %v", step, ok)
+	}
+}
@@ -15,8 +15,11 @@ import (
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/nugrhrizki/buzz/pkg/metrics"
 	"github.com/nugrhrizki/buzz/pkg/utils"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/message"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
 	"github.com/rs/zerolog"
 	"github.com/vincent-petithory/dataurl"
@@ -26,6 +29,12 @@ type Api struct {
 	log      *zerolog.Logger
 	whatsapp *whatsapp.Whatsapp
 	users    *user.Repository
+	messages *message.Repository
+	apikeys  *apikey.Repository
+	metrics  *metrics.Metrics
+
+	// humanize decides per session whether messages are always humanized
+	humanize func(userID int) bool
 }
 
 func New(
@@ -34,18 +43,31 @@ func New(
 	whatsapp *whatsapp.Whatsapp,
 
 	users *user.Repository,
+	messages *message.Repository,
+	apikeys *apikey.Repository,
+	metrics *metrics.Metrics,
 ) *Api {
 	return &Api{
 		log:      log,
 		whatsapp: whatsapp,
 		users:    users,
+		messages: messages,
+		apikeys:  apikeys,
+		metrics:  metrics,
 	}
 }
 
+// OnHumanize lets decide turn on humanized typing for every message of a
+// session, otherwise only requests asking for it get it
+func (a *Api) OnHumanize(decide func(userID int) bool) {
+	a.humanize = decide
+}
+
 func (a *Api) CreateUser(payload *user.User) (*user.User, error) {
 	user := user.User{
-		Name:  payload.Name,
-		Token: payload.Token,
+		Name:    payload.Name,
+		Token:   payload.Token,
+		OwnerId: payload.OwnerId,
 	}
 
 	if err := a.users.CreateUser(&user); err != nil {
@@ -59,12 +81,23 @@ func (a *Api) GetUsers() ([]user.User, error) {
 	return a.users.GetUsers()
 }
 
+func (a *Api) GetUsersByOwner(ownerId int64) ([]user.User, error) {
+	return a.users.GetUsersByOwner(ownerId)
+}
+
 func (a *Api) GetUserById(id int) (*user.User, error) {
 	return a.users.GetUserById(id)
 }
 
 func (a *Api) DeleteUser(payload *user.User) error {
-	return a.users.DeleteUser(payload)
+	if err := a.users.DeleteUser(payload); err != nil {
+		return err
+	}
+
+	// Make sure the session cannot be used anymore through a cached token or key
+	a.whatsapp.DeleteCacheUserInfo(payload.Token)
+	a.metrics.ForgetSession(payload.Id)
+	return a.apikeys.RevokeApiKeys(payload.Id)
 }
 
 func (a *Api) UpdateUser(payload *user.User) error {
@@ -207,6 +240,39 @@ func (a *Api) SetWebhook(userInfo *user.UserInfo, payload *SetWebhookPayload) er
 	return nil
 }
 
+func (a *Api) GetCallPolicy(userInfo *user.UserInfo) (*CallPolicyPayload, error) {
+	userId, err := strconv.Atoi(userInfo.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.users.GetUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CallPolicyPayload{
+		Policy: user.CallPolicy,
+		Reply:  user.CallReply,
+	}, nil
+}
+
+func (a *Api) SetCallPolicy(userInfo *user.UserInfo, payload *CallPolicyPayload) error {
+	if !user.ValidCallPolicy(payload.Policy) {
+		return whatsapp.ErrInvalidCallPolicy
+	}
+
+	userId, err := strconv.Atoi(userInfo.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := a.users.SetCallPolicy(userId, payload.Policy, payload.Reply); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (a *Api) GetQR(userInfo *user.UserInfo) (string, error) {
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
@@ -293,7 +359,10 @@ func (a *Api) GetStatus(userInfo *user.UserInfo) (*GetStatusResponse, error) {
 	}, nil
 }
 
-func (a *Api) SendDocument(userInfo *user.UserInfo, payload *SendDocumentPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendDocument(ctx context.Context, userInfo *user.UserInfo, payload *SendDocumentPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendDocument", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -326,7 +395,7 @@ func (a *Api) SendDocument(userInfo *user.UserInfo, payload *SendDocumentPayload
 		}
 
 		filedata = dataURL.Data
-		uploaded, err = client.Upload(context.Background(), filedata, whatsmeow.MediaDocument)
+		uploaded, err = a.upload(ctx, client, filedata, whatsmeow.MediaDocument)
 		if err != nil {
 			return whatsmeow.SendResponse{}, fmt.Errorf("failed to upload file: %v", err)
 		}
@@ -353,10 +422,23 @@ func (a *Api) SendDocument(userInfo *user.UserInfo, payload *SendDocumentPayload
 		}
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.FileName, types.ChatPresenceMediaText)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userid, recipient, msg, resp, nil)
+	return resp, nil
 }
 
-func (a *Api) SendAudio(userInfo *user.UserInfo, payload *SendAudioPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendAudio(ctx context.Context, userInfo *user.UserInfo, payload *SendAudioPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendAudio", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, _ := strconv.Atoi(txtid)
 
@@ -389,7 +471,7 @@ func (a *Api) SendAudio(userInfo *user.UserInfo, payload *SendAudioPayload) (wha
 	}
 
 	filedata = dataURL.Data
-	uploaded, err = client.Upload(context.Background(), filedata, whatsmeow.MediaAudio)
+	uploaded, err = a.upload(ctx, client, filedata, whatsmeow.MediaAudio)
 	if err != nil {
 		return whatsmeow.SendResponse{}, fmt.Errorf("failed to upload file: %v", err)
 	}
@@ -417,10 +499,22 @@ func (a *Api) SendAudio(userInfo *user.UserInfo, payload *SendAudioPayload) (wha
 		}
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, "", types.ChatPresenceMediaAudio)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userid, recipient, msg, resp, nil)
+	return resp, nil
 }
 
-func (a *Api) SendImage(userInfo *user.UserInfo, payload *SendImagePayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendImage(ctx context.Context, userInfo *user.UserInfo, payload *SendImagePayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendImage", userInfo)
+	defer span.End()
 
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
@@ -457,7 +551,7 @@ func (a *Api) SendImage(userInfo *user.UserInfo, payload *SendImagePayload) (wha
 	}
 
 	filedata = dataURL.Data
-	uploaded, err = client.Upload(context.Background(), filedata, whatsmeow.MediaImage)
+	uploaded, err = a.upload(ctx, client, filedata, whatsmeow.MediaImage)
 	if err != nil {
 		return whatsmeow.SendResponse{}, fmt.Errorf("failed to upload file: %v", err)
 	}
@@ -481,10 +575,23 @@ func (a *Api) SendImage(userInfo *user.UserInfo, payload *SendImagePayload) (wha
 		}
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.Caption, types.ChatPresenceMediaText)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userid, recipient, msg, resp, nil)
+	return resp, nil
 }
 
-func (a *Api) SendSticker(userInfo *user.UserInfo, payload *SendStickerPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendSticker(ctx context.Context, userInfo *user.UserInfo, payload *SendStickerPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendSticker", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -520,7 +627,7 @@ func (a *Api) SendSticker(userInfo *user.UserInfo, payload *SendStickerPayload)
 	}
 
 	filedata = dataURL.Data
-	uploaded, err = client.Upload(context.Background(), filedata, whatsmeow.MediaImage)
+	uploaded, err = a.upload(ctx, client, filedata, whatsmeow.MediaImage)
 	if err != nil {
 		return whatsmeow.SendResponse{}, fmt.Errorf("failed to upload file: %v", err)
 	}
@@ -544,10 +651,23 @@ func (a *Api) SendSticker(userInfo *user.UserInfo, payload *SendStickerPayload)
 		}
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, "", types.ChatPresenceMediaText)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userid, recipient, msg, resp, nil)
+	return resp, nil
 }
 
-func (a *Api) SendVideo(userInfo *user.UserInfo, payload *SendVideoPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendVideo(ctx context.Context, userInfo *user.UserInfo, payload *SendVideoPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendVideo", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -582,7 +702,7 @@ func (a *Api) SendVideo(userInfo *user.UserInfo, payload *SendVideoPayload) (wha
 	}
 
 	filedata = dataURL.Data
-	uploaded, err = client.Upload(context.Background(), filedata, whatsmeow.MediaVideo)
+	uploaded, err = a.upload(ctx, client, filedata, whatsmeow.MediaVideo)
 	if err != nil {
 		return whatsmeow.SendResponse{}, fmt.Errorf("failed to upload file: %v", err)
 	}
@@ -607,10 +727,23 @@ func (a *Api) SendVideo(userInfo *user.UserInfo, payload *SendVideoPayload) (wha
 		}
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.Caption, types.ChatPresenceMediaText)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userid, recipient, msg, resp, nil)
+	return resp, nil
 }
 
-func (a *Api) SendContact(userInfo *user.UserInfo, payload *SendContactPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendContact(ctx context.Context, userInfo *user.UserInfo, payload *SendContactPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendContact", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -645,10 +778,23 @@ func (a *Api) SendContact(userInfo *user.UserInfo, payload *SendContactPayload)
 		return whatsmeow.SendResponse{}, err
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.Name, types.ChatPresenceMediaText)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userid, recipient, msg, resp, nil)
+	return resp, nil
 }
 
-func (a *Api) SendLocation(userInfo *user.UserInfo, payload *SendLocationPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendLocation(ctx context.Context, userInfo *user.UserInfo, payload *SendLocationPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendLocation", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -684,9 +830,22 @@ func (a *Api) SendLocation(userInfo *user.UserInfo, payload *SendLocationPayload
 		return whatsmeow.SendResponse{}, err
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.Name, types.ChatPresenceMediaText)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userid, recipient, msg, resp, nil)
+	return resp, nil
 }
-func (a *Api) SendButton(userInfo *user.UserInfo, payload *SendButtonTextPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendButton(ctx context.Context, userInfo *user.UserInfo, payload *SendButtonTextPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendButton", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -721,8 +880,13 @@ func (a *Api) SendButton(userInfo *user.UserInfo, payload *SendButtonTextPayload
 		return whatsmeow.SendResponse{}, err
 	}
 
-	return client.SendMessage(
-		context.Background(),
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.Title, types.ChatPresenceMediaText)
+	}
+
+	return a.sendMessage(
+		ctx,
+		client,
 		recipient,
 		&waProto.Message{
 			ViewOnceMessage: &waProto.FutureProofMessage{
@@ -733,7 +897,10 @@ func (a *Api) SendButton(userInfo *user.UserInfo, payload *SendButtonTextPayload
 		},
 	)
 }
-func (a *Api) SendList(userInfo *user.UserInfo, payload *SendListPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendList(ctx context.Context, userInfo *user.UserInfo, payload *SendListPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendList", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userid, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -783,8 +950,13 @@ func (a *Api) SendList(userInfo *user.UserInfo, payload *SendListPayload) (whats
 		return whatsmeow.SendResponse{}, err
 	}
 
-	return client.SendMessage(
-		context.Background(),
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.Description, types.ChatPresenceMediaText)
+	}
+
+	return a.sendMessage(
+		ctx,
+		client,
 		recipient,
 		&waProto.Message{
 			ViewOnceMessage: &waProto.FutureProofMessage{
@@ -796,7 +968,10 @@ func (a *Api) SendList(userInfo *user.UserInfo, payload *SendListPayload) (whats
 	)
 }
 
-func (a *Api) SendText(userInfo *user.UserInfo, payload *SendTextPayload) (whatsmeow.SendResponse, error) {
+func (a *Api) SendText(ctx context.Context, userInfo *user.UserInfo, payload *SendTextPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendText", userInfo)
+	defer span.End()
+
 	txtid := userInfo.Id
 	userId, err := strconv.Atoi(txtid)
 	if err != nil {
@@ -832,7 +1007,17 @@ func (a *Api) SendText(userInfo *user.UserInfo, payload *SendTextPayload) (whats
 		return whatsmeow.SendResponse{}, err
 	}
 
-	return client.SendMessage(context.Background(), recipient, msg)
+	if a.humanized(userInfo, payload.Humanize) {
+		a.simulateTyping(client, recipient, payload.Body, types.ChatPresenceMediaText)
+	}
+
+	resp, err := a.sendMessage(ctx, client, recipient, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	a.storeSentMessage(ctx, userId, recipient, msg, resp, payload.AgentId)
+	return resp, nil
 }
 
 func (a *Api) CheckUser(userInfo *user.UserInfo, payload *CheckUserPayload) (*UserCollection, error) {
@@ -993,3 +1178,49 @@ func (a *Api) SendChatPresence(userInfo *user.UserInfo, payload *ChatPresencePay
 		types.ChatPresenceMedia(payload.Media),
 	)
 }
+
+func (a *Api) MarkRead(userInfo *user.UserInfo, payload *MarkReadPayload) error {
+	txtid := userInfo.Id
+	userId, err := strconv.Atoi(txtid)
+	if err != nil {
+		return err
+	}
+
+	if len(payload.MessageIds) < 1 {
+		return whatsapp.ErrMissingMessageIds
+	}
+
+	client, err := a.whatsapp.GetClient(userId)
+	if err != nil {
+		return err
+	}
+
+	chat, ok := a.whatsapp.ParseJID(payload.Chat)
+	if !ok {
+		return whatsapp.ErrInvalidPhoneNumber
+	}
+
+	// Sender is only required for group chats, direct chats are read from the chat itself
+	sender := chat
+	if payload.Sender != "" {
+		sender, ok = a.whatsapp.ParseJID(payload.Sender)
+		if !ok {
+			return whatsapp.ErrInvalidPhoneNumber
+		}
+	}
+
+	ids := make([]types.MessageID, len(payload.MessageIds))
+	for i, id := range payload.MessageIds {
+		ids[i] = types.MessageID(id)
+	}
+
+	if err := client.MarkRead(ids, time.Now(), chat, sender); err != nil {
+		return err
+	}
+
+	if err := a.messages.MarkMessagesRead(userId, chat.String(), payload.MessageIds); err != nil {
+		a.log.Warn().Err(err).Str("chat", chat.String()).Msg("Could not mark stored messages read")
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+// CreateApiKey issues a new key for a session. The plaintext key is only part
+// of this response, afterwards only its prefix can be shown.
+func (a *Api) CreateApiKey(userId int, payload *CreateApiKeyPayload) (*CreateApiKeyResponse, error) {
+	if err := apikey.ValidScopes(payload.Scopes); err != nil {
+		return nil, err
+	}
+	if err := apikey.ValidIps(payload.AllowedIps); err != nil {
+		return nil, err
+	}
+
+	key, prefix, err := apikey.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := &apikey.ApiKey{
+		WhatsappUserId: userId,
+		Name:           payload.Name,
+		Prefix:         prefix,
+		Hash:           apikey.Hash(key),
+		Scopes:         strings.Join(payload.Scopes, ","),
+		AllowedIps:     strings.Join(payload.AllowedIps, ","),
+		ExpiresAt:      payload.ExpiresAt,
+	}
+
+	if err := a.apikeys.CreateApiKey(apiKey); err != nil {
+		return nil, err
+	}
+
+	return &CreateApiKeyResponse{ApiKey: apiKey, Key: key}, nil
+}
+
+func (a *Api) GetApiKeys(userId int) ([]apikey.ApiKey, error) {
+	return a.apikeys.GetApiKeys(userId)
+}
+
+func (a *Api) RevokeApiKey(userId int, id int64) error {
+	if err := a.apikeys.RevokeApiKey(userId, id); err != nil {
+		return err
+	}
+
+	// the cached session info goes with the key, so on this instance nothing
+	// about the session outlives it
+	if session, err := a.users.GetUserById(userId); err == nil {
+		a.whatsapp.DeleteCacheUserInfo(session.Token)
+	}
+	return nil
+}
+
+// Authenticate resolves an api key to the session it belongs to. Keys are
+// cached, a revoked key stops working at once on the instance that revoked it
+// and within five minutes on the others.
+func (a *Api) Authenticate(token string, ip string) (*user.UserInfo, *apikey.ApiKey, error) {
+	key, err := a.apikeys.GetApiKeyByHash(apikey.Hash(token))
+	if err != nil {
+		return nil, nil, apikey.ErrInvalidKey
+	}
+
+	if err := key.Check(ip); err != nil {
+		return nil, nil, err
+	}
+
+	if err := a.apikeys.Touch(key); err != nil {
+		a.log.Warn().Err(err).Str("prefix", key.Prefix).Msg("Could not record api key usage")
+	}
+
+	session, err := a.users.GetUserById(key.WhatsappUserId)
+	if err != nil {
+		return nil, nil, apikey.ErrInvalidKey
+	}
+
+	// The session token stays the cache key, the event handler looks the
+	// webhook up with it
+	userInfo, found := a.whatsapp.GetCacheUserInfo(session.Token)
+	if !found {
+		userInfo = a.whatsapp.UserToUserInfo(session)
+		a.whatsapp.UpdateCacheUserInfo(session.Token, userInfo)
+	}
+
+	if userInfo.Id != strconv.Itoa(key.WhatsappUserId) {
+		return nil, nil, apikey.ErrInvalidKey
+	}
+
+	return &userInfo, key, nil
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+// chatClient resolves the client and the target chat shared by every chat action
+func (a *Api) chatClient(userInfo *user.UserInfo, chat string) (int, *whatsmeow.Client, types.JID, error) {
+	userId, err := strconv.Atoi(userInfo.Id)
+	if err != nil {
+		return 0, nil, types.EmptyJID, err
+	}
+
+	client, err := a.whatsapp.GetClient(userId)
+	if err != nil {
+		return 0, nil, types.EmptyJID, err
+	}
+
+	jid, ok := a.whatsapp.ParseJID(chat)
+	if !ok {
+		return 0, nil, types.EmptyJID, whatsapp.ErrInvalidPhoneNumber
+	}
+
+	return userId, client, jid, nil
+}
+
+// messageRange describes the latest stored message of a chat, WhatsApp uses
+// it to know up to which message an archive, delete or clear applies
+func (a *Api) messageRange(userId int, chat types.JID) *waProto.SyncActionMessageRange {
+	last, err := a.messages.GetLastMessage(userId, chat.String())
+	if err != nil {
+		return &waProto.SyncActionMessageRange{
+			LastMessageTimestamp: proto.Int64(time.Now().Unix()),
+		}
+	}
+
+	key := &waProto.MessageKey{
+		RemoteJid: proto.String(chat.String()),
+		FromMe:    proto.Bool(last.FromMe),
+		Id:        proto.String(last.MessageId),
+	}
+	if chat.Server == types.GroupServer && !last.FromMe {
+		key.Participant = proto.String(last.Sender)
+	}
+
+	return &waProto.SyncActionMessageRange{
+		LastMessageTimestamp: proto.Int64(last.Timestamp.Unix()),
+		Messages: []*waProto.SyncActionMessage{{
+			Key:       key,
+			Timestamp: proto.Int64(last.Timestamp.Unix()),
+		}},
+	}
+}
+
+func boolIndex(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+func (a *Api) ArchiveChat(userInfo *user.UserInfo, payload *ArchiveChatPayload) error {
+	userId, client, jid, err := a.chatClient(userInfo, payload.Chat)
+	if err != nil {
+		return err
+	}
+
+	var lastTimestamp time.Time
+	var lastKey *waProto.MessageKey
+	messageRange := a.messageRange(userId, jid)
+	if len(messageRange.Messages) > 0 {
+		lastTimestamp = time.Unix(messageRange.GetLastMessageTimestamp(), 0)
+		lastKey = messageRange.Messages[0].Key
+	}
+
+	return client.SendAppState(appstate.BuildArchive(jid, payload.Archive, lastTimestamp, lastKey))
+}
+
+func (a *Api) PinChat(userInfo *user.UserInfo, payload *PinChatPayload) error {
+	_, client, jid, err := a.chatClient(userInfo, payload.Chat)
+	if err != nil {
+		return err
+	}
+
+	return client.SendAppState(appstate.BuildPin(jid, payload.Pin))
+}
+
+func (a *Api) MuteChat(userInfo *user.UserInfo, payload *MuteChatPayload) error {
+	_, client, jid, err := a.chatClient(userInfo, payload.Chat)
+	if err != nil {
+		return err
+	}
+
+	duration := time.Duration(payload.Duration) * time.Second
+	return client.SendAppState(appstate.BuildMute(jid, payload.Mute, duration))
+}
+
+func (a *Api) MarkChatUnread(userInfo *user.UserInfo, payload *ChatPayload) error {
+	userId, client, jid, err := a.chatClient(userInfo, payload.Chat)
+	if err != nil {
+		return err
+	}
+
+	return client.SendAppState(appstate.PatchInfo{
+		Type: appstate.WAPatchRegularLow,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexMarkChatAsRead, jid.String()},
+			Version: 3,
+			Value: &waProto.SyncActionValue{
+				MarkChatAsReadAction: &waProto.MarkChatAsReadAction{
+					Read:         proto.Bool(false),
+					MessageRange: a.messageRange(userId, jid),
+				},
+			},
+		}},
+	})
+}
+
+func (a *Api) DeleteChat(userInfo *user.UserInfo, payload *DeleteChatPayload) error {
+	userId, client, jid, err := a.chatClient(userInfo, payload.Chat)
+	if err != nil {
+		return err
+	}
+
+	return client.SendAppState(appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexDeleteChat, jid.String(), boolIndex(payload.DeleteMedia)},
+			Version: 6,
+			Value: &waProto.SyncActionValue{
+				DeleteChatAction: &waProto.DeleteChatAction{
+					MessageRange: a.messageRange(userId, jid),
+				},
+			},
+		}},
+	})
+}
+
+func (a *Api) ClearChat(userInfo *user.UserInfo, payload *ClearChatPayload) error {
+	userId, client, jid, err := a.chatClient(userInfo, payload.Chat)
+	if err != nil {
+		return err
+	}
+
+	return client.SendAppState(appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index: []string{
+				appstate.IndexClearChat,
+				jid.String(),
+				boolIndex(payload.DeleteStarred),
+				boolIndex(payload.DeleteMedia),
+			},
+			Version: 6,
+			Value: &waProto.SyncActionValue{
+				ClearChatAction: &waProto.ClearChatAction{
+					MessageRange: a.messageRange(userId, jid),
+				},
+			},
+		}},
+	})
+}
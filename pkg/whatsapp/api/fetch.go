@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("url should point to a public address")
+
+// maxRedirects is how many redirects a download follows
+const maxRedirects = 5
+
+// sharedAddresses is the carrier-grade NAT range, private in practice but
+// not covered by IsPrivate
+var sharedAddresses = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddress tells whether ip can be reached on the public internet
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() &&
+		!ip.IsPrivate() &&
+		!ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!sharedAddresses.Contains(ip)
+}
+
+// refuseInternal runs before every connection, once the host is resolved,
+// so names pointing at internal hosts and redirects to them are refused
+func refuseInternal(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addrPort.Addr()) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url should use http or https")
+	}
+	return nil
+}
+
+// downloadClient fetches urls given by callers. It only talks to public
+// addresses, without the environment's proxy, which would dial for it.
+var downloadClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: refuseInternal,
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errors.New("too many redirects")
+		}
+		return checkScheme(req.URL)
+	},
+}
+
+// download gets rawURL with downloadClient
+func download(ctx context.Context, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %v", err)
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return downloadClient.Do(req)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.public {
+				t.Errorf("publicAddress(%s) = %v, want %v", tt.addr, got, tt.public)
+			}
+		})
+	}
+}
+
+func TestDownloadRefusesInternalHosts(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer internal.Close()
+
+	if _, err := download(context.Background(), internal.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("download of a loopback url: got %v, want %v", err, ErrForbiddenAddress)
+	}
+	if _, err := download(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("download of a file url should fail")
+	}
+}
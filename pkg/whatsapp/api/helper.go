@@ -1,8 +1,23 @@
 package api
 
 import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nugrhrizki/buzz/pkg/metrics"
+	"github.com/nugrhrizki/buzz/pkg/telemetry"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/message"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func validateMessageFields(
@@ -36,3 +51,144 @@ func validateMessageFields(
 
 	return recipient, nil
 }
+
+var tracer = otel.Tracer("github.com/nugrhrizki/buzz/pkg/whatsapp/api")
+
+// startSpan traces an operation of a session
+func startSpan(ctx context.Context, name string, userInfo *user.UserInfo) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attribute.String("buzz.session", userInfo.Id)))
+}
+
+// sendMessage sends msg and counts it by type and outcome
+func (a *Api) sendMessage(
+	ctx context.Context,
+	client *whatsmeow.Client,
+	to types.JID,
+	msg *waProto.Message,
+	extra ...whatsmeow.SendRequestExtra,
+) (whatsmeow.SendResponse, error) {
+	kind, _ := whatsapp.MessageContent(msg)
+	ctx, span := tracer.Start(ctx, "whatsmeow.SendMessage", trace.WithAttributes(
+		attribute.String("buzz.message.type", kind),
+	))
+	resp, err := client.SendMessage(ctx, to, msg, extra...)
+	span.SetAttributes(attribute.String("buzz.message.id", resp.ID))
+	telemetry.End(span, err)
+	if err == nil {
+		a.whatsapp.TraceSent(resp.ID, span.SpanContext())
+	}
+
+	a.metrics.Message(metrics.Sent, kind, err)
+	return resp, err
+}
+
+// upload uploads the media of a message
+func (a *Api) upload(
+	ctx context.Context,
+	client *whatsmeow.Client,
+	data []byte,
+	mediaType whatsmeow.MediaType,
+) (whatsmeow.UploadResponse, error) {
+	ctx, span := tracer.Start(ctx, "whatsmeow.Upload", trace.WithAttributes(
+		attribute.String("buzz.media.type", string(mediaType)),
+		attribute.Int("buzz.media.size", len(data)),
+	))
+	uploaded, err := client.Upload(ctx, data, mediaType)
+	telemetry.End(span, err)
+	return uploaded, err
+}
+
+// uploadNewsletter uploads the media of a newsletter message, which is not
+// encrypted
+func (a *Api) uploadNewsletter(
+	ctx context.Context,
+	client *whatsmeow.Client,
+	data []byte,
+	mediaType whatsmeow.MediaType,
+) (whatsmeow.UploadResponse, error) {
+	ctx, span := tracer.Start(ctx, "whatsmeow.UploadNewsletter", trace.WithAttributes(
+		attribute.String("buzz.media.type", string(mediaType)),
+		attribute.Int("buzz.media.size", len(data)),
+	))
+	uploaded, err := client.UploadNewsletter(ctx, data, mediaType)
+	telemetry.End(span, err)
+	return uploaded, err
+}
+
+// storeSentMessage keeps a copy of an outgoing message so it shows up in the
+// inbox. Sends are not audited, the row already names the agent and
+// sendMessage counts them in the metrics.
+func (a *Api) storeSentMessage(
+	ctx context.Context,
+	userId int,
+	recipient types.JID,
+	msg *waProto.Message,
+	resp whatsmeow.SendResponse,
+	agentId *int64,
+) {
+	kind, body := whatsapp.MessageContent(msg)
+	err := a.messages.CreateMessage(ctx, &message.Message{
+		WhatsappUserId: userId,
+		MessageId:      resp.ID,
+		Chat:           recipient.String(),
+		FromMe:         true,
+		Type:           kind,
+		Body:           body,
+		AgentId:        agentId,
+		Read:           true,
+		Timestamp:      resp.Timestamp,
+	})
+	if err != nil {
+		a.log.Warn().Err(err).Str("id", resp.ID).Msg("Could not store sent message")
+	}
+}
+
+const (
+	typingBaseDelay = 1 * time.Second
+	typingCharDelay = 40 * time.Millisecond
+	typingMaxDelay  = 10 * time.Second
+)
+
+// typingDuration estimates how long a person would take to type text, with
+// some jitter so consecutive messages don't share the exact same delay
+func typingDuration(text string) time.Duration {
+	duration := typingBaseDelay + time.Duration(utf8.RuneCountInString(text))*typingCharDelay
+	jitter := time.Duration(rand.Int63n(int64(duration)/5 + 1))
+	duration = duration - duration/10 + jitter
+	if duration > typingMaxDelay {
+		return typingMaxDelay
+	}
+	return duration
+}
+
+// humanized tells whether a message gets the typing indicator, requested
+// by the payload or turned on for the session
+func (a *Api) humanized(userInfo *user.UserInfo, requested bool) bool {
+	if requested || a.humanize == nil {
+		return requested
+	}
+	id, err := strconv.Atoi(userInfo.Id)
+	return err == nil && a.humanize(id)
+}
+
+// simulateTyping shows the composing indicator to the recipient for as long
+// as it would take to type text, then pauses it before the message goes out
+func (a *Api) simulateTyping(
+	client *whatsmeow.Client,
+	recipient types.JID,
+	text string,
+	media types.ChatPresenceMedia,
+) {
+	err := client.SendChatPresence(recipient, types.ChatPresenceComposing, media)
+	if err != nil {
+		a.log.Warn().Err(err).Str("jid", recipient.String()).Msg("Could not send composing presence")
+		return
+	}
+
+	time.Sleep(typingDuration(text))
+
+	err = client.SendChatPresence(recipient, types.ChatPresencePaused, media)
+	if err != nil {
+		a.log.Warn().Err(err).Str("jid", recipient.String()).Msg("Could not send paused presence")
+	}
+}
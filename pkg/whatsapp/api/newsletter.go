@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+const newsletterInvitePrefix = "https://whatsapp.com/channel/"
+
+func (a *Api) parseNewsletterJID(arg string) (types.JID, error) {
+	jid, ok := a.whatsapp.ParseJID(arg)
+	if !ok || jid.Server != types.NewsletterServer {
+		return types.EmptyJID, whatsapp.ErrInvalidNewsletter
+	}
+	return jid, nil
+}
+
+func (a *Api) CreateNewsletter(userInfo *user.UserInfo, payload *CreateNewsletterPayload) (*types.NewsletterMetadata, error) {
+	if payload.Name == "" {
+		return nil, errors.New("newsletter name cannot be empty")
+	}
+
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	params := whatsmeow.CreateNewsletterParams{
+		Name:        payload.Name,
+		Description: payload.Description,
+	}
+
+	if payload.Picture != "" {
+		dataURL, err := dataurl.DecodeString(payload.Picture)
+		if err != nil {
+			return nil, errors.New("could not decode base64 encoded data from payload")
+		}
+		params.Picture = dataURL.Data
+	}
+
+	metadata, err := client.CreateNewsletter(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// GetNewsletterInfo looks a channel up by its jid or by its invite link
+func (a *Api) GetNewsletterInfo(userInfo *user.UserInfo, payload *NewsletterInfoPayload) (*types.NewsletterMetadata, error) {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Invite != "" {
+		key := strings.TrimPrefix(payload.Invite, newsletterInvitePrefix)
+		return client.GetNewsletterInfoWithInvite(key)
+	}
+
+	jid, err := a.parseNewsletterJID(payload.Jid)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetNewsletterInfo(jid)
+}
+
+func (a *Api) FollowNewsletter(userInfo *user.UserInfo, payload *NewsletterPayload) error {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return err
+	}
+
+	jid, err := a.parseNewsletterJID(payload.Jid)
+	if err != nil {
+		return err
+	}
+
+	if err := client.FollowNewsletter(jid); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *Api) UnfollowNewsletter(userInfo *user.UserInfo, payload *NewsletterPayload) error {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return err
+	}
+
+	jid, err := a.parseNewsletterJID(payload.Jid)
+	if err != nil {
+		return err
+	}
+
+	if err := client.UnfollowNewsletter(jid); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *Api) GetSubscribedNewsletters(userInfo *user.UserInfo) ([]*types.NewsletterMetadata, error) {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetSubscribedNewsletters()
+}
+
+// SendNewsletterMessage publishes an update to a channel. Channel media is not
+// end-to-end encrypted, so it is uploaded without a media key and referenced
+// by the handle the upload returns.
+func (a *Api) SendNewsletterMessage(ctx context.Context, userInfo *user.UserInfo, payload *SendNewsletterMessagePayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendNewsletterMessage", userInfo)
+	defer span.End()
+
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+
+	jid, err := a.parseNewsletterJID(payload.Jid)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+
+	media := payload.Image
+	mediaType := whatsmeow.MediaImage
+	if payload.Video != "" {
+		media = payload.Video
+		mediaType = whatsmeow.MediaVideo
+	}
+
+	if media == "" {
+		if payload.Text == "" {
+			return whatsmeow.SendResponse{}, whatsapp.ErrEmptyBody
+		}
+
+		msg := &waProto.Message{Conversation: proto.String(payload.Text)}
+		resp, err := a.sendMessage(ctx, client, jid, msg)
+		if err != nil {
+			return resp, err
+		}
+
+		return resp, nil
+	}
+
+	if !strings.HasPrefix(media, "data") {
+		return whatsmeow.SendResponse{}, errors.New("data should start with \"data:mime/type;base64,\"")
+	}
+
+	dataURL, err := dataurl.DecodeString(media)
+	if err != nil {
+		return whatsmeow.SendResponse{}, errors.New("could not decode base64 encoded data from payload")
+	}
+
+	filedata := dataURL.Data
+	uploaded, err := a.uploadNewsletter(ctx, client, filedata, mediaType)
+	if err != nil {
+		return whatsmeow.SendResponse{}, fmt.Errorf("failed to upload file: %v", err)
+	}
+
+	msg := &waProto.Message{}
+	if mediaType == whatsmeow.MediaVideo {
+		msg.VideoMessage = &waProto.VideoMessage{
+			Caption:    proto.String(payload.Caption),
+			Url:        proto.String(uploaded.URL),
+			DirectPath: proto.String(uploaded.DirectPath),
+			Mimetype:   proto.String(http.DetectContentType(filedata)),
+			FileSha256: uploaded.FileSHA256,
+			FileLength: proto.Uint64(uploaded.FileLength),
+		}
+	} else {
+		msg.ImageMessage = &waProto.ImageMessage{
+			Caption:    proto.String(payload.Caption),
+			Url:        proto.String(uploaded.URL),
+			DirectPath: proto.String(uploaded.DirectPath),
+			Mimetype:   proto.String(http.DetectContentType(filedata)),
+			FileSha256: uploaded.FileSHA256,
+			FileLength: proto.Uint64(uploaded.FileLength),
+		}
+	}
+
+	resp, err := a.sendMessage(ctx, client, jid, msg, whatsmeow.SendRequestExtra{
+		MediaHandle: uploaded.Handle,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+func (a *Api) GetNewsletterMessages(userInfo *user.UserInfo, payload *NewsletterMessagesPayload) ([]*types.NewsletterMessage, error) {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	jid, err := a.parseNewsletterJID(payload.Jid)
+	if err != nil {
+		return nil, err
+	}
+
+	count := payload.Count
+	if count <= 0 {
+		count = 20
+	}
+
+	return client.GetNewsletterMessages(jid, &whatsmeow.GetNewsletterMessagesParams{
+		Count:  count,
+		Before: types.MessageServerID(payload.Before),
+	})
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"time"
+
 	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/types"
+
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/apikey"
 )
 
 type User struct {
@@ -31,6 +35,12 @@ type ChatPresencePayload struct {
 	Media string `json:"media"`
 }
 
+type MarkReadPayload struct {
+	Chat       string   `json:"chat"`
+	Sender     string   `json:"sender"`
+	MessageIds []string `json:"message_ids"`
+}
+
 type CheckUserPayload struct {
 	Phone []string `json:"phone"`
 }
@@ -45,6 +55,7 @@ type SendDocumentPayload struct {
 	FileName    string              `json:"filename"`
 	Id          string              `json:"id"`
 	ContextInfo waProto.ContextInfo `json:"context_info"`
+	Humanize    bool                `json:"humanize"`
 }
 
 type SendAudioPayload struct {
@@ -53,6 +64,7 @@ type SendAudioPayload struct {
 	Caption     string              `json:"caption"`
 	Id          string              `json:"id"`
 	ContextInfo waProto.ContextInfo `json:"context_info"`
+	Humanize    bool                `json:"humanize"`
 }
 
 type SendImagePayload struct {
@@ -61,6 +73,7 @@ type SendImagePayload struct {
 	Caption     string              `json:"caption"`
 	Id          string              `json:"id"`
 	ContextInfo waProto.ContextInfo `json:"context_info"`
+	Humanize    bool                `json:"humanize"`
 }
 
 type SendStickerPayload struct {
@@ -69,6 +82,7 @@ type SendStickerPayload struct {
 	Id           string              `json:"id"`
 	PngThumbnail []byte              `json:"png_thumbnail"`
 	ContextInfo  waProto.ContextInfo `json:"context_info"`
+	Humanize     bool                `json:"humanize"`
 }
 
 type SendVideoPayload struct {
@@ -78,6 +92,7 @@ type SendVideoPayload struct {
 	Id            string              `json:"id"`
 	JpegThumbnail []byte              `json:"jpeg_thumbnail"`
 	ContextInfo   waProto.ContextInfo `json:"context_info"`
+	Humanize      bool                `json:"humanize"`
 }
 
 type SendContactPayload struct {
@@ -86,6 +101,7 @@ type SendContactPayload struct {
 	Name        string              `json:"name"`
 	Vcard       string              `json:"vcard"`
 	ContextInfo waProto.ContextInfo `json:"context_info"`
+	Humanize    bool                `json:"humanize"`
 }
 
 type SendLocationPayload struct {
@@ -95,6 +111,7 @@ type SendLocationPayload struct {
 	Latitude    float64             `json:"latitude"`
 	Longitude   float64             `json:"longitude"`
 	ContextInfo waProto.ContextInfo `json:"context_info"`
+	Humanize    bool                `json:"humanize"`
 }
 
 type SendButtonPayload struct {
@@ -103,10 +120,11 @@ type SendButtonPayload struct {
 }
 
 type SendButtonTextPayload struct {
-	Phone   string              `json:"phone"`
-	Title   string              `json:"title"`
-	Buttons []SendButtonPayload `json:"buttons"`
-	Id      string              `json:"id"`
+	Phone    string              `json:"phone"`
+	Title    string              `json:"title"`
+	Buttons  []SendButtonPayload `json:"buttons"`
+	Id       string              `json:"id"`
+	Humanize bool                `json:"humanize"`
 }
 
 type SendListRowPayload struct {
@@ -128,6 +146,7 @@ type SendListPayload struct {
 	FooterText  string                   `json:"footer_text"`
 	Sections    []SendListSectionPayload `json:"sections"`
 	Id          string                   `json:"id"`
+	Humanize    bool                     `json:"humanize"`
 }
 
 type SendTextPayload struct {
@@ -135,6 +154,8 @@ type SendTextPayload struct {
 	Body        string              `json:"body"`
 	Id          string              `json:"id"`
 	ContextInfo waProto.ContextInfo `json:"context_info"`
+	AgentId     *int64              `json:"-"`
+	Humanize    bool                `json:"humanize"`
 }
 
 type GetAvatarPayload struct {
@@ -147,7 +168,117 @@ type GetStatusResponse struct {
 	LoggedIn  bool `json:"logged_in"`
 }
 
+type CreateApiKeyPayload struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIps []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type CreateApiKeyResponse struct {
+	*apikey.ApiKey
+	Key string `json:"key"`
+}
+
+type CallPolicyPayload struct {
+	Policy string `json:"policy"`
+	Reply  string `json:"reply"`
+}
+
 type GetWebhookResponse struct {
 	Webhook   string   `json:"webhook"`
 	Subscribe []string `json:"subscribe"`
 }
+
+type ChatPayload struct {
+	Chat string `json:"chat"`
+}
+
+type ArchiveChatPayload struct {
+	Chat    string `json:"chat"`
+	Archive bool   `json:"archive"`
+}
+
+type PinChatPayload struct {
+	Chat string `json:"chat"`
+	Pin  bool   `json:"pin"`
+}
+
+type MuteChatPayload struct {
+	Chat     string `json:"chat"`
+	Mute     bool   `json:"mute"`
+	Duration int64  `json:"duration"`
+}
+
+type DeleteChatPayload struct {
+	Chat        string `json:"chat"`
+	DeleteMedia bool   `json:"delete_media"`
+}
+
+type ClearChatPayload struct {
+	Chat          string `json:"chat"`
+	DeleteStarred bool   `json:"delete_starred"`
+	DeleteMedia   bool   `json:"delete_media"`
+}
+
+type SetPushNamePayload struct {
+	Name string `json:"name"`
+}
+
+type SetAboutPayload struct {
+	About string `json:"about"`
+}
+
+type SetProfilePicturePayload struct {
+	Image  string `json:"image"`
+	URL    string `json:"url"`
+	Remove bool   `json:"remove"`
+}
+
+type SetPrivacyPayload struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type BlocklistPayload struct {
+	Phone string `json:"phone"`
+}
+
+type SendStatusPayload struct {
+	Text            string   `json:"text"`
+	BackgroundColor string   `json:"background_color"`
+	Font            string   `json:"font"`
+	Image           string   `json:"image"`
+	Video           string   `json:"video"`
+	Caption         string   `json:"caption"`
+	Recipients      []string `json:"recipients"`
+}
+
+type CreateNewsletterPayload struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Picture     string `json:"picture"`
+}
+
+type NewsletterPayload struct {
+	Jid string `json:"jid"`
+}
+
+type NewsletterInfoPayload struct {
+	Jid    string `json:"jid"`
+	Invite string `json:"invite"`
+}
+
+type SendNewsletterMessagePayload struct {
+	Jid     string `json:"jid"`
+	Text    string `json:"text"`
+	Image   string `json:"image"`
+	Video   string `json:"video"`
+	Caption string `json:"caption"`
+}
+
+type NewsletterMessagesPayload struct {
+	Jid    string `json:"jid"`
+	Count  int    `json:"count"`
+	Before int    `json:"before"`
+}
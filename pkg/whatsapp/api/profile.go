@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/vincent-petithory/dataurl"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+const maxProfilePictureSize = 5 << 20
+
+var privacySettingValues = map[types.PrivacySettingType][]types.PrivacySetting{
+	types.PrivacySettingTypeLastSeen: {
+		types.PrivacySettingAll,
+		types.PrivacySettingContacts,
+		types.PrivacySettingContactBlacklist,
+		types.PrivacySettingNone,
+	},
+	types.PrivacySettingTypeProfile: {
+		types.PrivacySettingAll,
+		types.PrivacySettingContacts,
+		types.PrivacySettingContactBlacklist,
+		types.PrivacySettingNone,
+	},
+	types.PrivacySettingTypeStatus: {
+		types.PrivacySettingAll,
+		types.PrivacySettingContacts,
+		types.PrivacySettingContactBlacklist,
+		types.PrivacySettingNone,
+	},
+	types.PrivacySettingTypeReadReceipts: {
+		types.PrivacySettingAll,
+		types.PrivacySettingNone,
+	},
+	types.PrivacySettingTypeGroupAdd: {
+		types.PrivacySettingAll,
+		types.PrivacySettingContacts,
+		types.PrivacySettingContactBlacklist,
+		types.PrivacySettingNone,
+	},
+}
+
+func (a *Api) loggedInClient(userInfo *user.UserInfo) (*whatsmeow.Client, error) {
+	userId, err := strconv.Atoi(userInfo.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := a.whatsapp.GetClient(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.IsLoggedIn() {
+		return nil, whatsapp.ErrNotLoggedIn
+	}
+
+	return client, nil
+}
+
+func (a *Api) SetPushName(userInfo *user.UserInfo, payload *SetPushNamePayload) error {
+	if payload.Name == "" {
+		return whatsapp.ErrEmptyBody
+	}
+
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SendAppState(appstate.BuildSettingPushName(payload.Name)); err != nil {
+		return err
+	}
+
+	client.Store.PushName = payload.Name
+	if err := client.Store.Save(); err != nil {
+		a.log.Warn().Err(err).Msg("Could not save push name to device store")
+	}
+
+	// Outgoing messages carry the push name of the last available presence
+	if err := client.SendPresence(types.PresenceAvailable); err != nil {
+		a.log.Warn().Err(err).Msg("Failed to send available presence")
+	}
+
+	return nil
+}
+
+func (a *Api) SetAbout(userInfo *user.UserInfo, payload *SetAboutPayload) error {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SetStatusMessage(payload.About); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// profilePicture reads the picture either from the data url or by downloading it
+func profilePicture(ctx context.Context, payload *SetProfilePicturePayload) ([]byte, error) {
+	if payload.Image != "" {
+		dataURL, err := dataurl.DecodeString(payload.Image)
+		if err != nil {
+			return nil, errors.New("could not decode base64 encoded data from payload")
+		}
+		return dataURL.Data, nil
+	}
+
+	if payload.URL == "" {
+		return nil, errors.New("either image or url should be provided")
+	}
+
+	resp, err := download(ctx, payload.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download picture: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download picture: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProfilePictureSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download picture: %v", err)
+	}
+
+	if len(data) > maxProfilePictureSize {
+		return nil, errors.New("picture should not be larger than 5MB")
+	}
+
+	return data, nil
+}
+
+// SetProfilePicture changes the picture of the session's own account, an
+// empty payload with remove set clears it. WhatsApp expects a square JPEG.
+func (a *Api) SetProfilePicture(ctx context.Context, userInfo *user.UserInfo, payload *SetProfilePicturePayload) (string, error) {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return "", err
+	}
+
+	var content interface{}
+	if !payload.Remove {
+		picture, err := profilePicture(ctx, payload)
+		if err != nil {
+			return "", err
+		}
+
+		if http.DetectContentType(picture) != "image/jpeg" {
+			return "", errors.New("profile picture should be a jpeg image")
+		}
+
+		content = []waBinary.Node{{
+			Tag:     "picture",
+			Attrs:   waBinary.Attrs{"type": "image"},
+			Content: picture,
+		}}
+	}
+
+	resp, err := client.DangerousInternals().SendIQ(whatsmeow.DangerousInfoQuery{
+		Namespace: "w:profile:picture",
+		Type:      "set",
+		To:        types.ServerJID,
+		Content:   content,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var pictureId string
+	if node, ok := resp.GetOptionalChildByTag("picture"); ok {
+		pictureId, _ = node.Attrs["id"].(string)
+	}
+
+	return pictureId, nil
+}
+
+func (a *Api) GetPrivacySettings(userInfo *user.UserInfo) (*types.PrivacySettings, error) {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.TryFetchPrivacySettings(true)
+}
+
+func (a *Api) SetPrivacySetting(userInfo *user.UserInfo, payload *SetPrivacyPayload) (*types.PrivacySettings, error) {
+	name := types.PrivacySettingType(payload.Name)
+	value := types.PrivacySetting(payload.Value)
+
+	values, ok := privacySettingValues[name]
+	if !ok {
+		return nil, whatsapp.ErrInvalidPrivacySetting
+	}
+
+	valid := false
+	for _, v := range values {
+		if v == value {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, whatsapp.ErrInvalidPrivacyValue
+	}
+
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := client.SetPrivacySetting(name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (a *Api) GetBlocklist(userInfo *user.UserInfo) (*types.Blocklist, error) {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetBlocklist()
+}
+
+func (a *Api) updateBlocklist(userInfo *user.UserInfo, phone string, action events.BlocklistChangeAction) (*types.Blocklist, error) {
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	jid, ok := a.whatsapp.ParseJID(phone)
+	if !ok {
+		return nil, whatsapp.ErrInvalidPhoneNumber
+	}
+
+	blocklist, err := client.UpdateBlocklist(jid, action)
+	if err != nil {
+		return nil, err
+	}
+
+	return blocklist, nil
+}
+
+func (a *Api) Block(userInfo *user.UserInfo, payload *BlocklistPayload) (*types.Blocklist, error) {
+	return a.updateBlocklist(userInfo, payload.Phone, events.BlocklistChangeActionBlock)
+}
+
+func (a *Api) Unblock(userInfo *user.UserInfo, payload *BlocklistPayload) (*types.Blocklist, error) {
+	return a.updateBlocklist(userInfo, payload.Phone, events.BlocklistChangeActionUnblock)
+}
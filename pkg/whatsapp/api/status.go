@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nugrhrizki/buzz/pkg/whatsapp"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+// parseColor turns #RRGGBB or #AARRGGBB into the ARGB value used by text statuses
+func parseColor(color string) (uint32, error) {
+	hex := strings.TrimPrefix(color, "#")
+	switch len(hex) {
+	case 6:
+		hex = "ff" + hex
+	case 8:
+	default:
+		return 0, whatsapp.ErrInvalidColor
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, whatsapp.ErrInvalidColor
+	}
+	return uint32(value), nil
+}
+
+func parseFont(font string) (waProto.ExtendedTextMessage_FontType, error) {
+	value, ok := waProto.ExtendedTextMessage_FontType_value[strings.ToUpper(font)]
+	if !ok {
+		return 0, whatsapp.ErrInvalidFont
+	}
+	return waProto.ExtendedTextMessage_FontType(value), nil
+}
+
+// statusAudience lists who would receive a status with the current status
+// privacy, mirroring how whatsmeow picks the broadcast recipients
+func statusAudience(client *whatsmeow.Client) ([]types.JID, error) {
+	privacy, err := client.GetStatusPrivacy()
+	if err != nil {
+		return nil, err
+	}
+
+	if privacy[0].Type == types.StatusPrivacyTypeWhitelist {
+		return privacy[0].List, nil
+	}
+
+	excluded := make(map[types.JID]bool)
+	if privacy[0].Type == types.StatusPrivacyTypeBlacklist {
+		for _, jid := range privacy[0].List {
+			excluded[jid] = true
+		}
+	}
+
+	contacts, err := client.Store.Contacts.GetAllContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	audience := make([]types.JID, 0, len(contacts))
+	for jid := range contacts {
+		if !excluded[jid] {
+			audience = append(audience, jid)
+		}
+	}
+	return audience, nil
+}
+
+// checkAllowlist makes sure a status can't reach anyone outside the allowlist.
+// WhatsApp always broadcasts statuses to the audience picked in the status
+// privacy settings, so the post is refused instead of being narrowed down.
+func (a *Api) checkAllowlist(client *whatsmeow.Client, recipients []string) error {
+	allowed := make(map[string]bool)
+	for _, recipient := range recipients {
+		jid, ok := a.whatsapp.ParseJID(recipient)
+		if !ok {
+			return whatsapp.ErrInvalidPhoneNumber
+		}
+		allowed[jid.User] = true
+	}
+
+	audience, err := statusAudience(client)
+	if err != nil {
+		return fmt.Errorf("failed to get status audience: %v", err)
+	}
+
+	outside := 0
+	for _, jid := range audience {
+		if !allowed[jid.User] {
+			outside++
+		}
+	}
+
+	if outside > 0 {
+		return fmt.Errorf("%w: %d contacts outside of it would see this status", whatsapp.ErrStatusAllowlist, outside)
+	}
+	return nil
+}
+
+func (a *Api) statusMessage(ctx context.Context, client *whatsmeow.Client, payload *SendStatusPayload) (*waProto.Message, error) {
+	media := payload.Image
+	mediaType := whatsmeow.MediaImage
+	if payload.Video != "" {
+		media = payload.Video
+		mediaType = whatsmeow.MediaVideo
+	}
+
+	if media == "" {
+		if payload.Text == "" {
+			return nil, whatsapp.ErrEmptyBody
+		}
+
+		text := &waProto.ExtendedTextMessage{
+			Text: proto.String(payload.Text),
+		}
+
+		if payload.BackgroundColor != "" {
+			color, err := parseColor(payload.BackgroundColor)
+			if err != nil {
+				return nil, err
+			}
+			text.BackgroundArgb = proto.Uint32(color)
+		}
+
+		if payload.Font != "" {
+			font, err := parseFont(payload.Font)
+			if err != nil {
+				return nil, err
+			}
+			text.Font = font.Enum()
+		}
+
+		return &waProto.Message{ExtendedTextMessage: text}, nil
+	}
+
+	if !strings.HasPrefix(media, "data") {
+		return nil, errors.New("data should start with \"data:mime/type;base64,\"")
+	}
+
+	dataURL, err := dataurl.DecodeString(media)
+	if err != nil {
+		return nil, errors.New("could not decode base64 encoded data from payload")
+	}
+
+	filedata := dataURL.Data
+	uploaded, err := a.upload(ctx, client, filedata, mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %v", err)
+	}
+
+	if mediaType == whatsmeow.MediaVideo {
+		return &waProto.Message{VideoMessage: &waProto.VideoMessage{
+			Caption:       proto.String(payload.Caption),
+			Url:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			Mimetype:      proto.String(http.DetectContentType(filedata)),
+			FileEncSha256: uploaded.FileEncSHA256,
+			FileSha256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uint64(len(filedata))),
+		}}, nil
+	}
+
+	return &waProto.Message{ImageMessage: &waProto.ImageMessage{
+		Caption:       proto.String(payload.Caption),
+		Url:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		MediaKey:      uploaded.MediaKey,
+		Mimetype:      proto.String(http.DetectContentType(filedata)),
+		FileEncSha256: uploaded.FileEncSHA256,
+		FileSha256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uint64(len(filedata))),
+	}}, nil
+}
+
+// SendStatus publishes a text, image or video status to status@broadcast
+func (a *Api) SendStatus(ctx context.Context, userInfo *user.UserInfo, payload *SendStatusPayload) (whatsmeow.SendResponse, error) {
+	ctx, span := startSpan(ctx, "SendStatus", userInfo)
+	defer span.End()
+
+	client, err := a.loggedInClient(userInfo)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+
+	if len(payload.Recipients) > 0 {
+		if err := a.checkAllowlist(client, payload.Recipients); err != nil {
+			return whatsmeow.SendResponse{}, err
+		}
+	}
+
+	msg, err := a.statusMessage(ctx, client, payload)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+
+	resp, err := a.sendMessage(ctx, client, types.StatusBroadcastJID, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,158 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// Keys look like buzz_<prefix>_<secret>. Only the sha256 of the whole key is
+// stored, the prefix is kept in clear so a key can be recognised in a list.
+const (
+	KeyPrefix    = "buzz_"
+	prefixLength = 8
+	secretLength = 32
+)
+
+const (
+	ScopeSend  = "send"
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
+var Scopes = []string{ScopeSend, ScopeRead, ScopeAdmin}
+
+var (
+	ErrInvalidScope  = errors.New("scope should be one of send, read or admin")
+	ErrInvalidIP     = errors.New("allowed ips should be ip addresses or cidr ranges")
+	ErrInvalidKey    = errors.New("invalid api key")
+	ErrKeyExpired    = errors.New("api key has expired")
+	ErrKeyRevoked    = errors.New("api key has been revoked")
+	ErrIPNotAllowed  = errors.New("api key is not allowed from this ip")
+	ErrMissingScopes = errors.New("api key needs at least one scope")
+)
+
+type ApiKey struct {
+	Id             int64      `db:"id"               json:"id"`
+	WhatsappUserId int        `db:"whatsapp_user_id" json:"whatsapp_user_id"`
+	Name           string     `db:"name"             json:"name"`
+	Prefix         string     `db:"prefix"           json:"prefix"`
+	Hash           string     `db:"hash"             json:"-"`
+	Scopes         string     `db:"scopes"           json:"scopes"`
+	AllowedIps     string     `db:"allowed_ips"      json:"allowed_ips"`
+	ExpiresAt      *time.Time `db:"expires_at"       json:"expires_at"`
+	LastUsedAt     *time.Time `db:"last_used_at"     json:"last_used_at"`
+	RevokedAt      *time.Time `db:"revoked_at"       json:"revoked_at"`
+	CreatedAt      time.Time  `db:"created_at"       json:"created_at"`
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
+
+// Generate returns a new plaintext key together with its visible prefix
+func Generate() (key string, prefix string, err error) {
+	buf := make([]byte, prefixLength/2+secretLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	prefix = KeyPrefix + hex.EncodeToString(buf[:prefixLength/2])
+	key = prefix + "_" + hex.EncodeToString(buf[prefixLength/2:])
+	return key, prefix, nil
+}
+
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func ValidScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrMissingScopes
+	}
+
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeSend, ScopeRead, ScopeAdmin:
+		default:
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+func ValidIps(ips []string) error {
+	for _, ip := range ips {
+		if _, _, err := net.ParseCIDR(ip); err == nil {
+			continue
+		}
+		if net.ParseIP(ip) == nil {
+			return ErrInvalidIP
+		}
+	}
+	return nil
+}
+
+func split(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// HasScope reports whether the key grants scope, admin grants every scope
+func (k *ApiKey) HasScope(scope string) bool {
+	for _, s := range split(k.Scopes) {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP reports whether ip may use the key, an empty allowlist allows all
+func (k *ApiKey) AllowsIP(ip string) bool {
+	allowed := split(k.AllowedIps)
+	if len(allowed) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowedIp := net.ParseIP(entry); allowedIp != nil && allowedIp.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check tells why a key cannot be used right now, if it cannot
+func (k *ApiKey) Check(ip string) error {
+	if k.RevokedAt != nil {
+		return ErrKeyRevoked
+	}
+	if k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt) {
+		return ErrKeyExpired
+	}
+	if !k.AllowsIP(ip) {
+		return ErrIPNotAllowed
+	}
+	return nil
+}
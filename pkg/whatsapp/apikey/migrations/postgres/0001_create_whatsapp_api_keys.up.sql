@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS whatsapp_api_keys (
+	id BIGSERIAL PRIMARY KEY,
+	whatsapp_user_id BIGINT NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	prefix TEXT NOT NULL,
+	hash TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	allowed_ips TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS whatsapp_api_keys_hash_uindex ON whatsapp_api_keys (hash);
+CREATE INDEX IF NOT EXISTS whatsapp_api_keys_user_index ON whatsapp_api_keys (whatsapp_user_id);
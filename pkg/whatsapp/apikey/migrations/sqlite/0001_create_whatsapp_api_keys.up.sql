@@ -0,0 +1,16 @@
+CREATE TABLE whatsapp_api_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	whatsapp_user_id BIGINT NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	prefix TEXT NOT NULL,
+	hash TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	allowed_ips TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX whatsapp_api_keys_hash_uindex ON whatsapp_api_keys (hash);
+CREATE INDEX whatsapp_api_keys_user_index ON whatsapp_api_keys (whatsapp_user_id);
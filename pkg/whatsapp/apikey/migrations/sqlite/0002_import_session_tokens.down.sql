@@ -0,0 +1 @@
+DELETE FROM whatsapp_api_keys WHERE name = 'Session token' AND prefix LIKE '%****';
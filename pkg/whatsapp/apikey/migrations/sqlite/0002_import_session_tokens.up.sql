@@ -0,0 +1,13 @@
+-- Session tokens used to be accepted as they are. The ones that exist carry
+-- on as keys with every scope, which can be revoked like any other key.
+-- sha256 is registered by the database package.
+INSERT INTO whatsapp_api_keys (whatsapp_user_id, name, prefix, hash, scopes)
+SELECT
+	id,
+	'Session token',
+	CASE WHEN length(token) > 8 THEN substr(token, 1, 4) || '****' ELSE '****' END,
+	sha256(token),
+	'admin'
+FROM whatsapp_users
+WHERE token <> ''
+ON CONFLICT (hash) DO NOTHING;
@@ -0,0 +1,139 @@
+package apikey
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+)
+
+// keyCacheTTL bounds how long a revoked key keeps working on the instances
+// that did not revoke it, they only see the revocation once their copy expires
+const keyCacheTTL = 5 * time.Minute
+
+// lastUsedInterval keeps last-used tracking from writing on every request
+const lastUsedInterval = time.Minute
+
+type Repository struct {
+	db      *database.Database
+	keys    *cache.Cache
+	touched *cache.Cache
+}
+
+func NewRepository(db *database.Database) *Repository {
+	return &Repository{
+		db:      db,
+		keys:    cache.New(keyCacheTTL, 10*time.Minute),
+		touched: cache.New(lastUsedInterval, 10*time.Minute),
+	}
+}
+
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "whatsapp_apikey", migrations
+}
+
+func (r *Repository) CreateApiKey(key *ApiKey) error {
+	return r.db.Get(
+		key,
+		`INSERT INTO whatsapp_api_keys
+			(whatsapp_user_id, name, prefix, hash, scopes, allowed_ips, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *`,
+		key.WhatsappUserId,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		key.Scopes,
+		key.AllowedIps,
+		key.ExpiresAt,
+	)
+}
+
+func (r *Repository) GetApiKeys(userId int) ([]ApiKey, error) {
+	keys := []ApiKey{}
+	err := r.db.Select(
+		&keys,
+		"SELECT * FROM whatsapp_api_keys WHERE whatsapp_user_id = $1 ORDER BY id DESC",
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetApiKeyByHash looks a key up by the hash of its plaintext. Keys are cached
+// for keyCacheTTL, RevokeApiKey only drops them from the cache of this instance.
+func (r *Repository) GetApiKeyByHash(hash string) (*ApiKey, error) {
+	if key, found := r.keys.Get(hash); found {
+		return key.(*ApiKey), nil
+	}
+
+	var key ApiKey
+	err := r.db.Get(
+		&key,
+		"SELECT * FROM whatsapp_api_keys WHERE hash = $1",
+		hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.keys.SetDefault(hash, &key)
+	return &key, nil
+}
+
+func (r *Repository) RevokeApiKey(userId int, id int64) error {
+	var hash string
+	err := r.db.Get(
+		&hash,
+		`UPDATE whatsapp_api_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND whatsapp_user_id = $2 AND revoked_at IS NULL
+		RETURNING hash`,
+		id,
+		userId,
+	)
+	if err != nil {
+		return err
+	}
+
+	r.keys.Delete(hash)
+	return nil
+}
+
+// RevokeApiKeys revokes every key of a session, used when the session goes away
+func (r *Repository) RevokeApiKeys(userId int) error {
+	var hashes []string
+	err := r.db.Select(
+		&hashes,
+		`UPDATE whatsapp_api_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE whatsapp_user_id = $1 AND revoked_at IS NULL
+		RETURNING hash`,
+		userId,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		r.keys.Delete(hash)
+	}
+	return nil
+}
+
+func (r *Repository) Touch(key *ApiKey) error {
+	if err := r.touched.Add(key.Hash, true, cache.DefaultExpiration); err != nil {
+		// Already recorded within the last interval
+		return nil
+	}
+
+	_, err := r.db.Exec(
+		"UPDATE whatsapp_api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1",
+		key.Id,
+	)
+	return err
+}
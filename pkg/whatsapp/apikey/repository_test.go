@@ -0,0 +1,133 @@
+package apikey
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+func TestRepository(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		keys := NewRepository(db)
+		databasetest.Migrate(t, db, user.NewRepository(db, databasetest.Logger()), keys)
+
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+		created := []*ApiKey{
+			{WhatsappUserId: 1, Name: "send", Prefix: "bz_1", Hash: Hash("one"), Scopes: ScopeSend, AllowedIps: "10.0.0.0/8", ExpiresAt: &expires},
+			{WhatsappUserId: 1, Name: "read", Prefix: "bz_2", Hash: Hash("two"), Scopes: ScopeRead},
+			{WhatsappUserId: 2, Name: "other", Prefix: "bz_3", Hash: Hash("three"), Scopes: ScopeAdmin},
+		}
+		for _, key := range created {
+			if err := keys.CreateApiKey(key); err != nil {
+				t.Fatalf("create %s: %v", key.Name, err)
+			}
+			if key.Id == 0 || key.CreatedAt.IsZero() {
+				t.Errorf("created %s key was not returned: %+v", key.Name, key)
+			}
+		}
+		if err := keys.CreateApiKey(&ApiKey{WhatsappUserId: 1, Prefix: "bz_4", Hash: Hash("one"), Scopes: ScopeSend}); err == nil {
+			t.Error("creating a key with a used hash should fail")
+		}
+
+		found, err := keys.GetApiKeyByHash(Hash("one"))
+		if err != nil {
+			t.Fatalf("get by hash: %v", err)
+		}
+		if found.Id != created[0].Id || found.ExpiresAt == nil || !found.ExpiresAt.Equal(expires) {
+			t.Errorf("get by hash: got %+v", found)
+		}
+
+		listed, err := keys.GetApiKeys(1)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(listed) != 2 || listed[0].Name != "read" || listed[1].Name != "send" {
+			t.Errorf("keys of session 1: got %+v", listed)
+		}
+
+		if err := keys.Touch(found); err != nil {
+			t.Fatalf("touch: %v", err)
+		}
+		if listed, _ := keys.GetApiKeys(1); listed[1].LastUsedAt == nil {
+			t.Error("touch did not record the last use")
+		}
+
+		revokes := []struct {
+			name    string
+			session int
+			id      int64
+			err     error
+		}{
+			{"of another session", 2, created[0].Id, sql.ErrNoRows},
+			{"own", 1, created[0].Id, nil},
+			{"twice", 1, created[0].Id, sql.ErrNoRows},
+		}
+		for _, tt := range revokes {
+			if err := keys.RevokeApiKey(tt.session, tt.id); !errors.Is(err, tt.err) {
+				t.Errorf("revoke %s: got %v, want %v", tt.name, err, tt.err)
+			}
+		}
+
+		// the cached key has to be dropped on revocation
+		revoked, err := keys.GetApiKeyByHash(Hash("one"))
+		if err != nil {
+			t.Fatalf("get revoked: %v", err)
+		}
+		if !errors.Is(revoked.Check("10.1.2.3"), ErrKeyRevoked) {
+			t.Errorf("revoked key: got %v, want %v", revoked.Check("10.1.2.3"), ErrKeyRevoked)
+		}
+
+		if err := keys.RevokeApiKeys(1); err != nil {
+			t.Fatalf("revoke all: %v", err)
+		}
+		for _, key := range created {
+			stored, err := keys.GetApiKeyByHash(key.Hash)
+			if err != nil {
+				t.Fatalf("get %s: %v", key.Name, err)
+			}
+			if (stored.RevokedAt != nil) != (key.WhatsappUserId == 1) {
+				t.Errorf("key %s of session %d: revoked at %v", key.Name, key.WhatsappUserId, stored.RevokedAt)
+			}
+		}
+	})
+}
+
+// Sessions used to authenticate with their raw token, migrating turns those
+// into admin keys so they keep working
+func TestImportSessionTokens(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		users := user.NewRepository(db, databasetest.Logger())
+		databasetest.Migrate(t, db, users)
+
+		tokens := []struct {
+			token  string
+			prefix string
+		}{
+			{"legacy-session-token", "lega****"},
+			{"short", "****"},
+		}
+		for _, tt := range tokens {
+			if err := users.CreateUser(&user.User{Name: tt.token, Token: tt.token}); err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+		}
+
+		keys := NewRepository(db)
+		databasetest.Migrate(t, db, users, keys)
+
+		for _, tt := range tokens {
+			key, err := keys.GetApiKeyByHash(Hash(tt.token))
+			if err != nil {
+				t.Fatalf("imported key of %q: %v", tt.token, err)
+			}
+			if key.Prefix != tt.prefix || !key.HasScope(ScopeAdmin) || key.Check("127.0.0.1") != nil {
+				t.Errorf("imported key of %q: got %+v", tt.token, key)
+			}
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package whatsapp
+
+import (
+	"context"
+
+	waBinary "go.mau.fi/whatsmeow/binary"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nugrhrizki/buzz/pkg/metrics"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/message"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+const (
+	CallOutcomeNotified = "notified"
+	CallOutcomeRejected = "rejected"
+	CallOutcomeReplied  = "rejected_replied"
+	CallOutcomeFailed   = "reject_failed"
+	CallOutcomeAccepted = "accepted"
+	CallOutcomeEnded    = "ended"
+)
+
+const defaultCallReply = "Sorry, this number doesn't take calls. Please send a message instead."
+
+// rejectCall declines an incoming call. whatsmeow has no helper for it yet, so
+// the reject stanza is sent the same way the official clients do, addressed
+// to the caller's account rather than the device that rang.
+func (c *Client) rejectCall(meta types.BasicCallMeta) error {
+	return c.WAClient.DangerousInternals().SendNode(waBinary.Node{
+		Tag: "call",
+		Attrs: waBinary.Attrs{
+			"id":   c.WAClient.GenerateMessageID(),
+			"from": c.WAClient.Store.ID.ToNonAD(),
+			"to":   meta.From.ToNonAD(),
+		},
+		Content: []waBinary.Node{{
+			Tag: "reject",
+			Attrs: waBinary.Attrs{
+				"call-id":      meta.CallID,
+				"call-creator": meta.CallCreator.ToNonAD(),
+				"count":        "0",
+			},
+		}},
+	})
+}
+
+// handleCall applies the session call policy to an incoming call and reports
+// what was done with it. It talks to WhatsApp, so it runs outside the event
+// handler.
+func (c *Client) handleCall(meta types.BasicCallMeta) string {
+	u, err := c.whatsapp.users.GetUserById(c.userID)
+	if err != nil {
+		c.log.Error().Err(err).Msg("Could not load call policy")
+		return CallOutcomeNotified
+	}
+
+	if u.CallPolicy != user.CallPolicyReject && u.CallPolicy != user.CallPolicyRejectReply {
+		return CallOutcomeNotified
+	}
+
+	if err := c.rejectCall(meta); err != nil {
+		c.log.Error().Err(err).Str("id", meta.CallID).Msg("Failed to reject call")
+		return CallOutcomeFailed
+	}
+	c.log.Info().Str("id", meta.CallID).Str("from", meta.CallCreator.String()).Msg("Rejected call")
+
+	if u.CallPolicy == user.CallPolicyReject {
+		return CallOutcomeRejected
+	}
+
+	reply := u.CallReply
+	if reply == "" {
+		reply = defaultCallReply
+	}
+
+	recipient := meta.CallCreator.ToNonAD()
+	msg := &waProto.Message{Conversation: proto.String(reply)}
+	resp, err := c.WAClient.SendMessage(context.Background(), recipient, msg)
+	c.whatsapp.metrics.Message(metrics.Sent, "text", err)
+	if err != nil {
+		c.log.Error().Err(err).Str("to", recipient.String()).Msg("Failed to send call reply")
+		return CallOutcomeRejected
+	}
+
+	err = c.whatsapp.messages.CreateMessage(context.Background(), &message.Message{
+		WhatsappUserId: c.userID,
+		MessageId:      resp.ID,
+		Chat:           recipient.String(),
+		FromMe:         true,
+		Type:           "text",
+		Body:           reply,
+		Read:           true,
+		Timestamp:      resp.Timestamp,
+	})
+	if err != nil {
+		c.log.Warn().Err(err).Str("id", resp.ID).Msg("Could not store call reply")
+	}
+
+	return CallOutcomeReplied
+}
+
+func callPostmap(postmap map[string]interface{}, meta types.BasicCallMeta, outcome string) {
+	postmap["type"] = "Call"
+	postmap["id"] = meta.CallID
+	postmap["caller"] = meta.CallCreator.ToNonAD().String()
+	postmap["timestamp"] = meta.Timestamp
+	postmap["outcome"] = outcome
+}
@@ -1,6 +1,7 @@
 package whatsapp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"mime"
@@ -10,9 +11,10 @@ import (
 	"strings"
 	"sync/atomic"
 
+	"github.com/nugrhrizki/buzz/pkg/metrics"
 	"github.com/nugrhrizki/buzz/pkg/utils"
-	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
-	"github.com/patrickmn/go-cache"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/message"
+	"github.com/rs/zerolog"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/appstate"
 	"go.mau.fi/whatsmeow/types"
@@ -28,6 +30,7 @@ type Client struct {
 	token          string
 	subscriptions  []string
 	whatsapp       *Whatsapp
+	log            *zerolog.Logger
 }
 
 func NewClient(
@@ -39,6 +42,7 @@ func NewClient(
 
 	whatsapp *Whatsapp,
 ) Client {
+	log := whatsapp.log.With().Int("session", userID).Logger()
 	return Client{
 		WAClient:       waClient,
 		eventHandlerID: eventHandlerID,
@@ -46,6 +50,7 @@ func NewClient(
 		token:          token,
 		subscriptions:  subscriptions,
 		whatsapp:       whatsapp,
+		log:            &log,
 	}
 }
 
@@ -75,24 +80,21 @@ func (c *Client) EventHandler(rawEvt interface{}) {
 	postmap["event"] = rawEvt
 	dowebhook := 0
 	path := ""
-
-	ex, err := os.Executable()
-	if err != nil {
-		panic(err)
-	}
-	exPath := filepath.Dir(ex)
+	// messageIds are the messages the event is about
+	var messageIds []string
 
 	switch evt := rawEvt.(type) {
 	case *events.AppStateSyncComplete:
 		if len(c.WAClient.Store.PushName) > 0 && evt.Name == appstate.WAPatchCriticalBlock {
 			err := c.WAClient.SendPresence(types.PresenceAvailable)
 			if err != nil {
-				c.whatsapp.log.Warn().Err(err).Msg("Failed to send available presence")
+				c.log.Warn().Err(err).Msg("Failed to send available presence")
 			} else {
-				c.whatsapp.log.Info().Msg("Marked self as available")
+				c.log.Info().Msg("Marked self as available")
 			}
 		}
 	case *events.Connected, *events.PushNameSetting:
+		c.whatsapp.metrics.SessionState(c.userID, c.WAClient.IsConnected(), c.WAClient.IsLoggedIn())
 		if len(c.WAClient.Store.PushName) == 0 {
 			return
 		}
@@ -100,47 +102,59 @@ func (c *Client) EventHandler(rawEvt interface{}) {
 		// This makes sure that outgoing messages always have the right pushname.
 		err := c.WAClient.SendPresence(types.PresenceAvailable)
 		if err != nil {
-			c.whatsapp.log.Warn().Err(err).Msg("Failed to send available presence")
+			c.log.Warn().Err(err).Msg("Failed to send available presence")
 		} else {
-			c.whatsapp.log.Info().Msg("Marked self as available")
+			c.log.Info().Msg("Marked self as available")
 		}
-		c.whatsapp.log.Info().Msg("Setting up status connection")
+		c.log.Info().Msg("Setting up status connection")
 		err = c.whatsapp.users.SetUserConnected(c.userID, 1)
 		if err != nil {
-			c.whatsapp.log.Error().Err(err).Msg("Failed to set user connected")
+			c.log.Error().Err(err).Msg("Failed to set user connected")
 			return
 		}
 	case *events.PairSuccess:
-		c.whatsapp.log.Info().Str("userid", strconv.Itoa(c.userID)).Str("token", c.token).Str("ID", evt.ID.String()).Str("BusinessName", evt.BusinessName).Str("Platform", evt.Platform).Msg("QR Pair Success")
+		c.log.Info().Str("userid", strconv.Itoa(c.userID)).Str("ID", evt.ID.String()).Str("BusinessName", evt.BusinessName).Str("Platform", evt.Platform).Msg("QR Pair Success")
 		jid := evt.ID
-		err = c.whatsapp.users.SetUserJid(c.userID, jid)
+		err := c.whatsapp.users.SetUserJid(c.userID, jid)
 		if err != nil {
-			c.whatsapp.log.Error().Err(err).Msg("Failed to set user jid")
+			c.log.Error().Err(err).Msg("Failed to set user jid")
 			return
 		}
 
-		userInfo, found := c.whatsapp.userInfoCache.Get(c.token)
+		userInfo, found := c.whatsapp.LoadUserInfo(c.userID, c.token)
 		if !found {
-			c.whatsapp.log.Warn().Msg("No user info cached on pairing?")
+			c.log.Warn().Msg("No user info on pairing?")
 		} else {
-			txtid := userInfo.(user.UserInfo).Id
-			token := userInfo.(user.UserInfo).Token
-			newUserInfo := user.UserInfo{
-				Id:      txtid,
-				Jid:     jid.String(),
-				Webhook: userInfo.(user.UserInfo).Webhook,
-				Token:   token,
-				Events:  userInfo.(user.UserInfo).Events,
-			}
-			c.whatsapp.userInfoCache.Set(token, newUserInfo, cache.NoExpiration)
-			c.whatsapp.log.Info().Str("jid", jid.String()).Str("userid", txtid).Str("token", token).Msg("User information set")
+			userInfo.Jid = jid.String()
+			c.whatsapp.UpdateCacheUserInfo(c.token, userInfo)
+			c.log.Info().Str("jid", jid.String()).Str("userid", userInfo.Id).Msg("User information set")
 		}
 	case *events.StreamReplaced:
-		c.whatsapp.log.Info().Msg("Received StreamReplaced event")
+		c.log.Info().Msg("Received StreamReplaced event")
+		c.whatsapp.metrics.SessionState(c.userID, false, false)
 		return
+	case *events.Disconnected:
+		c.log.Info().Msg("Disconnected, reconnecting")
+		c.whatsapp.metrics.SessionState(c.userID, false, c.WAClient.Store.ID != nil)
+		c.whatsapp.metrics.Reconnect(nil)
+	case *events.UndecryptableMessage:
+		c.log.Warn().Str("id", evt.Info.ID).Str("source", evt.Info.SourceString()).Msg("Could not decrypt message")
+		c.whatsapp.metrics.Message(metrics.Received, "unknown", ErrUndecryptable)
 	case *events.Message:
+		if evt.Info.Chat == types.StatusBroadcastJID {
+			postmap["type"] = "Status"
+			dowebhook = 1
+			c.log.Info().Str("id", evt.Info.ID).Str("source", evt.Info.SourceString()).Msg("Status update received")
+			break
+		}
+
 		postmap["type"] = "Message"
 		dowebhook = 1
+		messageIds = []string{evt.Info.ID}
+		if !evt.Info.IsFromMe {
+			kind, _ := MessageContent(evt.Message)
+			c.whatsapp.metrics.Message(metrics.Received, kind, nil)
+		}
 		metaParts := []string{fmt.Sprintf("pushname: %s", evt.Info.PushName), fmt.Sprintf("timestamp: %s", evt.Info.Timestamp)}
 		if evt.Info.Type != "" {
 			metaParts = append(metaParts, fmt.Sprintf("type: %s", evt.Info.Type))
@@ -155,86 +169,88 @@ func (c *Client) EventHandler(rawEvt interface{}) {
 			metaParts = append(metaParts, "ephemeral")
 		}
 
-		c.whatsapp.log.Info().Str("id", evt.Info.ID).Str("source", evt.Info.SourceString()).Str("parts", strings.Join(metaParts, ", ")).Msg("Message Received")
+		c.log.Info().Str("id", evt.Info.ID).Str("source", evt.Info.SourceString()).Str("parts", strings.Join(metaParts, ", ")).Msg("Message Received")
+
+		download := c.whatsapp.downloadsMedia(c.userID)
 
 		// try to get Image if any
 		img := evt.Message.GetImageMessage()
-		if img != nil {
+		if img != nil && download {
 
 			// check/creates user directory for files
-			userDirectory := fmt.Sprintf("%s/files/user_%s", exPath, txtid)
+			userDirectory := filepath.Join(c.whatsapp.mediaPath, "user_"+txtid)
 			_, err := os.Stat(userDirectory)
 			if os.IsNotExist(err) {
 				errDir := os.MkdirAll(userDirectory, 0751)
 				if errDir != nil {
-					c.whatsapp.log.Error().Err(errDir).Msg("Could not create user directory")
+					c.log.Error().Err(errDir).Msg("Could not create user directory")
 					return
 				}
 			}
 
 			data, err := c.WAClient.Download(img)
 			if err != nil {
-				c.whatsapp.log.Error().Err(err).Msg("Failed to download image")
+				c.log.Error().Err(err).Msg("Failed to download image")
 				return
 			}
 			exts, _ := mime.ExtensionsByType(img.GetMimetype())
 			path = fmt.Sprintf("%s/%s%s", userDirectory, evt.Info.ID, exts[0])
 			err = os.WriteFile(path, data, 0600)
 			if err != nil {
-				c.whatsapp.log.Error().Err(err).Msg("Failed to save image")
+				c.log.Error().Err(err).Msg("Failed to save image")
 				return
 			}
-			c.whatsapp.log.Info().Str("path", path).Msg("Image saved")
+			c.log.Info().Str("path", path).Msg("Image saved")
 		}
 
 		// try to get Audio if any
 		audio := evt.Message.GetAudioMessage()
-		if audio != nil {
+		if audio != nil && download {
 
 			// check/creates user directory for files
-			userDirectory := fmt.Sprintf("%s/files/user_%s", exPath, txtid)
+			userDirectory := filepath.Join(c.whatsapp.mediaPath, "user_"+txtid)
 			_, err := os.Stat(userDirectory)
 			if os.IsNotExist(err) {
 				errDir := os.MkdirAll(userDirectory, 0751)
 				if errDir != nil {
-					c.whatsapp.log.Error().Err(errDir).Msg("Could not create user directory")
+					c.log.Error().Err(errDir).Msg("Could not create user directory")
 					return
 				}
 			}
 
 			data, err := c.WAClient.Download(audio)
 			if err != nil {
-				c.whatsapp.log.Error().Err(err).Msg("Failed to download audio")
+				c.log.Error().Err(err).Msg("Failed to download audio")
 				return
 			}
 			exts, _ := mime.ExtensionsByType(audio.GetMimetype())
 			path = fmt.Sprintf("%s/%s%s", userDirectory, evt.Info.ID, exts[0])
 			err = os.WriteFile(path, data, 0600)
 			if err != nil {
-				c.whatsapp.log.Error().Err(err).Msg("Failed to save audio")
+				c.log.Error().Err(err).Msg("Failed to save audio")
 				return
 			}
-			c.whatsapp.log.Info().Str("path", path).Msg("Audio saved")
+			c.log.Info().Str("path", path).Msg("Audio saved")
 		}
 
 		// try to get Document if any
 		document := evt.Message.GetDocumentMessage()
-		if document != nil {
+		if document != nil && download {
 
 			// check/creates user directory for files
-			userDirectory := fmt.Sprintf("%s/files/user_%s", exPath, txtid)
+			userDirectory := filepath.Join(c.whatsapp.mediaPath, "user_"+txtid)
 			_, err := os.Stat(userDirectory)
 			if os.IsNotExist(err) {
 				errDir := os.MkdirAll(userDirectory, 0751)
 				if errDir != nil {
-					c.whatsapp.log.Error().Err(errDir).Msg("Could not create user directory")
+					c.log.Error().Err(errDir).Msg("Could not create user directory")
 					return
 				}
 			}
 
 			data, err := c.WAClient.Download(document)
 			if err != nil {
-				c.whatsapp.log.Error().Err(err).Msg("Failed to download document")
+				c.log.Error().Err(err).Msg("Failed to download document")
 				return
 			}
 			extension := ""
@@ -248,17 +264,36 @@ func (c *Client) EventHandler(rawEvt interface{}) {
 			path = fmt.Sprintf("%s/%s%s", userDirectory, evt.Info.ID, extension)
 			err = os.WriteFile(path, data, 0600)
 			if err != nil {
-				c.whatsapp.log.Error().Err(err).Msg("Failed to save document")
+				c.log.Error().Err(err).Msg("Failed to save document")
 				return
 			}
-			c.whatsapp.log.Info().Str("path", path).Msg("Document saved")
+			c.log.Info().Str("path", path).Msg("Document saved")
+		}
+
+		kind, body := MessageContent(evt.Message)
+		err := c.whatsapp.messages.CreateMessage(context.Background(), &message.Message{
+			WhatsappUserId: c.userID,
+			MessageId:      evt.Info.ID,
+			Chat:           evt.Info.Chat.String(),
+			Sender:         evt.Info.Sender.String(),
+			PushName:       evt.Info.PushName,
+			FromMe:         evt.Info.IsFromMe,
+			Type:           kind,
+			Body:           body,
+			MediaPath:      path,
+			Read:           evt.Info.IsFromMe,
+			Timestamp:      evt.Info.Timestamp,
+		})
+		if err != nil {
+			c.log.Error().Err(err).Msg("Failed to store message")
 		}
 	case *events.Receipt:
 		postmap["type"] = "ReadReceipt"
 		dowebhook = 1
+		messageIds = evt.MessageIDs
 		switch evt.Type {
 		case types.ReceiptTypeRead, types.ReceiptTypeReadSelf:
-			c.whatsapp.log.Info().Strs("id", evt.MessageIDs).Str("source", evt.SourceString()).Str("timestamp", fmt.Sprintf("%v", evt.Timestamp)).Msg("Message was read")
+			c.log.Info().Strs("id", evt.MessageIDs).Str("source", evt.SourceString()).Str("timestamp", fmt.Sprintf("%v", evt.Timestamp)).Msg("Message was read")
 			if evt.Type == types.ReceiptTypeRead {
 				postmap["state"] = "Read"
 			} else {
@@ -266,7 +301,7 @@ func (c *Client) EventHandler(rawEvt interface{}) {
 			}
 		case types.ReceiptTypeDelivered:
 			postmap["state"] = "Delivered"
-			c.whatsapp.log.Info().Str("id", evt.MessageIDs[0]).Str("source", evt.SourceString()).Str("timestamp", fmt.Sprintf("%v", evt.Timestamp)).Msg("Message delivered")
+			c.log.Info().Str("id", evt.MessageIDs[0]).Str("source", evt.SourceString()).Str("timestamp", fmt.Sprintf("%v", evt.Timestamp)).Msg("Message delivered")
 		default:
 			// Discard webhooks for inactive or other delivery types
 			return
@@ -277,25 +312,25 @@ func (c *Client) EventHandler(rawEvt interface{}) {
 		if evt.Unavailable {
 			postmap["state"] = "offline"
 			if evt.LastSeen.IsZero() {
-				c.whatsapp.log.Info().Str("from", evt.From.String()).Msg("User is now offline")
+				c.log.Info().Str("from", evt.From.String()).Msg("User is now offline")
 			} else {
-				c.whatsapp.log.Info().Str("from", evt.From.String()).Str("lastSeen", fmt.Sprintf("%v", evt.LastSeen)).Msg("User is now offline")
+				c.log.Info().Str("from", evt.From.String()).Str("lastSeen", fmt.Sprintf("%v", evt.LastSeen)).Msg("User is now offline")
 			}
 		} else {
 			postmap["state"] = "online"
-			c.whatsapp.log.Info().Str("from", evt.From.String()).Msg("User is now online")
+			c.log.Info().Str("from", evt.From.String()).Msg("User is now online")
 		}
 	case *events.HistorySync:
 		postmap["type"] = "HistorySync"
 		dowebhook = 1
 
 		// check/creates user directory for files
-		userDirectory := fmt.Sprintf("%s/files/user_%s", exPath, txtid)
+		userDirectory := filepath.Join(c.whatsapp.mediaPath, "user_"+txtid)
 		_, err := os.Stat(userDirectory)
 		if os.IsNotExist(err) {
 			errDir := os.MkdirAll(userDirectory, 0751)
 			if errDir != nil {
-				c.whatsapp.log.Error().Err(errDir).Msg("Could not create user directory")
+				c.log.Error().Err(errDir).Msg("Could not create user directory")
 				return
 			}
 		}
@@ -304,82 +339,157 @@ func (c *Client) EventHandler(rawEvt interface{}) {
 		fileName := fmt.Sprintf("%s/history-%d.json", userDirectory, id)
 		file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE, 0600)
 		if err != nil {
-			c.whatsapp.log.Error().Err(err).Msg("Failed to open file to write history sync")
+			c.log.Error().Err(err).Msg("Failed to open file to write history sync")
 			return
 		}
 		enc := json.NewEncoder(file)
 		enc.SetIndent("", "  ")
 		err = enc.Encode(evt.Data)
 		if err != nil {
-			c.whatsapp.log.Error().Err(err).Msg("Failed to write history sync")
+			c.log.Error().Err(err).Msg("Failed to write history sync")
 			return
 		}
-		c.whatsapp.log.Info().Str("filename", fileName).Msg("Wrote history sync")
+		c.log.Info().Str("filename", fileName).Msg("Wrote history sync")
 		_ = file.Close()
+	case *events.Archive:
+		if evt.FromFullSync {
+			return
+		}
+		postmap["type"] = "ChatState"
+		postmap["action"] = "archive"
+		postmap["chat"] = evt.JID.String()
+		postmap["state"] = evt.Action.GetArchived()
+		dowebhook = 1
+	case *events.Pin:
+		if evt.FromFullSync {
+			return
+		}
+		postmap["type"] = "ChatState"
+		postmap["action"] = "pin"
+		postmap["chat"] = evt.JID.String()
+		postmap["state"] = evt.Action.GetPinned()
+		dowebhook = 1
+	case *events.Mute:
+		if evt.FromFullSync {
+			return
+		}
+		postmap["type"] = "ChatState"
+		postmap["action"] = "mute"
+		postmap["chat"] = evt.JID.String()
+		postmap["state"] = evt.Action.GetMuted()
+		dowebhook = 1
+	case *events.MarkChatAsRead:
+		if evt.FromFullSync {
+			return
+		}
+		postmap["type"] = "ChatState"
+		postmap["action"] = "read"
+		postmap["chat"] = evt.JID.String()
+		postmap["state"] = evt.Action.GetRead()
+		dowebhook = 1
+	case *events.DeleteChat:
+		if evt.FromFullSync {
+			return
+		}
+		postmap["type"] = "ChatState"
+		postmap["action"] = "delete"
+		postmap["chat"] = evt.JID.String()
+		postmap["state"] = true
+		dowebhook = 1
+	case *events.ClearChat:
+		if evt.FromFullSync {
+			return
+		}
+		postmap["type"] = "ChatState"
+		postmap["action"] = "clear"
+		postmap["chat"] = evt.JID.String()
+		postmap["state"] = true
+		dowebhook = 1
 	case *events.AppState:
-		c.whatsapp.log.Info().Str("index", fmt.Sprintf("%+v", evt.Index)).Str("actionValue", fmt.Sprintf("%+v", evt.SyncActionValue)).Msg("App state event received")
+		c.log.Info().Str("index", fmt.Sprintf("%+v", evt.Index)).Str("actionValue", fmt.Sprintf("%+v", evt.SyncActionValue)).Msg("App state event received")
 	case *events.LoggedOut:
-		c.whatsapp.log.Info().Str("reason", evt.Reason.String()).Msg("Logged out")
+		c.log.Info().Str("reason", evt.Reason.String()).Msg("Logged out")
+		c.whatsapp.metrics.SessionState(c.userID, false, false)
 		c.whatsapp.killchannel[c.userID] <- true
 		err := c.whatsapp.users.SetUserConnected(c.userID, 0)
 		if err != nil {
-			c.whatsapp.log.Error().Err(err).Msg("Failed to set user disconnected")
+			c.log.Error().Err(err).Msg("Failed to set user disconnected")
 			return
 		}
 	case *events.ChatPresence:
 		postmap["type"] = "ChatPresence"
 		dowebhook = 1
-		c.whatsapp.log.Info().Str("state", string(evt.State)).Str("media", string(evt.Media)).Str("chat", evt.MessageSource.Chat.String()).Str("sender", evt.MessageSource.Sender.String()).Msg("Chat Presence received")
+		c.log.Info().Str("state", string(evt.State)).Str("media", string(evt.Media)).Str("chat", evt.MessageSource.Chat.String()).Str("sender", evt.MessageSource.Sender.String()).Msg("Chat Presence received")
 	case *events.CallOffer:
-		c.whatsapp.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call offer")
+		c.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call offer")
+		_, video := evt.Data.GetOptionalChildByTag("video")
+		go func() {
+			callPostmap(postmap, evt.BasicCallMeta, c.handleCall(evt.BasicCallMeta))
+			postmap["video"] = video
+			c.notify(postmap, "", nil)
+		}()
 	case *events.CallAccept:
-		c.whatsapp.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call accept")
+		c.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call accept")
+		callPostmap(postmap, evt.BasicCallMeta, CallOutcomeAccepted)
+		dowebhook = 1
 	case *events.CallTerminate:
-		c.whatsapp.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call terminate")
+		c.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call terminate")
+		callPostmap(postmap, evt.BasicCallMeta, CallOutcomeEnded)
+		postmap["reason"] = evt.Reason
+		dowebhook = 1
 	case *events.CallOfferNotice:
-		c.whatsapp.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call offer notice")
+		c.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call offer notice")
+		go func() {
+			callPostmap(postmap, evt.BasicCallMeta, c.handleCall(evt.BasicCallMeta))
+			postmap["video"] = evt.Media == "video"
+			postmap["group"] = evt.Type == "group"
+			c.notify(postmap, "", nil)
+		}()
 	case *events.CallRelayLatency:
-		c.whatsapp.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call relay latency")
+		c.log.Info().Str("event", fmt.Sprintf("%+v", evt)).Msg("Got call relay latency")
 	default:
-		c.whatsapp.log.Warn().Str("event", fmt.Sprintf("%+v", evt)).Msg("Unhandled event")
+		c.log.Warn().Str("event", fmt.Sprintf("%+v", evt)).Msg("Unhandled event")
 	}
 
 	if dowebhook == 1 {
-		// call webhook
-		webhookurl := ""
-		userInfo, found := c.whatsapp.userInfoCache.Get(c.token)
-		if !found {
-			c.whatsapp.log.Warn().
-				Str("token", c.token).
-				Msg("Could not call webhook as there is no user for this token")
-		} else {
-			webhookurl = userInfo.(user.UserInfo).Webhook
-		}
+		c.notify(postmap, path, messageIds)
+	}
+}
 
-		if !utils.Find(c.subscriptions, postmap["type"].(string)) &&
-			!utils.Find(c.subscriptions, "All") {
-			c.whatsapp.log.Warn().
-				Str("type", postmap["type"].(string)).
-				Msg("Skipping webhook. Not subscribed for this type")
-			return
-		}
+// notify sends an event to the session webhook, path is a file to attach and
+// messageIds the messages the event is about
+func (c *Client) notify(postmap map[string]interface{}, path string, messageIds []string) {
+	webhookurl := ""
+	userInfo, found := c.whatsapp.LoadUserInfo(c.userID, c.token)
+	if !found {
+		c.log.Warn().
+			Msg("Could not call webhook as there is no user for this token")
+	} else {
+		webhookurl = userInfo.Webhook
+	}
 
-		if webhookurl != "" {
-			c.whatsapp.log.Info().Str("url", webhookurl).Msg("Calling webhook")
-			values, _ := json.Marshal(postmap)
-			if path == "" {
-				data := make(map[string]string)
-				data["jsonData"] = string(values)
-				data["token"] = c.token
-				go c.whatsapp.CallHook(webhookurl, data, c.userID)
-			} else {
-				data := make(map[string]string)
-				data["jsonData"] = string(values)
-				data["token"] = c.token
-				go c.whatsapp.CallHookFile(webhookurl, data, c.userID, path)
-			}
+	if !utils.Find(c.subscriptions, postmap["type"].(string)) &&
+		!utils.Find(c.subscriptions, "All") {
+		c.log.Warn().
+			Str("type", postmap["type"].(string)).
+			Msg("Skipping webhook. Not subscribed for this type")
+		return
+	}
+
+	if webhookurl != "" {
+		c.log.Info().Str("url", webhookurl).Msg("Calling webhook")
+		values, _ := json.Marshal(postmap)
+		// the receiver tells sessions apart by id, the token authenticates
+		// against this api and never leaves it
+		data := make(map[string]string)
+		data["jsonData"] = string(values)
+		data["session"] = strconv.Itoa(c.userID)
+		if path == "" {
+			go c.whatsapp.CallHook(webhookurl, data, c.userID, messageIds)
 		} else {
-			c.whatsapp.log.Warn().Str("userid", strconv.Itoa(c.userID)).Msg("No webhook set for user")
+			go c.whatsapp.CallHookFile(webhookurl, data, c.userID, messageIds, path)
 		}
+	} else {
+		c.log.Warn().Str("userid", strconv.Itoa(c.userID)).Msg("No webhook set for user")
 	}
 }
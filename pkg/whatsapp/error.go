@@ -13,4 +13,15 @@ var (
 	ErrEmptyBody              = errors.New("body cannot be empty")
 	ErrMissingStanzaId        = errors.New("missing stanza id in contextinfo")
 	ErrMissingParticipant     = errors.New("missing participant in contextinfo")
+	ErrMissingMessageIds      = errors.New("message ids cannot be empty")
+	ErrNotLoggedIn            = errors.New("not logged in")
+	ErrInvalidPrivacySetting  = errors.New("privacy setting should be one of last, profile, status, readreceipts or groupadd")
+	ErrInvalidPrivacyValue    = errors.New("value is not allowed for this privacy setting")
+	ErrInvalidColor           = errors.New("color should be formatted as #RRGGBB or #AARRGGBB")
+	ErrInvalidFont            = errors.New("unknown status font")
+	ErrStatusAllowlist        = errors.New("status privacy reaches beyond the recipient allowlist")
+	ErrInvalidNewsletter      = errors.New("invalid newsletter jid")
+	ErrInvalidCallPolicy      = errors.New("call policy should be one of notify, reject or reject_reply")
+	ErrUndecryptable          = errors.New("message could not be decrypted")
+	ErrWebhookStatus          = errors.New("webhook answered with an error status")
 )
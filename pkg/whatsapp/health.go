@@ -0,0 +1,50 @@
+package whatsapp
+
+import "os"
+
+// SessionState tells whether a session marked connected really is
+type SessionState struct {
+	Id        int  `json:"id"`
+	Connected bool `json:"connected"`
+	LoggedIn  bool `json:"logged_in"`
+}
+
+// CheckStore reads the devices from the whatsmeow store
+func (w *Whatsapp) CheckStore() error {
+	_, err := w.container.GetAllDevices()
+	return err
+}
+
+// CheckMedia makes sure received media can be written to the media path
+func (w *Whatsapp) CheckMedia() error {
+	if err := os.MkdirAll(w.mediaPath, 0751); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(w.mediaPath, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// SessionStates returns the state of every session marked connected, the
+// ones the server brings up when it starts
+func (w *Whatsapp) SessionStates() ([]SessionState, error) {
+	users, err := w.users.GetConnectedUser()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]SessionState, 0, len(users))
+	for _, u := range users {
+		state := SessionState{Id: u.Id}
+		if client, err := w.GetClient(u.Id); err == nil {
+			state.Connected = client.IsConnected()
+			state.LoggedIn = client.IsLoggedIn()
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
 	"github.com/patrickmn/go-cache"
 	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/types"
 )
 
@@ -27,8 +28,32 @@ func (w *Whatsapp) GetCacheUserInfo(token string) (user.UserInfo, bool) {
 	return user.UserInfo{}, false
 }
 
+// UpdateCacheUserInfo caches the info of a session for userInfoTTL, after
+// that it is read from the database again
 func (w *Whatsapp) UpdateCacheUserInfo(token string, value user.UserInfo) {
-	w.userInfoCache.Set(token, value, cache.NoExpiration)
+	w.userInfoCache.Set(token, value, cache.DefaultExpiration)
+}
+
+// LoadUserInfo returns the info of a session, from the cache when it is
+// still there
+func (w *Whatsapp) LoadUserInfo(userID int, token string) (user.UserInfo, bool) {
+	if userInfo, found := w.GetCacheUserInfo(token); found {
+		return userInfo, true
+	}
+
+	u, err := w.users.GetUserById(userID)
+	if err != nil {
+		w.log.Warn().Err(err).Int("session", userID).Msg("Could not load session info")
+		return user.UserInfo{}, false
+	}
+
+	userInfo := w.UserToUserInfo(u)
+	w.UpdateCacheUserInfo(token, userInfo)
+	return userInfo, true
+}
+
+func (w *Whatsapp) DeleteCacheUserInfo(token string) {
+	w.userInfoCache.Delete(token)
 }
 
 // parseJID parses a JID string and returns a JID struct
@@ -40,6 +65,16 @@ func (w *Whatsapp) ParseJID(arg string) (types.JID, bool) {
 		arg = arg[1:]
 	}
 
+	// Channel ids are not phone numbers, leave them to the regular jid parser
+	if strings.HasSuffix(arg, "@"+types.NewsletterServer) {
+		recipient, err := types.ParseJID(arg)
+		if err != nil {
+			w.log.Error().Err(err).Str("jid", arg).Msg("Invalid newsletter jid")
+			return recipient, false
+		}
+		return recipient, true
+	}
+
 	// Basic only digit check for recipient phone number, we want to remove @server and .session
 	phonenumber := ""
 	phonenumber = strings.Split(arg, "@")[0]
@@ -86,3 +121,31 @@ func (w *Whatsapp) GetClient(userId int) (*whatsmeow.Client, error) {
 
 	return client, nil
 }
+
+// MessageContent returns the kind of a message and its readable text, if any
+func MessageContent(msg *waProto.Message) (string, string) {
+	switch {
+	case msg.GetConversation() != "":
+		return "text", msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		return "text", msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		return "image", msg.GetImageMessage().GetCaption()
+	case msg.GetVideoMessage() != nil:
+		return "video", msg.GetVideoMessage().GetCaption()
+	case msg.GetAudioMessage() != nil:
+		return "audio", ""
+	case msg.GetDocumentMessage() != nil:
+		return "document", msg.GetDocumentMessage().GetFileName()
+	case msg.GetStickerMessage() != nil:
+		return "sticker", ""
+	case msg.GetContactMessage() != nil:
+		return "contact", msg.GetContactMessage().GetDisplayName()
+	case msg.GetLocationMessage() != nil:
+		return "location", msg.GetLocationMessage().GetName()
+	case msg.GetReactionMessage() != nil:
+		return "reaction", msg.GetReactionMessage().GetText()
+	default:
+		return "unknown", ""
+	}
+}
@@ -1,19 +1,100 @@
 package whatsapp
 
-// webhook for regular messages
-func (w *Whatsapp) CallHook(myurl string, payload map[string]string, id int) {
-	w.log.Info().Str("url", myurl).Msg("Sending POST")
-	_, err := w.clientHttp[id].R().SetFormData(payload).Post(myurl)
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
-	if err != nil {
-		w.log.Debug().Str("error", err.Error())
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nugrhrizki/buzz/pkg/telemetry"
+)
+
+var tracer = otel.Tracer("github.com/nugrhrizki/buzz/pkg/whatsapp")
+
+// sentSpanTTL bounds how long after a send the webhooks about the message,
+// like its receipts, still link to the span it was sent in
+const sentSpanTTL = time.Hour
+
+// TraceSent remembers the span a message was sent in
+func (w *Whatsapp) TraceSent(messageId string, span trace.SpanContext) {
+	if span.IsValid() {
+		w.sentSpans.SetDefault(messageId, span)
 	}
 }
 
+// sentLinks links to the spans the messages were sent in, when they were
+// sent from here
+func (w *Whatsapp) sentLinks(messageIds []string) []trace.Link {
+	var links []trace.Link
+	for _, id := range messageIds {
+		if span, found := w.sentSpans.Get(id); found {
+			links = append(links, trace.Link{SpanContext: span.(trace.SpanContext)})
+		}
+	}
+	return links
+}
+
+// webhook for regular messages
+func (w *Whatsapp) CallHook(myurl string, payload map[string]string, id int, messageIds []string) {
+	w.log.Info().Str("url", myurl).Msg("Sending POST")
+	w.deliver(myurl, id, messageIds, func(req *resty.Request) (*resty.Response, error) {
+		return req.SetFormData(payload).Post(myurl)
+	})
+}
+
 // webhook for messages with file attachments
-func (w *Whatsapp) CallHookFile(myurl string, payload map[string]string, id int, file string) {
+func (w *Whatsapp) CallHookFile(myurl string, payload map[string]string, id int, messageIds []string, file string) {
 	w.log.Info().Str("file", file).Str("url", myurl).Msg("Sending POST")
-	w.clientHttp[id].R().SetFiles(map[string]string{
-		"file": file,
-	}).SetFormData(payload).Post(myurl)
+	w.deliver(myurl, id, messageIds, func(req *resty.Request) (*resty.Response, error) {
+		return req.SetFiles(map[string]string{
+			"file": file,
+		}).SetFormData(payload).Post(myurl)
+	})
+}
+
+// deliver posts a webhook in a trace of its own, linked to the sends of the
+// messages it is about. The receiver gets the trace context in the request
+// headers. Only the scheme and host of the url are recorded, its path and
+// query often carry a secret.
+func (w *Whatsapp) deliver(myurl string, id int, messageIds []string, post func(req *resty.Request) (*resty.Response, error)) {
+	attributes := []attribute.KeyValue{
+		attribute.String("buzz.session", strconv.Itoa(id)),
+	}
+	if len(messageIds) > 0 {
+		attributes = append(attributes, attribute.String("buzz.message.id", strings.Join(messageIds, ",")))
+	}
+	if parsed, err := url.Parse(myurl); err == nil {
+		attributes = append(attributes, semconv.URLScheme(parsed.Scheme), semconv.ServerAddress(parsed.Hostname()))
+	}
+
+	ctx, span := tracer.Start(context.Background(), "webhook.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+		trace.WithLinks(w.sentLinks(messageIds)...),
+	)
+
+	req := w.clientHttp[id].R().SetContext(ctx)
+	telemetry.Inject(ctx, req.Header)
+
+	start := time.Now()
+	resp, err := post(req)
+	failed := err == nil && resp.IsError()
+	w.metrics.Webhook(time.Since(start), err, failed)
+
+	if err != nil {
+		w.log.Warn().Err(err).Int("userid", id).Msg("Webhook delivery failed")
+	} else {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode()))
+		if failed {
+			err = ErrWebhookStatus
+		}
+	}
+	telemetry.End(span, err)
 }
@@ -0,0 +1,94 @@
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/patrickmn/go-cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+	"github.com/nugrhrizki/buzz/pkg/metrics"
+)
+
+func TestDeliverSpan(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("traceparent") == "" {
+				t.Error("the webhook came without trace context")
+			}
+		}))
+		defer receiver.Close()
+
+		w := &Whatsapp{
+			clientHttp: map[int]*resty.Client{1: resty.New()},
+			sentSpans:  cache.New(sentSpanTTL, time.Minute),
+			log:        databasetest.Logger(),
+			metrics:    metrics.New(db, config.Default()),
+		}
+
+		_, send := otel.Tracer("test").Start(context.Background(), "send")
+		send.End()
+		w.TraceSent("sent", send.SpanContext())
+
+		tests := []struct {
+			name       string
+			messageIds []string
+			messageId  string
+			links      int
+		}{
+			{"receipt of a sent message", []string{"sent", "elsewhere"}, "sent,elsewhere", 1},
+			{"received message", []string{"received"}, "received", 0},
+			{"no message", nil, "", 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ended := len(recorder.Ended())
+				w.CallHook(receiver.URL+"/hook?secret=hunter2", map[string]string{"jsonData": "{}"}, 1, tt.messageIds)
+
+				spans := recorder.Ended()[ended:]
+				if len(spans) != 1 {
+					t.Fatalf("got %d spans, want 1", len(spans))
+				}
+				span := spans[0]
+
+				attributes := map[attribute.Key]attribute.Value{}
+				for _, kv := range span.Attributes() {
+					attributes[kv.Key] = kv.Value
+				}
+				if got := attributes["buzz.message.id"].AsString(); got != tt.messageId {
+					t.Errorf("buzz.message.id = %q, want %q", got, tt.messageId)
+				}
+				for key, value := range attributes {
+					if value.Type() == attribute.STRING && (strings.Contains(value.AsString(), "hunter2") || strings.Contains(value.AsString(), "/hook")) {
+						t.Errorf("%s records the path or query of the url: %s", key, value.AsString())
+					}
+				}
+
+				if len(span.Links()) != tt.links {
+					t.Fatalf("got %d links, want %d", len(span.Links()), tt.links)
+				}
+				if tt.links > 0 && span.Links()[0].SpanContext.SpanID() != send.SpanContext().SpanID() {
+					t.Error("the webhook is not linked to the send")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package message
+
+import (
+	"embed"
+	"time"
+)
+
+type Message struct {
+	Id             int64     `db:"id"               json:"id"`
+	WhatsappUserId int       `db:"whatsapp_user_id" json:"whatsapp_user_id"`
+	MessageId      string    `db:"message_id"       json:"message_id"`
+	Chat           string    `db:"chat"             json:"chat"`
+	Sender         string    `db:"sender"           json:"sender"`
+	PushName       string    `db:"push_name"        json:"push_name"`
+	FromMe         bool      `db:"from_me"          json:"from_me"`
+	Type           string    `db:"type"             json:"type"`
+	Body           string    `db:"body"             json:"body"`
+	MediaPath      string    `db:"media_path"       json:"media_path"`
+	AgentId        *int64    `db:"agent_id"         json:"agent_id"`
+	Read           bool      `db:"read"             json:"read"`
+	Timestamp      time.Time `db:"timestamp"        json:"timestamp"`
+	CreatedAt      time.Time `db:"created_at"       json:"created_at"`
+}
+
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS whatsapp_messages (
+	id BIGSERIAL PRIMARY KEY,
+	whatsapp_user_id BIGINT NOT NULL,
+	message_id TEXT NOT NULL,
+	chat TEXT NOT NULL,
+	sender TEXT NOT NULL DEFAULT '',
+	push_name TEXT NOT NULL DEFAULT '',
+	from_me BOOLEAN NOT NULL DEFAULT FALSE,
+	type TEXT NOT NULL DEFAULT 'text',
+	body TEXT NOT NULL DEFAULT '',
+	media_path TEXT NOT NULL DEFAULT '',
+	agent_id BIGINT,
+	read BOOLEAN NOT NULL DEFAULT FALSE,
+	timestamp TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS whatsapp_messages_message_uindex ON whatsapp_messages (whatsapp_user_id, message_id);
+CREATE INDEX IF NOT EXISTS whatsapp_messages_chat_index ON whatsapp_messages (whatsapp_user_id, chat, timestamp);
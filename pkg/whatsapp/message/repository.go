@@ -0,0 +1,117 @@
+package message
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/rs/zerolog"
+	"io/fs"
+)
+
+type Repository struct {
+	db  *database.Database
+	log *zerolog.Logger
+}
+
+func NewRepository(db *database.Database, log *zerolog.Logger) *Repository {
+	return &Repository{db, log}
+}
+
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "whatsapp_message", migrations
+}
+
+func (r *Repository) CreateMessage(ctx context.Context, message *Message) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO whatsapp_messages
+			(whatsapp_user_id, message_id, chat, sender, push_name, from_me, type, body, media_path, agent_id, read, timestamp)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (whatsapp_user_id, message_id) DO NOTHING`,
+		message.WhatsappUserId,
+		message.MessageId,
+		message.Chat,
+		message.Sender,
+		message.PushName,
+		message.FromMe,
+		message.Type,
+		message.Body,
+		message.MediaPath,
+		message.AgentId,
+		message.Read,
+		message.Timestamp,
+	)
+	if err != nil {
+		r.log.Error().Err(err).Msg("failed to create message")
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetMessagesByChat(userId int, chat string, limit int, offset int) ([]Message, error) {
+	messages := []Message{}
+	err := r.db.Select(
+		&messages,
+		`SELECT * FROM whatsapp_messages
+		WHERE whatsapp_user_id = $1 AND chat = $2
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $3 OFFSET $4`,
+		userId,
+		chat,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *Repository) MarkChatRead(userId int, chat string) error {
+	_, err := r.db.Exec(
+		"UPDATE whatsapp_messages SET read = TRUE WHERE whatsapp_user_id = $1 AND chat = $2 AND from_me = FALSE AND read = FALSE",
+		userId,
+		chat,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) MarkMessagesRead(userId int, chat string, ids []string) error {
+	query, args, err := sqlx.In(
+		"UPDATE whatsapp_messages SET read = TRUE WHERE whatsapp_user_id = ? AND chat = ? AND message_id IN (?)",
+		userId,
+		chat,
+		ids,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(r.db.DB.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetLastMessage(userId int, chat string) (*Message, error) {
+	var message Message
+	err := r.db.Get(
+		&message,
+		`SELECT * FROM whatsapp_messages
+		WHERE whatsapp_user_id = $1 AND chat = $2
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1`,
+		userId,
+		chat,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
@@ -0,0 +1,122 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestRepository(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		messages := NewRepository(db, databasetest.Logger())
+		databasetest.Migrate(t, db, messages)
+
+		start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+		var agent int64 = 3
+		stored := []Message{
+			{MessageId: "m1", Body: "one"},
+			{MessageId: "m2", Body: "two"},
+			{MessageId: "m3", Body: "three", FromMe: true, Read: true, AgentId: &agent},
+			{MessageId: "m4", Body: "four"},
+			{MessageId: "m5", Body: "five", Chat: "other"},
+		}
+		for i := range stored {
+			message := &stored[i]
+			message.WhatsappUserId = 1
+			message.Type = "text"
+			message.Timestamp = start.Add(time.Duration(i) * time.Minute)
+			if message.Chat == "" {
+				message.Chat = "chat"
+			}
+			if err := messages.CreateMessage(context.Background(), message); err != nil {
+				t.Fatalf("store %s: %v", message.MessageId, err)
+			}
+		}
+
+		// a message delivered twice is only stored once
+		again := stored[0]
+		again.Body = "changed"
+		if err := messages.CreateMessage(context.Background(), &again); err != nil {
+			t.Fatalf("store again: %v", err)
+		}
+
+		pages := []struct {
+			limit  int
+			offset int
+			ids    []string
+		}{
+			{10, 0, []string{"m4", "m3", "m2", "m1"}},
+			{2, 0, []string{"m4", "m3"}},
+			{2, 2, []string{"m2", "m1"}},
+			{2, 4, nil},
+		}
+		for _, tt := range pages {
+			page, err := messages.GetMessagesByChat(1, "chat", tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("page %d+%d: %v", tt.offset, tt.limit, err)
+			}
+			if len(page) != len(tt.ids) {
+				t.Errorf("page %d+%d: got %d messages, want %v", tt.offset, tt.limit, len(page), tt.ids)
+				continue
+			}
+			for i, id := range tt.ids {
+				if page[i].MessageId != id {
+					t.Errorf("page %d+%d: message %d is %s, want %s", tt.offset, tt.limit, i, page[i].MessageId, id)
+				}
+			}
+		}
+
+		last, err := messages.GetLastMessage(1, "chat")
+		if err != nil {
+			t.Fatalf("last: %v", err)
+		}
+		if last.MessageId != "m4" || !last.Timestamp.Equal(stored[3].Timestamp) {
+			t.Errorf("last: got %+v", last)
+		}
+		if _, err := messages.GetLastMessage(2, "chat"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("last of another session: got %v, want %v", err, sql.ErrNoRows)
+		}
+
+		first, _ := messages.GetMessagesByChat(1, "chat", 1, 3)
+		if first[0].Body != "one" {
+			t.Errorf("a duplicate replaced the stored message: got %q", first[0].Body)
+		}
+
+		unread := func() map[string]bool {
+			all, err := messages.GetMessagesByChat(1, "chat", 10, 0)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			ids := map[string]bool{}
+			for _, message := range all {
+				if !message.Read {
+					ids[message.MessageId] = true
+				}
+			}
+			return ids
+		}
+
+		// m5 is in another chat and stays unread
+		if err := messages.MarkMessagesRead(1, "chat", []string{"m1", "m4", "m5"}); err != nil {
+			t.Fatalf("mark messages read: %v", err)
+		}
+		if ids := unread(); len(ids) != 1 || !ids["m2"] {
+			t.Errorf("unread after marking m1 and m4: got %v, want m2", ids)
+		}
+		if other, _ := messages.GetLastMessage(1, "other"); other.Read {
+			t.Error("marking messages read touched another chat")
+		}
+
+		if err := messages.MarkChatRead(1, "chat"); err != nil {
+			t.Fatalf("mark chat read: %v", err)
+		}
+		if ids := unread(); len(ids) != 0 {
+			t.Errorf("unread after marking the chat: got %v", ids)
+		}
+	})
+}
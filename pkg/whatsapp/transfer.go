@@ -0,0 +1,237 @@
+package whatsapp
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
+)
+
+var (
+	ErrNotPaired     = errors.New("session is not paired with a phone")
+	ErrSessionExists = errors.New("a session with this token or device already exists")
+	ErrBadExport     = errors.New("session export is not valid")
+)
+
+// deviceTables are the whatsmeow tables that make up a paired device with
+// the column naming the device, in an order they can be inserted in
+var deviceTables = []struct {
+	name string
+	jid  string
+}{
+	{"whatsmeow_device", "jid"},
+	{"whatsmeow_identity_keys", "our_jid"},
+	{"whatsmeow_pre_keys", "jid"},
+	{"whatsmeow_sessions", "our_jid"},
+	{"whatsmeow_sender_keys", "our_jid"},
+	{"whatsmeow_app_state_sync_keys", "jid"},
+	{"whatsmeow_app_state_version", "jid"},
+	{"whatsmeow_app_state_mutation_macs", "jid"},
+	{"whatsmeow_contacts", "our_jid"},
+	{"whatsmeow_chat_settings", "our_jid"},
+	{"whatsmeow_message_secrets", "our_jid"},
+	{"whatsmeow_privacy_tokens", "our_jid"},
+}
+
+// Export is a session with its whatsmeow device, enough to move it to
+// another Buzz instance without pairing again
+type Export struct {
+	Session user.User                      `json:"session"`
+	Device  map[string][]map[string]Column `json:"device"`
+}
+
+// Column is a value of a device row. Binary values are written as
+// {"base64": "..."} so they read back as bytes.
+type Column struct {
+	Value any
+}
+
+func (c Column) MarshalJSON() ([]byte, error) {
+	if b, ok := c.Value.([]byte); ok {
+		return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+	}
+	return json.Marshal(c.Value)
+}
+
+func (c *Column) UnmarshalJSON(data []byte) error {
+	var encoded struct {
+		Base64 *string `json:"base64"`
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if err := json.Unmarshal(data, &encoded); err != nil || encoded.Base64 == nil {
+			return ErrBadExport
+		}
+		b, err := base64.StdEncoding.DecodeString(*encoded.Base64)
+		if err != nil {
+			return err
+		}
+		c.Value = b
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c.Value); err != nil {
+		return err
+	}
+	if number, ok := c.Value.(json.Number); ok {
+		if n, err := number.Int64(); err == nil {
+			c.Value = n
+		} else if f, err := number.Float64(); err == nil {
+			c.Value = f
+		}
+	}
+	return nil
+}
+
+// HasDevice tells whether the keys of a paired device are stored
+func (w *Whatsapp) HasDevice(jid string) (bool, error) {
+	var count int
+	err := w.db.DB.Get(&count, "SELECT COUNT(*) FROM whatsmeow_device WHERE jid = $1", jid)
+	return count > 0, err
+}
+
+// ExportSession reads a paired session and its device
+func (w *Whatsapp) ExportSession(id int) (*Export, error) {
+	session, err := w.users.GetUserById(id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Jid == "" {
+		return nil, ErrNotPaired
+	}
+
+	export := &Export{
+		Session: *session,
+		Device:  map[string][]map[string]Column{},
+	}
+	export.Session.Qrcode = ""
+
+	for _, table := range deviceTables {
+		rows, err := w.deviceRows(table.name, table.jid, session.Jid)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", table.name, err)
+		}
+		export.Device[table.name] = rows
+	}
+	if len(export.Device["whatsmeow_device"]) == 0 {
+		return nil, ErrNotPaired
+	}
+	return export, nil
+}
+
+func (w *Whatsapp) deviceRows(table string, jidColumn string, jid string) ([]map[string]Column, error) {
+	rows, err := w.db.DB.Queryx(
+		fmt.Sprintf("SELECT * FROM %s WHERE %s = $1", table, jidColumn),
+		jid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]Column{}
+	for rows.Next() {
+		values := map[string]any{}
+		if err := rows.MapScan(values); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]Column, len(values))
+		for _, column := range types {
+			value := values[column.Name()]
+			// sqlite hands booleans back as numbers, keep them booleans so
+			// the export also imports into postgres
+			if n, ok := value.(int64); ok && strings.HasPrefix(column.DatabaseTypeName(), "BOOL") {
+				value = n != 0
+			}
+			row[column.Name()] = Column{value}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+var columnName = regexp.MustCompile(`^[a-z_]+$`)
+
+// ImportSession stores an exported session and its device as a new session.
+// The session should no longer run on the instance it was exported from,
+// WhatsApp disconnects one of the two otherwise.
+func (w *Whatsapp) ImportSession(export *Export) (*user.User, error) {
+	session := export.Session
+	if session.Jid == "" || session.Token == "" || len(export.Device["whatsmeow_device"]) != 1 {
+		return nil, ErrBadExport
+	}
+
+	if _, err := w.users.GetUserByToken(session.Token); err == nil {
+		return nil, ErrSessionExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	tx, err := w.db.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var devices int
+	if err := tx.Get(&devices, "SELECT COUNT(*) FROM whatsmeow_device WHERE jid = $1", session.Jid); err != nil {
+		return nil, err
+	}
+	if devices > 0 {
+		return nil, ErrSessionExists
+	}
+
+	for _, table := range deviceTables {
+		for _, row := range export.Device[table.name] {
+			if jid, ok := row[table.jid].Value.(string); !ok || jid != session.Jid {
+				return nil, fmt.Errorf("%w: %s holds a row of another device", ErrBadExport, table.name)
+			}
+
+			columns := make([]string, 0, len(row))
+			placeholders := make([]string, 0, len(row))
+			args := make([]any, 0, len(row))
+			for column, value := range row {
+				if !columnName.MatchString(column) {
+					return nil, fmt.Errorf("%w: bad column %q in %s", ErrBadExport, column, table.name)
+				}
+				columns = append(columns, column)
+				args = append(args, value.Value)
+				placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+			}
+
+			_, err := tx.Exec(
+				fmt.Sprintf(
+					"INSERT INTO %s (%s) VALUES (%s)",
+					table.name,
+					strings.Join(columns, ", "),
+					strings.Join(placeholders, ", "),
+				),
+				args...,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", table.name, err)
+			}
+		}
+	}
+
+	if err := w.users.ImportUser(tx, &session); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
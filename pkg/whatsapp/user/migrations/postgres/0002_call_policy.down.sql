@@ -0,0 +1,2 @@
+ALTER TABLE whatsapp_users DROP COLUMN IF EXISTS call_reply;
+ALTER TABLE whatsapp_users DROP COLUMN IF EXISTS call_policy;
@@ -0,0 +1,2 @@
+ALTER TABLE whatsapp_users ADD COLUMN IF NOT EXISTS call_policy TEXT NOT NULL DEFAULT 'notify';
+ALTER TABLE whatsapp_users ADD COLUMN IF NOT EXISTS call_reply TEXT NOT NULL DEFAULT '';
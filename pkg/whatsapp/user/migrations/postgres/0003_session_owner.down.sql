@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS whatsapp_users_owner_id_index;
+
+ALTER TABLE whatsapp_users DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,3 @@
+ALTER TABLE whatsapp_users ADD COLUMN IF NOT EXISTS owner_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS whatsapp_users_owner_id_index ON whatsapp_users (owner_id);
@@ -0,0 +1,11 @@
+CREATE TABLE whatsapp_users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	token TEXT NOT NULL,
+	webhook TEXT NOT NULL DEFAULT '',
+	jid TEXT NOT NULL DEFAULT '',
+	qrcode TEXT NOT NULL DEFAULT '',
+	connected INTEGER,
+	expiration INTEGER,
+	events TEXT NOT NULL DEFAULT 'All'
+);
@@ -0,0 +1,3 @@
+ALTER TABLE whatsapp_users ADD COLUMN owner_id BIGINT;
+
+CREATE INDEX whatsapp_users_owner_id_index ON whatsapp_users (owner_id);
@@ -3,7 +3,9 @@ package user
 import (
 	"database/sql"
 	"errors"
+	"io/fs"
 
+	"github.com/jmoiron/sqlx"
 	"go.mau.fi/whatsmeow/types"
 
 	"github.com/nugrhrizki/buzz/pkg/database"
@@ -19,8 +21,8 @@ func NewRepository(db *database.Database, log *zerolog.Logger) *Repository {
 	return &Repository{db, log}
 }
 
-func (r *Repository) Migration() string {
-	return New()
+func (r *Repository) Migrations() (string, fs.FS) {
+	return "whatsapp_user", migrations
 }
 
 func (r *Repository) CreateUser(user *User) error {
@@ -35,10 +37,12 @@ func (r *Repository) CreateUser(user *User) error {
 		return err
 	}
 
-	_, err = r.db.Exec(
-		"INSERT INTO whatsapp_users (name, token) VALUES ($1, $2)",
+	err = r.db.Get(
+		&user.Id,
+		"INSERT INTO whatsapp_users (name, token, owner_id) VALUES ($1, $2, $3) RETURNING id",
 		user.Name,
 		user.Token,
+		user.OwnerId,
 	)
 	if err != nil {
 		r.log.Error().Err(err).Msg("failed to create user")
@@ -47,11 +51,34 @@ func (r *Repository) CreateUser(user *User) error {
 	return nil
 }
 
+// ImportUser inserts a session moved from another instance as part of tx.
+// It keeps the settings of the session but gets a new id and no owner.
+func (r *Repository) ImportUser(tx *sqlx.Tx, user *User) error {
+	user.OwnerId = nil
+	return tx.Get(
+		&user.Id,
+		`INSERT INTO whatsapp_users
+			(name, token, webhook, jid, connected, expiration, events, call_policy, call_reply)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		user.Name,
+		user.Token,
+		user.Webhook,
+		user.Jid,
+		user.Connected,
+		user.Expiration,
+		user.Events,
+		user.CallPolicy,
+		user.CallReply,
+	)
+}
+
 func (r *Repository) UpdateUser(user *User) error {
 	_, err := r.db.Exec(
-		"UPDATE whatsapp_users SET name = $1, token = $2 WHERE id = $3",
+		"UPDATE whatsapp_users SET name = $1, token = $2, owner_id = $3 WHERE id = $4",
 		user.Name,
 		user.Token,
+		user.OwnerId,
 		user.Id,
 	)
 	if err != nil {
@@ -99,6 +126,21 @@ func (r *Repository) GetUsers() ([]User, error) {
 	return users, nil
 }
 
+func (r *Repository) GetUsersByOwner(ownerId int64) ([]User, error) {
+	users := []User{}
+	err := r.db.DB.Select(
+		&users,
+		"SELECT * FROM whatsapp_users WHERE owner_id = $1 ORDER BY id DESC",
+		ownerId,
+	)
+	if err != nil {
+		r.log.Error().Err(err).Msg("failed to get users by owner")
+		return nil, err
+	}
+
+	return users, nil
+}
+
 func (r *Repository) GetUserById(id int) (*User, error) {
 	var user User
 	err := r.db.Get(
@@ -117,6 +159,7 @@ func (r *Repository) GetUserByJid(jid string) (*User, error) {
 	err := r.db.Get(
 		&user,
 		"SELECT * FROM whatsapp_users WHERE jid = $1",
+		jid,
 	)
 	if err != nil {
 		return nil, err
@@ -209,3 +252,16 @@ func (r *Repository) SetWebhook(id int, webhook string) error {
 	}
 	return nil
 }
+
+func (r *Repository) SetCallPolicy(id int, policy string, reply string) error {
+	_, err := r.db.Exec(
+		"UPDATE whatsapp_users SET call_policy = $1, call_reply = $2 WHERE id = $3",
+		policy,
+		reply,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/nugrhrizki/buzz/pkg/database"
+	"github.com/nugrhrizki/buzz/pkg/database/databasetest"
+)
+
+func TestRepository(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		users := NewRepository(db, databasetest.Logger())
+		databasetest.Migrate(t, db, users)
+
+		var owner int64 = 7
+		first := &User{Name: "first", Token: "token-1", OwnerId: &owner}
+		second := &User{Name: "second", Token: "token-2"}
+		for _, user := range []*User{first, second} {
+			if err := users.CreateUser(user); err != nil {
+				t.Fatalf("create %s: %v", user.Name, err)
+			}
+			if user.Id == 0 {
+				t.Errorf("create %s did not return the id", user.Name)
+			}
+		}
+		if err := users.CreateUser(&User{Name: "copy", Token: "token-1"}); err == nil {
+			t.Error("creating a session with a used token should fail")
+		}
+
+		jid := types.NewJID("6281234567890", types.DefaultUserServer)
+		if err := users.SetUserJid(first.Id, jid); err != nil {
+			t.Fatalf("set jid: %v", err)
+		}
+		if err := users.SetUserConnected(first.Id, 1); err != nil {
+			t.Fatalf("set connected: %v", err)
+		}
+		if err := users.SetQRCode(first.Id, "qr"); err != nil {
+			t.Fatalf("set qrcode: %v", err)
+		}
+		if err := users.SetEvents(first.Id, "Message,ReadReceipt"); err != nil {
+			t.Fatalf("set events: %v", err)
+		}
+		if err := users.SetWebhook(first.Id, "https://example.com/hook"); err != nil {
+			t.Fatalf("set webhook: %v", err)
+		}
+		if err := users.SetCallPolicy(first.Id, "reject", "busy"); err != nil {
+			t.Fatalf("set call policy: %v", err)
+		}
+
+		lookups := []struct {
+			name   string
+			lookup func() (*User, error)
+		}{
+			{"by id", func() (*User, error) { return users.GetUserById(first.Id) }},
+			{"by token", func() (*User, error) { return users.GetUserByToken("token-1") }},
+			{"by jid", func() (*User, error) { return users.GetUserByJid(jid.String()) }},
+		}
+		for _, tt := range lookups {
+			user, err := tt.lookup()
+			if err != nil {
+				t.Fatalf("get %s: %v", tt.name, err)
+			}
+			if user.Id != first.Id ||
+				user.Jid != jid.String() ||
+				user.Connected == nil || *user.Connected != 1 ||
+				user.Qrcode != "qr" ||
+				user.Events != "Message,ReadReceipt" ||
+				user.Webhook != "https://example.com/hook" ||
+				user.CallPolicy != "reject" || user.CallReply != "busy" ||
+				user.OwnerId == nil || *user.OwnerId != owner {
+				t.Errorf("get %s: got %+v", tt.name, user)
+			}
+		}
+		if qrcode, err := users.GetQRCode(first.Id); err != nil || qrcode != "qr" {
+			t.Errorf("qrcode: got %q, %v", qrcode, err)
+		}
+
+		lists := []struct {
+			name  string
+			list  func() ([]User, error)
+			names []string
+		}{
+			{"all", users.GetUsers, []string{"second", "first"}},
+			{"connected", users.GetConnectedUser, []string{"first"}},
+			{"by owner", func() ([]User, error) { return users.GetUsersByOwner(owner) }, []string{"first"}},
+			{"by other owner", func() ([]User, error) { return users.GetUsersByOwner(owner + 1) }, nil},
+		}
+		for _, tt := range lists {
+			got, err := tt.list()
+			if err != nil {
+				t.Fatalf("list %s: %v", tt.name, err)
+			}
+			if len(got) != len(tt.names) {
+				t.Errorf("list %s: got %d sessions, want %d", tt.name, len(got), len(tt.names))
+				continue
+			}
+			for i, name := range tt.names {
+				if got[i].Name != name {
+					t.Errorf("list %s: session %d is %s, want %s", tt.name, i, got[i].Name, name)
+				}
+			}
+		}
+
+		second.Name = "renamed"
+		second.OwnerId = &owner
+		if err := users.UpdateUser(second); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if owned, _ := users.GetUsersByOwner(owner); len(owned) != 2 {
+			t.Errorf("got %d sessions of the owner after the update, want 2", len(owned))
+		}
+
+		if err := users.DeleteUser(second); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := users.GetUserById(second.Id); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("deleted session: got %v, want %v", err, sql.ErrNoRows)
+		}
+	})
+}
+
+func TestImportUser(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *database.Database) {
+		users := NewRepository(db, databasetest.Logger())
+		databasetest.Migrate(t, db, users)
+
+		var owner int64 = 7
+		imported := &User{Name: "moved", Token: "token", Events: "All", CallPolicy: "notify", OwnerId: &owner}
+		err := func() error {
+			tx, err := db.DB.Beginx()
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			if err := users.ImportUser(tx, imported); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}()
+		if err != nil {
+			t.Fatalf("import: %v", err)
+		}
+
+		user, err := users.GetUserById(imported.Id)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if user.Name != "moved" || user.OwnerId != nil {
+			t.Errorf("imported session: got %+v", user)
+		}
+	})
+}
@@ -1,5 +1,7 @@
 package user
 
+import "embed"
+
 type User struct {
 	Id         int    `db:"id"         json:"id"`
 	Name       string `db:"name"       json:"name"`
@@ -10,6 +12,58 @@ type User struct {
 	Connected  *int   `db:"connected"  json:"connected"`
 	Expiration *int   `db:"expiration" json:"expiration"`
 	Events     string `db:"events"     json:"events"`
+	CallPolicy string `db:"call_policy" json:"call_policy"`
+	CallReply  string `db:"call_reply"  json:"call_reply"`
+	OwnerId    *int64 `db:"owner_id"    json:"owner_id"`
+}
+
+// Session is the view of a session that is safe to list, it leaves out the
+// token and the QR code
+type Session struct {
+	Id         int    `json:"id"`
+	Name       string `json:"name"`
+	TokenHint  string `json:"token_hint"`
+	Webhook    string `json:"webhook"`
+	Jid        string `json:"jid"`
+	Connected  *int   `json:"connected"`
+	Expiration *int   `json:"expiration"`
+	Events     string `json:"events"`
+	CallPolicy string `json:"call_policy"`
+	OwnerId    *int64 `json:"owner_id"`
+}
+
+func (u *User) Session() Session {
+	hint := "****"
+	if len(u.Token) > 8 {
+		hint = u.Token[:4] + "****"
+	}
+
+	return Session{
+		Id:         u.Id,
+		Name:       u.Name,
+		TokenHint:  hint,
+		Webhook:    u.Webhook,
+		Jid:        u.Jid,
+		Connected:  u.Connected,
+		Expiration: u.Expiration,
+		Events:     u.Events,
+		CallPolicy: u.CallPolicy,
+		OwnerId:    u.OwnerId,
+	}
+}
+
+const (
+	CallPolicyNotify      = "notify"
+	CallPolicyReject      = "reject"
+	CallPolicyRejectReply = "reject_reply"
+)
+
+func ValidCallPolicy(policy string) bool {
+	switch policy {
+	case CallPolicyNotify, CallPolicyReject, CallPolicyRejectReply:
+		return true
+	}
+	return false
 }
 
 type UserInfo struct {
@@ -20,16 +74,7 @@ type UserInfo struct {
 	Events  string `json:"events"`
 }
 
-func New() string {
-	return `CREATE TABLE IF NOT EXISTS whatsapp_users (
-		id BIGSERIAL PRIMARY KEY,
-		name TEXT NOT NULL,
-		token TEXT NOT NULL,
-		webhook TEXT NOT NULL DEFAULT '',
-		jid TEXT NOT NULL DEFAULT '',
-		qrcode TEXT NOT NULL DEFAULT '',
-		connected INTEGER,
-		expiration INTEGER,
-		events TEXT NOT NULL DEFAULT 'All'
-	);`
-}
+// migrations holds the versioned schema scripts of this module
+//
+//go:embed migrations
+var migrations embed.FS
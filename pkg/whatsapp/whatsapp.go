@@ -17,10 +17,13 @@ import (
 	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
-	_ "modernc.org/sqlite"
 
-	"github.com/nugrhrizki/buzz/pkg/env"
+	"github.com/nugrhrizki/buzz/pkg/config"
+	"github.com/nugrhrizki/buzz/pkg/database"
+	buzzLog "github.com/nugrhrizki/buzz/pkg/log"
+	"github.com/nugrhrizki/buzz/pkg/metrics"
 	"github.com/nugrhrizki/buzz/pkg/utils"
+	"github.com/nugrhrizki/buzz/pkg/whatsapp/message"
 	"github.com/nugrhrizki/buzz/pkg/whatsapp/user"
 )
 
@@ -31,32 +34,57 @@ type Whatsapp struct {
 
 	container     *sqlstore.Container
 	userInfoCache *cache.Cache
+	sentSpans     *cache.Cache
 	log           *zerolog.Logger
 
-	users *user.Repository
+	mediaPath      string
+	webhookTimeout time.Duration
+	whatsmeowLevel string
+
+	// qrHandler is told about every pairing code, e.g. to print it
+	qrHandler func(userID int, code string)
+	// autoDownload decides per session whether received media is stored
+	autoDownload func(userID int) bool
+
+	db      *database.Database
+	metrics *metrics.Metrics
+
+	users    *user.Repository
+	messages *message.Repository
 }
 
+// userInfoTTL bounds how long a changed session takes to show in the event
+// handler and the api
+const userInfoTTL = 5 * time.Minute
+
 var MessageTypes = []string{
 	"Message",
 	"ReadReceipt",
 	"Presence",
 	"HistorySync",
 	"ChatPresence",
+	"ChatState",
+	"Status",
+	"Call",
 	"All",
 }
 
 func New(
 	users *user.Repository,
+	messages *message.Repository,
 	log *zerolog.Logger,
-	env *env.Env,
+	db *database.Database,
+	config *config.Config,
+	metrics *metrics.Metrics,
 ) *Whatsapp {
-	container, err := sqlstore.New(
-		env.DB_DRIVER,
-		env.DB_DSN,
-		nil,
-	)
+	// whatsmeow keeps its tables next to ours, it calls sqlite "sqlite3"
+	dialect := db.Dialect()
+	if dialect == database.SQLite {
+		dialect = "sqlite3"
+	}
 
-	if err != nil {
+	container := sqlstore.NewWithDB(db.DB.DB, dialect, buzzLog.Whatsmeow(log, config.Log.WhatsmeowLevel, "Database"))
+	if err := container.Upgrade(); err != nil {
 		panic(err)
 	}
 
@@ -66,13 +94,37 @@ func New(
 		killchannel: make(map[int](chan bool)),
 
 		container:     container,
-		userInfoCache: cache.New(5*time.Minute, 10*time.Minute),
+		userInfoCache: cache.New(userInfoTTL, 2*userInfoTTL),
+		sentSpans:     cache.New(sentSpanTTL, sentSpanTTL/2),
 		log:           log,
 
-		users: users,
+		mediaPath:      config.Whatsapp.MediaPath,
+		webhookTimeout: config.Whatsapp.WebhookTimeout,
+		whatsmeowLevel: config.Log.WhatsmeowLevel,
+
+		db:      db,
+		metrics: metrics,
+
+		users:    users,
+		messages: messages,
 	}
 }
 
+// OnAutoDownload lets decide tell whether the media a session receives is
+// downloaded, it always is without one
+func (w *Whatsapp) OnAutoDownload(decide func(userID int) bool) {
+	w.autoDownload = decide
+}
+
+func (w *Whatsapp) downloadsMedia(userID int) bool {
+	return w.autoDownload == nil || w.autoDownload(userID)
+}
+
+// OnQRCode calls handler with every pairing code a session is shown
+func (w *Whatsapp) OnQRCode(handler func(userID int, code string)) {
+	w.qrHandler = handler
+}
+
 // Connects to Whatsapp Websocket on server startup if last state was connected
 func (w *Whatsapp) ConnectOnStartup() {
 	users, err := w.users.GetConnectedUser()
@@ -82,7 +134,7 @@ func (w *Whatsapp) ConnectOnStartup() {
 	}
 
 	for _, u := range users {
-		w.log.Info().Str("token", u.Token).Msg("Connect to Whatsapp on startup")
+		w.log.Info().Int("session", u.Id).Msg("Connect to Whatsapp on startup")
 
 		userInfo := user.UserInfo{
 			Id:      strconv.Itoa(u.Id),
@@ -92,7 +144,7 @@ func (w *Whatsapp) ConnectOnStartup() {
 			Events:  u.Events,
 		}
 
-		w.userInfoCache.Set(u.Token, userInfo, cache.NoExpiration)
+		w.UpdateCacheUserInfo(u.Token, userInfo)
 		// Gets and set subscription to webhook events
 		eventarray := strings.Split(u.Events, ",")
 
@@ -121,7 +173,8 @@ func (w *Whatsapp) ConnectOnStartup() {
 }
 
 func (w *Whatsapp) StartClient(userID int, textjid string, token string, subscriptions []string) {
-	w.log.Info().
+	log := w.log.With().Int("session", userID).Logger()
+	log.Info().
 		Str("userid", strconv.Itoa(userID)).
 		Str("jid", textjid).
 		Msg("Starting websocket connection to Whatsapp")
@@ -143,12 +196,12 @@ func (w *Whatsapp) StartClient(userID int, textjid string, token string, subscri
 			panic(err)
 		}
 	} else {
-		w.log.Warn().Msg("No jid found. Creating new device")
+		log.Warn().Msg("No jid found. Creating new device")
 		deviceStore = w.container.NewDevice()
 	}
 
 	if deviceStore == nil {
-		w.log.Warn().Msg("No store found. Creating new one")
+		log.Warn().Msg("No store found. Creating new one")
 		deviceStore = w.container.NewDevice()
 	}
 
@@ -156,7 +209,11 @@ func (w *Whatsapp) StartClient(userID int, textjid string, token string, subscri
 	store.DeviceProps.PlatformType = waProto.DeviceProps_CHROME.Enum()
 	store.DeviceProps.Os = &osName
 
-	wclient := whatsmeow.NewClient(deviceStore, nil)
+	wclient := whatsmeow.NewClient(deviceStore, buzzLog.Whatsmeow(&log, w.whatsmeowLevel, "Client"))
+	wclient.AutoReconnectHook = func(err error) bool {
+		w.metrics.Reconnect(err)
+		return true
+	}
 
 	w.clientStore[userID] = wclient
 	client := NewClient(
@@ -170,7 +227,7 @@ func (w *Whatsapp) StartClient(userID int, textjid string, token string, subscri
 	client.SetEventHandlerID(client.WAClient.AddEventHandler(client.EventHandler))
 	w.clientHttp[userID] = resty.New()
 	w.clientHttp[userID].SetRedirectPolicy(resty.FlexibleRedirectPolicy(15))
-	w.clientHttp[userID].SetTimeout(5 * time.Second)
+	w.clientHttp[userID].SetTimeout(w.webhookTimeout)
 	w.clientHttp[userID].SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
 
 	if wclient.Store.ID == nil {
@@ -180,7 +237,7 @@ func (w *Whatsapp) StartClient(userID int, textjid string, token string, subscri
 		if err != nil {
 			// This error means that we're already logged in, so ignore it.
 			if !errors.Is(err, whatsmeow.ErrQRStoreContainsID) {
-				w.log.Error().Err(err).Msg("Failed to get QR channel")
+				log.Error().Err(err).Msg("Failed to get QR channel")
 			}
 		} else {
 			err = wclient.Connect()
@@ -190,39 +247,43 @@ func (w *Whatsapp) StartClient(userID int, textjid string, token string, subscri
 			for evt := range qrChan {
 				switch evt.Event {
 				case "code":
-					// Display QR code in terminal (useful for testing/developing)
+					if w.qrHandler != nil {
+						w.qrHandler(userID, evt.Code)
+					}
 					// Store encoded/embeded base64 QR on database for retrieval with the /qr endpoint
 					image, _ := qrcode.Encode(evt.Code, qrcode.Medium, 256)
 					base64qrcode := "data:image/png;base64," + base64.StdEncoding.EncodeToString(image)
 					err := w.users.SetQRCode(userID, base64qrcode)
 					if err != nil {
-						w.log.Error().Err(err).Msg("Failed to set QR code")
+						log.Error().Err(err).Msg("Failed to set QR code")
 					}
 				case "timeout":
 					// Clear QR code from DB on timeout
 					err = w.users.SetQRCode(userID, "")
 					if err != nil {
-						w.log.Error().Err(err).Msg("Failed to clear QR code")
+						log.Error().Err(err).Msg("Failed to clear QR code")
 					}
-					w.log.Warn().Msg("QR timeout killing channel")
+					log.Warn().Msg("QR timeout killing channel")
+					w.metrics.Pairing(metrics.Timeout)
 					delete(w.clientStore, userID)
 					w.killchannel[userID] <- true
 				case "success":
-					w.log.Info().Msg("QR pairing ok!")
+					log.Info().Msg("QR pairing ok!")
+					w.metrics.Pairing(metrics.Success)
 					// Clear QR code after pairing
 					err = w.users.SetQRCode(userID, "")
 					if err != nil {
-						w.log.Error().Err(err).Msg("Failed to clear QR code")
+						log.Error().Err(err).Msg("Failed to clear QR code")
 					}
 				default:
-					w.log.Info().Str("event", evt.Event).Msg("Login event")
+					log.Info().Str("event", evt.Event).Msg("Login event")
 				}
 			}
 		}
 
 	} else {
 		// Already logged in, just connect
-		w.log.Info().Msg("Already logged in, just connect")
+		log.Info().Msg("Already logged in, just connect")
 		err = wclient.Connect()
 		if err != nil {
 			panic(err)
@@ -233,12 +294,13 @@ func (w *Whatsapp) StartClient(userID int, textjid string, token string, subscri
 	for {
 		select {
 		case <-w.killchannel[userID]:
-			w.log.Info().Str("userid", strconv.Itoa(userID)).Msg("Received kill signal")
+			log.Info().Str("userid", strconv.Itoa(userID)).Msg("Received kill signal")
 			wclient.Disconnect()
 			delete(w.clientStore, userID)
+			w.metrics.SessionState(userID, false, false)
 			err = w.users.SetUserConnected(userID, 0)
 			if err != nil {
-				w.log.Error().Err(err).Msg("Failed to set user disconnected")
+				log.Error().Err(err).Msg("Failed to set user disconnected")
 			}
 			return
 		default:
@@ -2,6 +2,7 @@ import { toggleSidebarPin } from "@/store/global";
 import { ColorModeContextType } from "@kobalte/core";
 import { Navigator } from "@solidjs/router";
 
+import { request } from "@/lib/request";
 import { Menu } from "@/types";
 
 export type ActionCtx = {
@@ -110,7 +111,7 @@ function logoutAction(ctx: ActionCtx) {
     title: "Logout",
     mdIcon: "logout",
     handler: () => {
-      ctx.navigate("/auth");
+      request.post("/api/v1/auth/logout").finally(() => ctx.navigate("/auth"));
     },
   };
 }
@@ -19,7 +19,7 @@ interface SenderInfoProps {
 
 export function SenderInfo(props: SenderInfoProps) {
   const avatar = useAvatar(
-    () => props.sender?.token || "",
+    () => props.sender?.id || 0,
     () => props.sender?.jid || "",
   );
 
@@ -55,7 +55,7 @@ export function SenderInfo(props: SenderInfoProps) {
             <TableRow>
               <TableCell class="w-[1%] whitespace-nowrap">Token</TableCell>
               <TableCell class="w-[1%] whitespace-nowrap">:</TableCell>
-              <TableCell>{props.sender?.token || "-"}</TableCell>
+              <TableCell>{props.sender?.token_hint || "-"}</TableCell>
             </TableRow>
             <TableRow>
               <TableCell class="w-[1%] whitespace-nowrap">JID</TableCell>
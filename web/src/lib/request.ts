@@ -1,4 +1,4 @@
-import axios from "axios";
+import axios, { InternalAxiosRequestConfig } from "axios";
 
 const request = axios.create({
   baseURL: "http://localhost:3000",
@@ -6,6 +6,38 @@ const request = axios.create({
   timeout: 30000,
 });
 
+type RetryConfig = InternalAxiosRequestConfig & { _retried?: boolean };
+
+const noRefresh = ["/api/v1/auth/login", "/api/v1/auth/refresh", "/api/v1/auth/logout"];
+
+// Access tokens are short lived, trade the refresh token for a new one once
+// and replay the request before giving up.
+let refreshing: Promise<unknown> | null = null;
+
+async function refreshHandler(error: unknown) {
+  if (!axios.isAxiosError(error) || error.response?.status !== 401 || !error.config) {
+    throw error;
+  }
+
+  const config = error.config as RetryConfig;
+  if (config._retried || noRefresh.includes(config.url ?? "")) {
+    throw error;
+  }
+  config._retried = true;
+
+  refreshing ??= request.post("/api/v1/auth/refresh").finally(() => {
+    refreshing = null;
+  });
+
+  try {
+    await refreshing;
+  } catch {
+    throw error;
+  }
+  return request(config);
+}
+
+request.interceptors.response.use(null, refreshHandler);
 request.interceptors.response.use(null, requestErrorHandler, {
   synchronous: true,
 });
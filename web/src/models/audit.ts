@@ -0,0 +1,34 @@
+export interface AuditEvent {
+  id: number;
+  actor_type: string;
+  actor_id: string;
+  actor_name: string;
+  action: string;
+  target_type: string;
+  target_id: string;
+  method: string;
+  path: string;
+  status: number;
+  ip: string;
+  user_agent: string;
+  before: Record<string, unknown> | null;
+  after: Record<string, unknown> | null;
+  created_at: string;
+}
+
+export interface AuditFilter {
+  actor_type?: string;
+  actor_id?: string;
+  action?: string;
+  target_type?: string;
+  target_id?: string;
+  from?: string;
+  to?: string;
+  limit?: number;
+  offset?: number;
+}
+
+export interface AuditPage {
+  events: AuditEvent[];
+  total: number;
+}
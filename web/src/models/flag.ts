@@ -0,0 +1,18 @@
+export interface Flag {
+  id: number;
+  name: string;
+  description: string;
+  enabled: boolean;
+  rollout: number;
+  sessions: number[];
+  roles: number[];
+  created_at: string;
+  updated_at: string | null;
+}
+
+export type SaveFlag = Omit<Flag, "id" | "created_at" | "updated_at">;
+
+export interface FlagList {
+  flags: Flag[];
+  defaults: Record<string, boolean>;
+}
@@ -0,0 +1,16 @@
+export interface LogEntry {
+  id: number;
+  time: string;
+  level: string;
+  message: string;
+  fields: Record<string, unknown>;
+}
+
+export interface LogFilter {
+  level?: string;
+  session?: string;
+  text?: string;
+  from?: string;
+  to?: string;
+  limit?: number;
+}
@@ -8,6 +8,8 @@ export const senderSchema = z.object({
   token: z.string().min(1, {
     message: "Token is required",
   }),
+  token_hint: z.string().optional().nullable().nullish(),
+  owner_id: z.number().optional().nullable().nullish(),
   webhook: z.string().optional().nullable().nullish(),
   jid: z.string().optional().nullable().nullish(),
   qrcode: z.string().optional().nullable().nullish(),
@@ -18,6 +20,8 @@ export const senderSchema = z.object({
 
 export const createSenderSchema = senderSchema.omit({
   id: true,
+  token_hint: true,
+  owner_id: true,
   webhook: true,
   jid: true,
   qrcode: true,
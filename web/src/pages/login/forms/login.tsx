@@ -1,7 +1,7 @@
 import { createForm, zodForm } from "@modular-forms/solid";
 import { useNavigate } from "@solidjs/router";
 import { TbLoader } from "solid-icons/tb";
-import { Show } from "solid-js";
+import { Show, createSignal } from "solid-js";
 
 import { useLoginUser } from "@/services/auth";
 
@@ -16,6 +16,7 @@ import { type AuthForm, authFormSchema } from "../validations/auth";
 export function LoginForm() {
   const navigate = useNavigate();
   const login = useLoginUser();
+  const [mfaRequired, setMfaRequired] = createSignal(false);
   const [authForm, { Form, Field }] = createForm<AuthForm>({
     validate: zodForm(authFormSchema),
   });
@@ -28,6 +29,14 @@ export function LoginForm() {
       onError: async (error) => {
         const response = error as unknown as Response;
         const data = await response.json();
+        if (data.data?.mfa_required) {
+          setMfaRequired(true);
+          showToast({
+            title: data.title,
+            description: data.message,
+          });
+          return;
+        }
         showToast({
           title: "Failed to login",
           description: data.message,
@@ -63,6 +72,19 @@ export function LoginForm() {
               </Grid>
             )}
           </Field>
+          <Show when={mfaRequired()}>
+            <Field name="code">
+              {(field, props) => (
+                <Grid class="gap-1">
+                  <Label for="code">Authentication code</Label>
+                  <Input {...props} type="text" id="code" inputmode="numeric" autocomplete="one-time-code" />
+                  <Show when={field.error}>
+                    <p class="text-destructive text-xs">{field.error}</p>
+                  </Show>
+                </Grid>
+              )}
+            </Field>
+          </Show>
           <Button type="submit" disabled={authForm.submitting} class="mt-8">
             <Show when={authForm.submitting}>
               <TbLoader class="mr-2 h-4 w-4 animate-spin" />
@@ -7,6 +7,7 @@ export const authFormSchema = z.object({
   password: z.string().min(1, {
     message: "Password cannot be empty",
   }),
+  code: z.string().optional(),
 });
 
 export type AuthForm = z.infer<typeof authFormSchema>;
@@ -29,7 +29,7 @@ export const columns: ColumnDefiniton[] = [
     enableHiding: false,
   },
   {
-    accessorKey: "token",
+    accessorKey: "token_hint",
     header: (header) => <DataTable.ColumnHeader column={header.column} title="Token" />,
   },
   {
@@ -151,7 +151,7 @@ export function TableDetail(props: ParentProps<TableDetailProps>) {
               <TableRow>
                 <TableCell class="w-[1%] whitespace-nowrap">Token</TableCell>
                 <TableCell class="w-[1%] whitespace-nowrap">:</TableCell>
-                <TableCell>{props.sender.token || "-"}</TableCell>
+                <TableCell>{props.sender.token_hint || "-"}</TableCell>
               </TableRow>
               <TableRow>
                 <TableCell class="w-[1%] whitespace-nowrap">Status</TableCell>
@@ -26,7 +26,7 @@ import { Table, TableBody, TableCell, TableRow } from "@/components/ui/table";
 
 type ColumnDefiniton = ColumnDef<Contact>;
 
-export function columns(token: string): ColumnDefiniton[] {
+export function columns(session: number): ColumnDefiniton[] {
   return [
     DataTable.RowExpand(),
     {
@@ -34,7 +34,7 @@ export function columns(token: string): ColumnDefiniton[] {
       header: (header) => <DataTable.ColumnHeader column={header.column} title="Avatar" />,
       cell: (cell) => {
         const avatar = useAvatar(
-          () => token,
+          () => session,
           () => cell.row.original.jid,
         );
 
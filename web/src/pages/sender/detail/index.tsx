@@ -28,7 +28,7 @@ function SenderDetailPage() {
   const [status, setStatus] = createSignal<string>("");
   const [contacts, setContacts] = createSignal<Contact[]>([]);
   const [table, setTable] = createSignal<TanstackTable<Contact>>(
-    createContactsTable(contacts(), columns(sender.data?.token || "")),
+    createContactsTable(contacts(), columns(sender.data?.id || 0)),
   );
 
   onCleanup(() => {
@@ -44,7 +44,7 @@ function SenderDetailPage() {
 
   createEffect(() => {
     if (status() === STATUS.CONNECTED) {
-      getContacts(sender.data?.token || "").then((data) => {
+      getContacts(sender.data?.id || 0).then((data) => {
         if (data === null) {
           return;
         }
@@ -66,11 +66,11 @@ function SenderDetailPage() {
   });
 
   createEffect(() => {
-    setTable(createContactsTable(contacts(), columns(sender.data?.token || "")));
+    setTable(createContactsTable(contacts(), columns(sender.data?.id || 0)));
   });
 
   createEffect(() => {
-    statusRequest(sender.data?.token || "").then((status) => {
+    statusRequest(sender.data?.id || 0).then((status) => {
       if (status === null) {
         setStatus(STATUS.NOT_CONNECTED);
         return;
@@ -82,13 +82,13 @@ function SenderDetailPage() {
             showQR();
           } else {
             setStatus(STATUS.NOT_CONNECTED);
-            connect(sender.data?.token || "").then(() => {
+            connect(sender.data?.id || 0).then(() => {
               setStatus(STATUS.CONNECTED);
             });
           }
         } else {
           if (status.data.connected === false) {
-            connect(sender.data?.token || "").then(() => {
+            connect(sender.data?.id || 0).then(() => {
               setStatus(STATUS.CONNECTED);
             });
           }
@@ -97,7 +97,7 @@ function SenderDetailPage() {
         }
       } else if (status.success == false) {
         if (status.error == "no session") {
-          connect(sender.data?.token || "").then((data) => {
+          connect(sender.data?.id || 0).then((data) => {
             if (data.success === true) {
               showQR();
             } else {
@@ -115,7 +115,7 @@ function SenderDetailPage() {
   });
 
   function checkStatus() {
-    statusRequest(sender.data?.token || "").then((status) => {
+    statusRequest(sender.data?.id || 0).then((status) => {
       if (status === null) {
         setStatus(STATUS.NOT_CONNECTED);
         return;
@@ -156,7 +156,7 @@ function SenderDetailPage() {
     setStatus(STATUS.SHOW_QR);
     while (!scanned()) {
       setStatus(STATUS.NOT_SCANNED);
-      var data = await getQR(sender.data?.token || "");
+      var data = await getQR(sender.data?.id || 0);
       if (data === null) {
         setStatus(STATUS.NOT_CONNECTED);
         return;
@@ -70,7 +70,7 @@ export function EditSenderForm(props: EditSenderFormProps) {
     validate: zodForm(createSenderSchema),
     initialValues: {
       name: props.sender.name,
-      token: props.sender.token,
+      token: props.sender.token || props.sender.token_hint || "",
     },
   });
 
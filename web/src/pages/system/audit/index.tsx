@@ -0,0 +1,125 @@
+import { TbDownload } from "solid-icons/tb";
+import { For, Show, createSignal } from "solid-js";
+
+import { formatDate } from "@/lib/utils";
+
+import { AuditFilter } from "@/models/audit";
+
+import { auditExportUrl, useAuditEvents } from "@/services/audit";
+
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from "@/components/ui/table";
+
+const pageSize = 50;
+
+function changes(before: Record<string, unknown> | null, after: Record<string, unknown> | null) {
+  const fields = new Set([...Object.keys(before ?? {}), ...Object.keys(after ?? {})]);
+  return [...fields].map(
+    (field) => `${field}: ${JSON.stringify(before?.[field] ?? null)} → ${JSON.stringify(after?.[field] ?? null)}`,
+  );
+}
+
+function AuditPage() {
+  const [filter, setFilter] = createSignal<AuditFilter>({ limit: pageSize, offset: 0 });
+  const events = useAuditEvents(filter);
+
+  const update = (field: keyof AuditFilter) => (event: { target: HTMLInputElement }) => {
+    setFilter((current) => ({ ...current, [field]: event.target.value, offset: 0 }));
+  };
+
+  const page = () => Math.floor((filter().offset ?? 0) / pageSize) + 1;
+  const pages = () => Math.max(1, Math.ceil((events.data?.total ?? 0) / pageSize));
+
+  return (
+    <div class="space-y-4 p-8 pt-6">
+      <div class="flex items-center justify-between space-y-2">
+        <h2 class="text-3xl font-bold tracking-tight">Audit</h2>
+        <a href={auditExportUrl(filter())} target="_blank">
+          <Button variant="outline">
+            <TbDownload class="w-5 h-5 mr-2" />
+            Export CSV
+          </Button>
+        </a>
+      </div>
+      <div class="flex flex-wrap items-end gap-4">
+        <Input placeholder="Action, e.g. user." class="max-w-48" onInput={update("action")} />
+        <Input placeholder="Actor type" class="max-w-36" onInput={update("actor_type")} />
+        <Input placeholder="Actor id" class="max-w-36" onInput={update("actor_id")} />
+        <Input placeholder="Target type" class="max-w-36" onInput={update("target_type")} />
+        <Input placeholder="Target id" class="max-w-36" onInput={update("target_id")} />
+        <Input type="date" class="max-w-40" onInput={update("from")} />
+        <Input type="date" class="max-w-40" onInput={update("to")} />
+      </div>
+      <div class="rounded-md border">
+        <Table>
+          <TableHeader>
+            <TableRow>
+              <TableHead>Time</TableHead>
+              <TableHead>Actor</TableHead>
+              <TableHead>Action</TableHead>
+              <TableHead>Target</TableHead>
+              <TableHead>Request</TableHead>
+              <TableHead>Changes</TableHead>
+            </TableRow>
+          </TableHeader>
+          <TableBody>
+            <Show
+              when={events.data?.events.length}
+              fallback={
+                <TableRow>
+                  <TableCell colSpan={6} class="h-24 text-center">
+                    No audit events.
+                  </TableCell>
+                </TableRow>
+              }>
+              <For each={events.data?.events}>
+                {(event) => (
+                  <TableRow>
+                    <TableCell class="whitespace-nowrap">{formatDate(event.created_at)}</TableCell>
+                    <TableCell>
+                      {event.actor_type}
+                      <Show when={event.actor_name || event.actor_id}>: {event.actor_name || event.actor_id}</Show>
+                    </TableCell>
+                    <TableCell class="font-medium">{event.action}</TableCell>
+                    <TableCell>
+                      <Show when={event.target_type}>
+                        {event.target_type}: {event.target_id}
+                      </Show>
+                    </TableCell>
+                    <TableCell class="text-muted-foreground">
+                      {event.method} {event.path} ({event.status}) {event.ip}
+                    </TableCell>
+                    <TableCell class="text-xs">
+                      <For each={changes(event.before, event.after)}>{(line) => <div>{line}</div>}</For>
+                    </TableCell>
+                  </TableRow>
+                )}
+              </For>
+            </Show>
+          </TableBody>
+        </Table>
+      </div>
+      <div class="flex items-center justify-end gap-x-4">
+        <span class="text-sm text-muted-foreground">
+          Page {page()} of {pages()}
+        </span>
+        <Button
+          variant="outline"
+          size="sm"
+          disabled={page() <= 1}
+          onClick={() => setFilter((current) => ({ ...current, offset: (current.offset ?? 0) - pageSize }))}>
+          Previous
+        </Button>
+        <Button
+          variant="outline"
+          size="sm"
+          disabled={page() >= pages()}
+          onClick={() => setFilter((current) => ({ ...current, offset: (current.offset ?? 0) + pageSize }))}>
+          Next
+        </Button>
+      </div>
+    </div>
+  );
+}
+export default AuditPage;
@@ -1,69 +1,291 @@
-import { TbPlus } from "solid-icons/tb";
+import { As } from "@kobalte/core";
+import { useQueryClient } from "@tanstack/solid-query";
+import { isAxiosError } from "axios";
+import { TbLoader, TbPlus } from "solid-icons/tb";
+import { For, Show, createSignal } from "solid-js";
 
-import { Role } from "@/models/role";
+import { createFlagMutation, deleteFlagMutation, updateFlagMutation, useFlags } from "@/services/flag";
+
+import { Flag, SaveFlag } from "@/models/flag";
 
 import { Button } from "@/components/ui/button";
-import Datatable from "@/components/ui/data-table";
+import {
+  Dialog,
+  DialogContent,
+  DialogDescription,
+  DialogHeader,
+  DialogTitle,
+  DialogTrigger,
+} from "@/components/ui/dialog";
 import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from "@/components/ui/table";
+
+// ids are typed as a comma separated list
+const parseIds = (value: string) =>
+  value
+    .split(",")
+    .map((id) => Number(id.trim()))
+    .filter((id) => Number.isInteger(id) && id > 0);
+
+function errorMessage(error: unknown) {
+  if (isAxiosError(error)) {
+    return error.response?.data?.message ?? error.message;
+  }
+  return String(error);
+}
+
+interface FlagFormProps {
+  flag: SaveFlag;
+  // known flags keep the name Buzz checks
+  fixedName?: boolean;
+  pending: boolean;
+  error: unknown;
+  onSubmit: (flag: SaveFlag) => void;
+}
+
+function FlagForm(props: FlagFormProps) {
+  const [flag, setFlag] = createSignal<SaveFlag>({ ...props.flag });
+
+  const update = <K extends keyof SaveFlag>(field: K, value: SaveFlag[K]) => {
+    setFlag((current) => ({ ...current, [field]: value }));
+  };
+
+  return (
+    <form
+      class="grid gap-4"
+      onSubmit={(event) => {
+        event.preventDefault();
+        props.onSubmit(flag());
+      }}>
+      <div class="grid gap-1">
+        <Label for="name">Name</Label>
+        <Input
+          id="name"
+          value={flag().name}
+          disabled={props.fixedName}
+          onInput={(event) => update("name", event.target.value)}
+        />
+      </div>
+      <div class="grid gap-1">
+        <Label for="description">Description</Label>
+        <Input
+          id="description"
+          value={flag().description}
+          onInput={(event) => update("description", event.target.value)}
+        />
+      </div>
+      <div class="flex items-center gap-2">
+        <input
+          id="enabled"
+          type="checkbox"
+          checked={flag().enabled}
+          onChange={(event) => update("enabled", event.target.checked)}
+        />
+        <Label for="enabled">Enabled</Label>
+      </div>
+      <div class="grid gap-1">
+        <Label for="rollout">Rollout (% of sessions)</Label>
+        <Input
+          id="rollout"
+          type="number"
+          min="0"
+          max="100"
+          value={flag().rollout}
+          onInput={(event) => update("rollout", Number(event.target.value))}
+        />
+      </div>
+      <div class="grid gap-1">
+        <Label for="sessions">Sessions</Label>
+        <Input
+          id="sessions"
+          placeholder="1, 2, 3"
+          value={flag().sessions.join(", ")}
+          onChange={(event) => update("sessions", parseIds(event.target.value))}
+        />
+      </div>
+      <div class="grid gap-1">
+        <Label for="roles">Roles</Label>
+        <Input
+          id="roles"
+          placeholder="1, 2"
+          value={flag().roles.join(", ")}
+          onChange={(event) => update("roles", parseIds(event.target.value))}
+        />
+      </div>
+      <Show when={props.error}>
+        <p class="text-destructive text-xs">{errorMessage(props.error)}</p>
+      </Show>
+      <Button type="submit" disabled={props.pending} class="mt-4">
+        <Show when={props.pending}>
+          <TbLoader class="mr-2 h-4 w-4 animate-spin" />
+        </Show>
+        Submit
+      </Button>
+    </form>
+  );
+}
+
+const emptyFlag: SaveFlag = {
+  name: "",
+  description: "",
+  enabled: true,
+  rollout: 100,
+  sessions: [],
+  roles: [],
+};
+
+interface DialogFlagFormProps {
+  // the stored flag to edit, a new flag is created without one
+  flag?: Flag;
+  initial?: SaveFlag;
+  label: string;
+}
+
+function DialogFlagForm(props: DialogFlagFormProps) {
+  const queryClient = useQueryClient();
+  const createFlag = createFlagMutation();
+  const updateFlag = updateFlagMutation();
+  const [open, setOpen] = createSignal(false);
+
+  const mutation = () => (props.flag ? updateFlag : createFlag);
+
+  function handleSubmit(flag: SaveFlag) {
+    const onSuccess = () => {
+      setOpen(false);
+      queryClient.invalidateQueries({ queryKey: ["flags"] });
+    };
+    if (props.flag) {
+      updateFlag.mutate({ id: props.flag.id, flag }, { onSuccess });
+    } else {
+      createFlag.mutate(flag, { onSuccess });
+    }
+  }
 
-import { TableDetail, columns, createRoleTable } from "./data-table";
-
-const data: Role[] = [
-  {
-    id: 1,
-    name: "Admin",
-    actions: "{}",
-    created_at: new Date("2021-08-31T08:00:00.000Z"),
-    updated_at: new Date("2021-08-31T08:00:00.000Z"),
-    deleted_at: null,
-  },
-  {
-    id: 2,
-    name: "User",
-    actions: "{}",
-    created_at: new Date("2021-08-31T08:00:00.000Z"),
-    updated_at: new Date("2021-08-31T08:00:00.000Z"),
-    deleted_at: null,
-  },
-  {
-    id: 3,
-    name: "Guest",
-    actions: "{}",
-    created_at: new Date("2021-08-31T08:00:00.000Z"),
-    updated_at: new Date("2021-08-31T08:00:00.000Z"),
-    deleted_at: null,
-  },
-];
+  return (
+    <Dialog open={open()} onOpenChange={setOpen}>
+      <DialogTrigger asChild>
+        <As component={Button} variant={props.flag || props.initial ? "outline" : "default"}>
+          <Show when={!props.flag && !props.initial}>
+            <TbPlus class="w-5 h-5 mr-2" />
+          </Show>
+          {props.label}
+        </As>
+      </DialogTrigger>
+      <DialogContent class="sm:max-w-[425px]">
+        <DialogHeader>
+          <DialogTitle>{props.flag ? "Edit Flag" : "Create Flag"}</DialogTitle>
+          <DialogDescription>
+            A disabled flag is off everywhere. Targeted sessions and roles always get an enabled flag, the rollout
+            decides for the other sessions.
+          </DialogDescription>
+        </DialogHeader>
+        <FlagForm
+          flag={props.flag ?? props.initial ?? emptyFlag}
+          fixedName={!!props.flag || !!props.initial}
+          pending={mutation().isPending}
+          error={mutation().error}
+          onSubmit={handleSubmit}
+        />
+      </DialogContent>
+    </Dialog>
+  );
+}
 
 function FlagPage() {
-  const table = createRoleTable(data, columns);
+  const queryClient = useQueryClient();
+  const flags = useFlags();
+  const deleteFlag = deleteFlagMutation();
+  const [filter, setFilter] = createSignal("");
+
+  // known flags without a row yet are shown at their default
+  const unstored = () => {
+    const stored = new Set(flags.data?.flags.map((flag) => flag.name));
+    return Object.entries(flags.data?.defaults ?? {}).filter(([name]) => !stored.has(name));
+  };
+
+  const shown = () => (flags.data?.flags ?? []).filter((flag) => flag.name.includes(filter()));
+
+  function handleDelete(flag: Flag) {
+    if (!confirm(`Delete flag ${flag.name}?`)) {
+      return;
+    }
+    deleteFlag.mutate(flag.id, {
+      onSuccess: () => queryClient.invalidateQueries({ queryKey: ["flags"] }),
+    });
+  }
 
   return (
     <div class="space-y-4 p-8 pt-6">
       <div class="flex items-center justify-between space-y-2">
         <h2 class="text-3xl font-bold tracking-tight">Feature Flag</h2>
       </div>
-      <Datatable.Root>
-        <div class="flex items-end gap-x-4">
-          <Input
-            placeholder="Filter roles..."
-            value={(table.getColumn("name")?.getFilterValue() as string) ?? ""}
-            onInput={(event) => {
-              table.getColumn("name")?.setFilterValue(event.target.value);
-            }}
-            class="max-w-sm"
-          />
-          <div class="ml-auto flex items-end gap-x-4">
-            <Datatable.ColumnVisibility table={table} />
-            <Button>
-              <TbPlus class="w-5 h-5  mr-2" />
-              Tambah
-            </Button>
-          </div>
+      <div class="flex items-end gap-x-4">
+        <Input placeholder="Filter flags..." class="max-w-sm" onInput={(event) => setFilter(event.target.value)} />
+        <div class="ml-auto">
+          <DialogFlagForm label="Create" />
         </div>
-        <Datatable.Table table={table}>{(row) => <TableDetail role={row.original} />}</Datatable.Table>
-        <Datatable.Pagination table={table} />
-      </Datatable.Root>
+      </div>
+      <div class="rounded-md border">
+        <Table>
+          <TableHeader>
+            <TableRow>
+              <TableHead>Flag</TableHead>
+              <TableHead>Enabled</TableHead>
+              <TableHead>Rollout</TableHead>
+              <TableHead>Sessions</TableHead>
+              <TableHead>Roles</TableHead>
+              <TableHead />
+            </TableRow>
+          </TableHeader>
+          <TableBody>
+            <For each={shown()}>
+              {(flag) => (
+                <TableRow>
+                  <TableCell>
+                    <div class="font-medium">{flag.name}</div>
+                    <div class="text-xs text-muted-foreground">{flag.description}</div>
+                  </TableCell>
+                  <TableCell>{flag.enabled ? "Yes" : "No"}</TableCell>
+                  <TableCell>{flag.rollout}%</TableCell>
+                  <TableCell>{flag.sessions.join(", ") || "-"}</TableCell>
+                  <TableCell>{flag.roles.join(", ") || "-"}</TableCell>
+                  <TableCell class="flex justify-end gap-2">
+                    <DialogFlagForm flag={flag} label="Edit" />
+                    <Button variant="destructive" onClick={() => handleDelete(flag)}>
+                      Delete
+                    </Button>
+                  </TableCell>
+                </TableRow>
+              )}
+            </For>
+            <For each={unstored()}>
+              {([name, enabled]) => (
+                <TableRow class="text-muted-foreground">
+                  <TableCell>
+                    <div class="font-medium">{name}</div>
+                    <div class="text-xs">Default, not stored yet</div>
+                  </TableCell>
+                  <TableCell>{enabled ? "Yes" : "No"}</TableCell>
+                  <TableCell>100%</TableCell>
+                  <TableCell>-</TableCell>
+                  <TableCell>-</TableCell>
+                  <TableCell class="flex justify-end">
+                    <DialogFlagForm initial={{ ...emptyFlag, name, enabled }} label="Override" />
+                  </TableCell>
+                </TableRow>
+              )}
+            </For>
+            <Show when={!shown().length && !unstored().length}>
+              <TableRow>
+                <TableCell colSpan={6} class="h-24 text-center">
+                  No flags.
+                </TableCell>
+              </TableRow>
+            </Show>
+          </TableBody>
+        </Table>
+      </div>
     </div>
   );
 }
@@ -1,5 +1,5 @@
 import { A } from "@solidjs/router";
-import { TbFlag, TbHistory, TbSettings } from "solid-icons/tb";
+import { TbFlag, TbHistory, TbSettings, TbShieldCheck } from "solid-icons/tb";
 import { For, Show } from "solid-js";
 
 import { Menu } from "@/types";
@@ -9,6 +9,12 @@ import { Grid } from "@/components/ui/grid";
 
 function system(): Menu[] {
   return [
+    {
+      name: "Audit",
+      icon: <TbShieldCheck class="w-5 h-5" />,
+      href: "/audit",
+      show: true,
+    },
     {
       name: "Flag",
       icon: <TbFlag class="w-5 h-5" />,
@@ -1,8 +1,111 @@
+import { TbPlayerPause, TbPlayerPlay } from "solid-icons/tb";
+import { For, Show, createEffect, createSignal, onCleanup, untrack } from "solid-js";
+
+import { LogEntry, LogFilter } from "@/models/log";
+
+import { tailLogs, useLogEntries } from "@/services/log";
+
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from "@/components/ui/table";
+
+const levels = ["trace", "debug", "info", "warn", "error"];
+
+// live entries kept on screen while tailing
+const tailSize = 500;
+
+const levelClass: Record<string, string> = {
+  error: "text-destructive",
+  fatal: "text-destructive",
+  panic: "text-destructive",
+  warn: "text-yellow-600",
+};
+
+function fields(entry: LogEntry) {
+  return Object.entries(entry.fields)
+    .filter(([key]) => key !== "role")
+    .map(([key, value]) => `${key}=${typeof value === "string" ? value : JSON.stringify(value)}`)
+    .join(" ");
+}
+
 function LogPage() {
+  const [filter, setFilter] = createSignal<LogFilter>({ level: "info", limit: 200 });
+  const [live, setLive] = createSignal(false);
+  const [tailed, setTailed] = createSignal<LogEntry[]>([]);
+  const entries = useLogEntries(filter);
+
+  const update = (field: keyof LogFilter) => (event: { target: HTMLInputElement | HTMLSelectElement }) => {
+    setFilter((current) => ({ ...current, [field]: event.target.value }));
+  };
+
+  createEffect(() => {
+    if (!live()) {
+      return;
+    }
+    // start from what is on screen, new entries go on top
+    setTailed(untrack(() => entries.data ?? []));
+    const stop = tailLogs(filter(), (entry) => setTailed((current) => [entry, ...current].slice(0, tailSize)));
+    onCleanup(stop);
+  });
+
+  const shown = () => (live() ? tailed() : entries.data ?? []);
+
   return (
     <div class="space-y-4 p-8 pt-6">
       <div class="flex items-center justify-between space-y-2">
         <h2 class="text-3xl font-bold tracking-tight">Log</h2>
+        <Button variant="outline" onClick={() => setLive(!live())}>
+          <Show when={live()} fallback={<TbPlayerPlay class="w-5 h-5 mr-2" />}>
+            <TbPlayerPause class="w-5 h-5 mr-2" />
+          </Show>
+          {live() ? "Pause" : "Live tail"}
+        </Button>
+      </div>
+      <div class="flex flex-wrap items-end gap-4">
+        <select
+          class="flex h-10 rounded-md border border-input bg-transparent px-3 py-2 text-sm"
+          value={filter().level}
+          onChange={update("level")}>
+          <For each={levels}>{(level) => <option value={level}>{level}</option>}</For>
+        </select>
+        <Input placeholder="Session id" class="max-w-36" onInput={update("session")} />
+        <Input placeholder="Search" class="max-w-64" onInput={update("text")} />
+        <Input type="datetime-local" class="max-w-56" disabled={live()} onInput={update("from")} />
+        <Input type="datetime-local" class="max-w-56" disabled={live()} onInput={update("to")} />
+      </div>
+      <div class="rounded-md border">
+        <Table>
+          <TableHeader>
+            <TableRow>
+              <TableHead>Time</TableHead>
+              <TableHead>Level</TableHead>
+              <TableHead>Message</TableHead>
+              <TableHead>Fields</TableHead>
+            </TableRow>
+          </TableHeader>
+          <TableBody>
+            <Show
+              when={shown().length}
+              fallback={
+                <TableRow>
+                  <TableCell colSpan={4} class="h-24 text-center">
+                    No log entries.
+                  </TableCell>
+                </TableRow>
+              }>
+              <For each={shown()}>
+                {(entry) => (
+                  <TableRow>
+                    <TableCell class="whitespace-nowrap">{new Date(entry.time).toLocaleString()}</TableCell>
+                    <TableCell class={`font-medium uppercase ${levelClass[entry.level] ?? ""}`}>{entry.level}</TableCell>
+                    <TableCell>{entry.message}</TableCell>
+                    <TableCell class="font-mono text-xs text-muted-foreground break-all">{fields(entry)}</TableCell>
+                  </TableRow>
+                )}
+              </For>
+            </Show>
+          </TableBody>
+        </Table>
       </div>
     </div>
   );
@@ -16,6 +16,7 @@ const UserPage = lazy(() => import("@/pages/config/user"));
 const RolePage = lazy(() => import("@/pages/config/role"));
 
 const SystemPage = lazy(() => import("@/pages/system"));
+const AuditPage = lazy(() => import("@/pages/system/audit"));
 const FlagPage = lazy(() => import("@/pages/system/flag"));
 const LogPage = lazy(() => import("@/pages/system/log"));
 const SettingPage = lazy(() => import("@/pages/system/setting"));
@@ -35,6 +36,7 @@ export function Routes() {
         <Route path="/config/role" component={RolePage} />
 
         <Route path="/system" component={SystemPage} />
+        <Route path="/system/audit" component={AuditPage} />
         <Route path="/system/flag" component={FlagPage} />
         <Route path="/system/log" component={LogPage} />
         <Route path="/system/setting" component={SettingPage} />
@@ -0,0 +1,32 @@
+import { createQuery } from "@tanstack/solid-query";
+import { Accessor } from "solid-js";
+
+import { request } from "@/lib/request";
+
+import { AuditFilter, AuditPage } from "@/models/audit";
+
+const queryAudit = (filter: AuditFilter) =>
+  request.get<{ data: AuditPage }>("/api/v1/audit/", {
+    params: filter,
+  });
+
+function cleanFilter(filter: AuditFilter) {
+  return Object.fromEntries(Object.entries(filter).filter(([, value]) => value !== "" && value !== undefined));
+}
+
+export function useAuditEvents(filter: Accessor<AuditFilter>) {
+  return createQuery(() => ({
+    queryKey: ["audit", filter()],
+    queryFn: async () => {
+      const response = await queryAudit(cleanFilter(filter()));
+      return response.data.data;
+    },
+  }));
+}
+
+export function auditExportUrl(filter: AuditFilter) {
+  const params = new URLSearchParams(cleanFilter(filter) as Record<string, string>);
+  params.delete("limit");
+  params.delete("offset");
+  return `${request.defaults.baseURL}/api/v1/audit/export?${params.toString()}`;
+}
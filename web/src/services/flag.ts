@@ -0,0 +1,49 @@
+import { createMutation, createQuery } from "@tanstack/solid-query";
+
+import { request } from "@/lib/request";
+
+import { Flag, FlagList, SaveFlag } from "@/models/flag";
+
+const queryFlags = () => request.get<{ data: FlagList }>("/api/v1/system/flags");
+const createQueryFlag = (flag: SaveFlag) => request.post<{ data: Flag }>("/api/v1/system/flags", flag);
+const updateQueryFlag = (id: number, flag: SaveFlag) => request.put<{ data: Flag }>(`/api/v1/system/flags/${id}`, flag);
+const deleteQueryFlag = (id: number) => request.delete(`/api/v1/system/flags/${id}`);
+
+export function useFlags() {
+  return createQuery(() => ({
+    queryKey: ["flags"],
+    queryFn: async () => {
+      const response = await queryFlags();
+      return response.data.data;
+    },
+  }));
+}
+
+export function createFlagMutation() {
+  return createMutation(() => ({
+    mutationKey: ["flags"],
+    mutationFn: async (flag: SaveFlag) => {
+      const response = await createQueryFlag(flag);
+      return response.data.data;
+    },
+  }));
+}
+
+export function updateFlagMutation() {
+  return createMutation(() => ({
+    mutationKey: ["flags"],
+    mutationFn: async (props: { id: number; flag: SaveFlag }) => {
+      const response = await updateQueryFlag(props.id, props.flag);
+      return response.data.data;
+    },
+  }));
+}
+
+export function deleteFlagMutation() {
+  return createMutation(() => ({
+    mutationKey: ["flags"],
+    mutationFn: async (id: number) => {
+      await deleteQueryFlag(id);
+    },
+  }));
+}
@@ -0,0 +1,47 @@
+import { createQuery } from "@tanstack/solid-query";
+import { Accessor } from "solid-js";
+
+import { request } from "@/lib/request";
+
+import { LogEntry, LogFilter } from "@/models/log";
+
+const queryLogs = (filter: LogFilter) =>
+  request.get<{ data: { entries: LogEntry[] } }>("/api/v1/system/logs", {
+    params: filter,
+  });
+
+// from and to come from datetime-local inputs, the api wants RFC 3339
+function cleanFilter(filter: LogFilter) {
+  return Object.fromEntries(
+    Object.entries(filter)
+      .filter(([, value]) => value !== "" && value !== undefined)
+      .map(([key, value]) =>
+        key === "from" || key === "to" ? [key, new Date(value as string).toISOString()] : [key, value],
+      ),
+  );
+}
+
+export function useLogEntries(filter: Accessor<LogFilter>) {
+  return createQuery(() => ({
+    queryKey: ["logs", filter()],
+    queryFn: async () => {
+      const response = await queryLogs(cleanFilter(filter()));
+      return response.data.data.entries;
+    },
+  }));
+}
+
+// tailLogs calls onEntry with every new entry matching the filter until the
+// returned function is called
+export function tailLogs(filter: LogFilter, onEntry: (entry: LogEntry) => void) {
+  const params = new URLSearchParams(cleanFilter(filter) as Record<string, string>);
+  params.delete("from");
+  params.delete("to");
+  params.delete("limit");
+
+  const source = new EventSource(`${request.defaults.baseURL}/api/v1/system/logs/tail?${params.toString()}`, {
+    withCredentials: true,
+  });
+  source.addEventListener("log", (event) => onEntry(JSON.parse((event as MessageEvent).data)));
+  return () => source.close();
+}
@@ -12,74 +12,53 @@ const createQuerySender = (sender: Sender) => request.post<Sender>("/api/v1/what
 const updateQuerySender = (sender: Sender) => request.put<Sender>(`/api/v1/whatsapp/update-user/${sender.id}`, sender);
 const deleteQuerySender = (id: number) => request.delete<Sender>(`/api/v1/whatsapp/delete-user/${id}`);
 
-export async function statusRequest(token: string) {
-  if (token == "") {
+// the dashboard drives a session through the session api, naming it by id
+const sessionPath = (id: number, path: string) => `/api/v1/whatsapp/user/${id}/session${path}`;
+
+export async function statusRequest(id: number) {
+  if (!id) {
     return null;
   }
 
-  const response = await request.get("/api/v1/whatsapp/status", {
-    headers: {
-      token,
-    },
-  });
+  const response = await request.get(sessionPath(id, "/status"));
   return response.data;
 }
 
-export async function getQR(token: string) {
-  if (token == "") {
+export async function getQR(id: number) {
+  if (!id) {
     return null;
   }
 
-  const response = await request.get("/api/v1/whatsapp/qr", {
-    headers: {
-      token,
-    },
-  });
+  const response = await request.get(sessionPath(id, "/qr"));
   return response.data;
 }
 
-export async function connect(token: string) {
-  if (token == "") {
+export async function connect(id: number) {
+  if (!id) {
     return null;
   }
 
-  const response = await request.post(
-    "/api/v1/whatsapp/connect",
-    {
-      events: "All",
-      immediate: true,
-    },
-    {
-      headers: {
-        token,
-      },
-    },
-  );
+  const response = await request.post(sessionPath(id, "/connect"), {
+    events: "All",
+    immediate: true,
+  });
   return response.data;
 }
 
-export async function getContacts(token: string) {
-  if (token == "") {
+export async function getContacts(id: number) {
+  if (!id) {
     return null;
   }
 
-  const response = await request.post<ContactResponse>(
-    "/api/v1/whatsapp/contacts",
-    {},
-    {
-      headers: {
-        token,
-      },
-    },
-  );
+  const response = await request.post<ContactResponse>(sessionPath(id, "/contacts"), {});
   return response.data;
 }
 
-export function getAvatar(token: string, jid: string) {
-  if (token == "") {
+export function getAvatar(id: number, jid: string) {
+  if (!id) {
     throw json({
       title: "Oops, something went wrong!",
-      message: "Token is required",
+      message: "Session is required",
       statusCode: 400,
     });
   }
@@ -93,15 +72,7 @@ export function getAvatar(token: string, jid: string) {
     });
   }
 
-  return request.post(
-    "/api/v1/whatsapp/avatar",
-    { phone },
-    {
-      headers: {
-        token,
-      },
-    },
-  );
+  return request.post(sessionPath(id, "/avatar"), { phone });
 }
 
 export function useSender() {
@@ -124,11 +95,11 @@ export function useSenderDetail(id: number) {
   }));
 }
 
-export function useAvatar(token: Accessor<string>, jid: Accessor<string>) {
+export function useAvatar(id: Accessor<number>, jid: Accessor<string>) {
   return createQuery(() => ({
-    queryKey: ["sender-avatar", token(), jid()],
+    queryKey: ["sender-avatar", id(), jid()],
     queryFn: async () => {
-      const response = await getAvatar(token(), jid());
+      const response = await getAvatar(id(), jid());
       return response.data;
     },
     retry: 0,